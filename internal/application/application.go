@@ -9,7 +9,12 @@ import (
 
 	"github.com/miki208/stravaadventuregame/internal/database"
 	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/service/elevation"
+	"github.com/miki208/stravaadventuregame/internal/service/geocoding"
+	"github.com/miki208/stravaadventuregame/internal/service/maprender"
 	"github.com/miki208/stravaadventuregame/internal/service/openrouteservice"
+	"github.com/miki208/stravaadventuregame/internal/service/osrm"
+	"github.com/miki208/stravaadventuregame/internal/service/routing"
 	"github.com/miki208/stravaadventuregame/internal/service/strava"
 )
 
@@ -24,20 +29,32 @@ type App struct {
 
 	Templates  *template.Template
 	SessionMgr *helper.SessionManager
+	EventBus   *helper.EventBus
 
 	PathToCertCache string
 
-	SqlDb  *sql.DB
-	FileDb *database.FileDatabase
+	SqlDb       *sql.DB
+	SqlDbDriver string // database.DriverSQLite or database.DriverPostgres
+	FileDb      *database.FileDatabase
 
 	StravaSvc *strava.Strava
 	OrsSvc    *openrouteservice.OpenRouteService
 
+	RoutingSvc      routing.RoutingProvider
+	GeocoderSvc     geocoding.ReverseGeocoder
+	GeocodeCacheSvc *geocoding.CachedGeocoder // nil if caching is disabled
+	SearchSvc       geocoding.ForwardGeocoder
+
+	MapRenderer       *maprender.Renderer
+	MapImageDirectory string
+
 	CronSvc *Cron
 
 	logFile *os.File
 
-	SupportedActivityTypes []string
+	SupportedActivityTypes    []string
+	CoursePlacesEveryKm       float64
+	ActivityProcessingWorkers int
 }
 
 func (app *App) GetDefaultPageLoggedInUsers() string {
@@ -72,6 +89,22 @@ func (app *App) GetFullWebhookCallbackUrl() string {
 	return "https://" + app.Hostname + app.ProxyPathPrefix + app.StravaSvc.GetWebhookCallback()
 }
 
+func (app *App) GetAdventureMapImageUrl(imageId string) string {
+	return app.ProxyPathPrefix + "/map/" + imageId + ".png"
+}
+
+func (app *App) GetFullAdventureMapImageUrl(imageId string) string {
+	return "https://" + app.Hostname + app.GetAdventureMapImageUrl(imageId)
+}
+
+func (app *App) GetPublicAdventureUrl(slug string) string {
+	return app.ProxyPathPrefix + "/a/" + slug
+}
+
+func (app *App) GetFullPublicAdventureUrl(slug string) string {
+	return "https://" + app.Hostname + app.GetPublicAdventureUrl(slug)
+}
+
 func (app *App) Close() error {
 	if app.logFile != nil {
 		app.logFile.Close()
@@ -128,11 +161,13 @@ func MakeApp(configFileName string) *App {
 
 		Templates:  template.Must(template.ParseFiles(templates...)),
 		SessionMgr: helper.CreateSessionManager(conf.SessionDurationInMinutes),
+		EventBus:   helper.NewEventBus(),
 
 		PathToCertCache: conf.PathToCertCache,
 
-		SqlDb:  database.CreateSQLiteDatabase(conf.SqliteDbPath),
-		FileDb: database.CreateFileDatabase(conf.FileDbPath),
+		SqlDb:       database.CreateDatabase(conf.getDatabaseDriver(), conf.getDatabaseSource()),
+		SqlDbDriver: conf.getDatabaseDriver(),
+		FileDb:      database.CreateFileDatabase(conf.FileDbPath),
 
 		StravaSvc: strava.CreateService(
 			conf.StravaConf.ClientId,
@@ -143,14 +178,127 @@ func MakeApp(configFileName string) *App {
 			conf.StravaConf.VerifyToken,
 			conf.StravaConf.DeleteOldActivitiesAfterDays,
 			conf.StravaConf.ProcessWebhookEventsAfterSec),
-		OrsSvc: openrouteservice.CreateService(conf.OrsConf.ApiKey),
+		OrsSvc: openrouteservice.CreateService(conf.OrsConf.ApiKey, conf.OrsConf.Elevation),
+
+		MapRenderer:       createMapRenderer(&conf),
+		MapImageDirectory: conf.FileDbPath + "mapimage/",
 
 		logFile: logFile,
 
-		SupportedActivityTypes: conf.SupportedActivityTypes,
+		SupportedActivityTypes:    conf.SupportedActivityTypes,
+		CoursePlacesEveryKm:       conf.getCoursePlacesEveryKm(),
+		ActivityProcessingWorkers: conf.getActivityProcessingWorkers(),
+	}
+
+	if err = database.Migrate(app.SqlDb, app.SqlDbDriver); err != nil {
+		panic(fmt.Errorf("database migration failed: %w", err))
+	}
+
+	app.RoutingSvc = createRoutingProvider(&conf, app)
+	app.GeocoderSvc = createReverseGeocoder(&conf, app)
+	app.GeocodeCacheSvc = createGeocodeCache(&conf, app)
+	if app.GeocodeCacheSvc != nil {
+		app.GeocoderSvc = app.GeocodeCacheSvc
 	}
+	app.SearchSvc = createForwardGeocoder(&conf, app)
 
-	app.CronSvc = NewCron(app, conf.ScheduledJobIntervalSec)
+	app.CronSvc = NewCron(app, conf.ScheduledJobIntervalSec, conf.getScheduledJobShutdownTimeout(), conf.ScheduledJobs)
 
 	return app
 }
+
+func createRoutingProvider(conf *config, app *App) routing.RoutingProvider {
+	var engine routing.RoutingProvider = app.OrsSvc
+	if conf.RoutingConf != nil && conf.RoutingConf.Provider == "osrm" {
+		engine = osrm.CreateService(conf.RoutingConf.OsrmBaseUrl, conf.RoutingConf.OsrmProfile)
+	}
+
+	// routes imported by admins always take precedence over the calculated ones
+	var provider routing.RoutingProvider = routing.CreateChainProvider(routing.CreateImportedRouteProvider(app.SqlDb, app.FileDb), engine)
+
+	if conf.RoutingConf != nil && conf.RoutingConf.DemDirectory != "" {
+		provider = routing.CreateElevationProvider(provider, elevation.CreateDem(conf.RoutingConf.DemDirectory))
+	}
+
+	return provider
+}
+
+func createMapRenderer(conf *config) *maprender.Renderer {
+	tileDirectory := ""
+	width, height := 800, 500
+
+	if conf.MapImageConf != nil {
+		tileDirectory = conf.MapImageConf.TileDirectory
+
+		if conf.MapImageConf.Width > 0 {
+			width = conf.MapImageConf.Width
+		}
+
+		if conf.MapImageConf.Height > 0 {
+			height = conf.MapImageConf.Height
+		}
+	}
+
+	return maprender.CreateRenderer(tileDirectory, width, height)
+}
+
+func createReverseGeocoder(conf *config, app *App) geocoding.ReverseGeocoder {
+	names := []string{"ors", "local"}
+	maxDistanceKm := 50.0
+
+	if conf.GeocodingConf != nil {
+		if len(conf.GeocodingConf.ReverseGeocoders) > 0 {
+			names = conf.GeocodingConf.ReverseGeocoders
+		}
+
+		if conf.GeocodingConf.LocalMaxDistanceKm > 0 {
+			maxDistanceKm = conf.GeocodingConf.LocalMaxDistanceKm
+		}
+	}
+
+	var geocoders []geocoding.ReverseGeocoder
+	for _, name := range names {
+		switch name {
+		case "ors":
+			geocoders = append(geocoders, geocoding.CreateOrsReverseGeocoder(app.OrsSvc))
+		case "local":
+			geocoders = append(geocoders, geocoding.CreateLocalReverseGeocoder(app.SqlDb, maxDistanceKm))
+		}
+	}
+
+	return geocoding.CreateChainGeocoder(geocoders...)
+}
+
+func createForwardGeocoder(conf *config, app *App) geocoding.ForwardGeocoder {
+	names := []string{"ors", "local"}
+	if conf.GeocodingConf != nil && len(conf.GeocodingConf.ForwardGeocoders) > 0 {
+		names = conf.GeocodingConf.ForwardGeocoders
+	}
+
+	var geocoders []geocoding.ForwardGeocoder
+	for _, name := range names {
+		switch name {
+		case "ors":
+			geocoders = append(geocoders, geocoding.CreateOrsForwardGeocoder(app.OrsSvc))
+		case "local":
+			geocoders = append(geocoders, geocoding.CreateLocalForwardGeocoder(app.SqlDb))
+		}
+	}
+
+	return geocoding.CreateChainForwardGeocoder(geocoders...)
+}
+
+// createGeocodeCache returns nil if the cache is disabled (by setting its ttl to 0)
+func createGeocodeCache(conf *config, app *App) *geocoding.CachedGeocoder {
+	ttlHours := conf.getGeocodeCacheTtlHours()
+	if ttlHours == 0 {
+		return nil
+	}
+
+	warmCourseEveryKm := 0.0
+	if conf.GeocodingConf != nil {
+		warmCourseEveryKm = conf.GeocodingConf.WarmCourseEveryKm
+	}
+
+	return geocoding.CreateCachedGeocoder(app.GeocoderSvc, app.SqlDb, ttlHours*60*60, conf.getGeocodeCachePrecision(), warmCourseEveryKm)
+}
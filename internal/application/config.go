@@ -7,6 +7,9 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/database"
 )
 
 type stravaConfig struct {
@@ -21,27 +24,87 @@ type stravaConfig struct {
 }
 
 type openRouteServiceConfig struct {
-	ApiKey string `json:"api_key"`
+	ApiKey    string `json:"api_key"`
+	Elevation bool   `json:"elevation"`
+}
+
+type routingConfig struct {
+	Provider     string `json:"provider"`
+	OsrmBaseUrl  string `json:"osrm_base_url"`
+	OsrmProfile  string `json:"osrm_profile"`
+	DemDirectory string `json:"dem_directory"`
+}
+
+type geocodingConfig struct {
+	ReverseGeocoders    []string `json:"reverse_geocoders"`
+	ForwardGeocoders    []string `json:"forward_geocoders"`
+	LocalMaxDistanceKm  float64  `json:"local_max_distance_km"`
+	CacheTtlHours       *int     `json:"cache_ttl_hours"` // 0 disables the cache
+	CachePrecision      *int     `json:"cache_precision"`
+	WarmCourseEveryKm   float64  `json:"warm_course_every_km"`
+	CoursePlacesEveryKm float64  `json:"course_places_every_km"`
+}
+
+type mapImageConfig struct {
+	TileDirectory string `json:"tile_directory"`
+	Width         int    `json:"width"`
+	Height        int    `json:"height"`
+}
+
+// scheduledJobConfig overrides the schedule of a scheduled job: either interval_sec or cron (a cron expression, in UTC).
+type scheduledJobConfig struct {
+	IntervalSec int    `json:"interval_sec"`
+	Cron        string `json:"cron"`
+	JitterSec   int    `json:"jitter_sec"`
+}
+
+// getSchedule returns nil if the schedule is not overridden (e.g. only the jitter is).
+func (jobConf *scheduledJobConfig) getSchedule() (Schedule, error) {
+	if jobConf.IntervalSec != 0 && jobConf.Cron != "" {
+		return nil, fmt.Errorf("only one of interval_sec and cron can be set")
+	}
+
+	if jobConf.Cron != "" {
+		return ParseCronExpression(jobConf.Cron)
+	}
+
+	if jobConf.IntervalSec < 0 {
+		return nil, fmt.Errorf("interval_sec must be positive")
+	}
+
+	if jobConf.IntervalSec > 0 {
+		return Every(time.Second * time.Duration(jobConf.IntervalSec)), nil
+	}
+
+	return nil, nil
 }
 
 type config struct {
-	UseTls                    bool                    `json:"use_tls"`
-	InsecurePort              int                     `json:"insecure_port"`
-	LoggingLevel              string                  `json:"logging_level"`
-	SessionDurationInMinutes  int                     `json:"session_duration_in_minutes"`
-	Hostname                  string                  `json:"hostname"`
-	ProxyPathPrefix           string                  `json:"proxy_path_prefix"`
-	DefaultPageLoggedInUsers  string                  `json:"default_page_logged_in"`
-	DefaultPageLoggedOutUsers string                  `json:"default_page_logged_out"`
-	AdminPanelPage            string                  `json:"admin_panel_page"`
-	PathToTemplates           string                  `json:"path_to_templates"`
-	PathToCertCache           string                  `json:"path_to_cert_cache"`
-	SqliteDbPath              string                  `json:"sqlite_db_path"`
-	FileDbPath                string                  `json:"file_db_path"`
-	StravaConf                *stravaConfig           `json:"strava_config"`
-	OrsConf                   *openRouteServiceConfig `json:"open_route_service_config"`
-	ScheduledJobIntervalSec   int                     `json:"scheduled_job_interval_sec"`
-	SupportedActivityTypes    []string                `json:"supported_activity_types"`
+	UseTls                         bool                           `json:"use_tls"`
+	InsecurePort                   int                            `json:"insecure_port"`
+	LoggingLevel                   string                         `json:"logging_level"`
+	SessionDurationInMinutes       int                            `json:"session_duration_in_minutes"`
+	Hostname                       string                         `json:"hostname"`
+	ProxyPathPrefix                string                         `json:"proxy_path_prefix"`
+	DefaultPageLoggedInUsers       string                         `json:"default_page_logged_in"`
+	DefaultPageLoggedOutUsers      string                         `json:"default_page_logged_out"`
+	AdminPanelPage                 string                         `json:"admin_panel_page"`
+	PathToTemplates                string                         `json:"path_to_templates"`
+	PathToCertCache                string                         `json:"path_to_cert_cache"`
+	DatabaseDriver                 string                         `json:"database_driver"`
+	SqliteDbPath                   string                         `json:"sqlite_db_path"`
+	PostgresDsn                    string                         `json:"postgres_dsn"`
+	FileDbPath                     string                         `json:"file_db_path"`
+	StravaConf                     *stravaConfig                  `json:"strava_config"`
+	OrsConf                        *openRouteServiceConfig        `json:"open_route_service_config"`
+	RoutingConf                    *routingConfig                 `json:"routing_config"`
+	GeocodingConf                  *geocodingConfig               `json:"geocoding_config"`
+	MapImageConf                   *mapImageConfig                `json:"map_image_config"`
+	ScheduledJobIntervalSec        int                            `json:"scheduled_job_interval_sec"`
+	ScheduledJobs                  map[string]*scheduledJobConfig `json:"scheduled_jobs"`
+	ScheduledJobShutdownTimeoutSec int                            `json:"scheduled_job_shutdown_timeout_sec"`
+	ActivityProcessingWorkers      int                            `json:"activity_processing_workers"`
+	SupportedActivityTypes         []string                       `json:"supported_activity_types"`
 }
 
 func (conf *config) loadFromFile(fileName string) error {
@@ -73,6 +136,69 @@ func (conf *config) getLoggingLevel() slog.Level {
 	}
 }
 
+func (conf *config) getCoursePlacesEveryKm() float64 {
+	if conf.GeocodingConf == nil || conf.GeocodingConf.CoursePlacesEveryKm == 0 {
+		return 5
+	}
+
+	return conf.GeocodingConf.CoursePlacesEveryKm
+}
+
+// getGeocodeCacheTtlHours returns how long geocoded names are cached, 30 days by default. 0 means that the cache is
+// disabled.
+func (conf *config) getGeocodeCacheTtlHours() int {
+	if conf.GeocodingConf == nil || conf.GeocodingConf.CacheTtlHours == nil {
+		return 30 * 24
+	}
+
+	return *conf.GeocodingConf.CacheTtlHours
+}
+
+// getGeocodeCachePrecision returns the number of decimals of coordinates that share a cached name, 2 (~1 km) by default
+func (conf *config) getGeocodeCachePrecision() int {
+	if conf.GeocodingConf == nil || conf.GeocodingConf.CachePrecision == nil {
+		return 2
+	}
+
+	return *conf.GeocodingConf.CachePrecision
+}
+
+// getDatabaseDriver returns the SQL database used for everything but courses and map images, SQLite by default
+func (conf *config) getDatabaseDriver() string {
+	if conf.DatabaseDriver == "" {
+		return database.DriverSQLite
+	}
+
+	return conf.DatabaseDriver
+}
+
+// getDatabaseSource returns the path of the SQLite file, or the connection string of PostgreSQL
+func (conf *config) getDatabaseSource() string {
+	if conf.getDatabaseDriver() == database.DriverPostgres {
+		return conf.PostgresDsn
+	}
+
+	return conf.SqliteDbPath
+}
+
+// getActivityProcessingWorkers returns how many athletes' activity events are processed in parallel
+func (conf *config) getActivityProcessingWorkers() int {
+	if conf.ActivityProcessingWorkers == 0 {
+		return 4
+	}
+
+	return conf.ActivityProcessingWorkers
+}
+
+// getScheduledJobShutdownTimeout returns how long the shutdown waits for running scheduled jobs to finish
+func (conf *config) getScheduledJobShutdownTimeout() time.Duration {
+	if conf.ScheduledJobShutdownTimeoutSec == 0 {
+		return 30 * time.Second
+	}
+
+	return time.Second * time.Duration(conf.ScheduledJobShutdownTimeoutSec)
+}
+
 func (conf *config) validate() error {
 	//TODO: add real bulletproof validation
 
@@ -115,8 +241,21 @@ func (conf *config) validate() error {
 		return fmt.Errorf("path to certificate cache cannot be empty")
 	}
 
-	if conf.SqliteDbPath == "" || conf.FileDbPath == "" {
-		return fmt.Errorf("database paths cannot be empty")
+	switch conf.getDatabaseDriver() {
+	case database.DriverSQLite:
+		if conf.SqliteDbPath == "" {
+			return fmt.Errorf("sqlite_db_path cannot be empty")
+		}
+	case database.DriverPostgres:
+		if conf.PostgresDsn == "" {
+			return fmt.Errorf("postgres_dsn cannot be empty")
+		}
+	default:
+		return fmt.Errorf("database driver must be either sqlite or postgres")
+	}
+
+	if conf.FileDbPath == "" {
+		return fmt.Errorf("file database path cannot be empty")
 	}
 
 	if conf.StravaConf == nil || conf.OrsConf == nil {
@@ -134,10 +273,76 @@ func (conf *config) validate() error {
 		return fmt.Errorf("openrouteservice configuration is invalid")
 	}
 
+	if conf.RoutingConf != nil {
+		switch conf.RoutingConf.Provider {
+		case "", "ors":
+		case "osrm":
+			if conf.RoutingConf.OsrmBaseUrl == "" || conf.RoutingConf.OsrmProfile == "" {
+				return fmt.Errorf("osrm base url and profile cannot be empty")
+			}
+		default:
+			return fmt.Errorf("routing provider must be either ors or osrm")
+		}
+	}
+
+	if conf.GeocodingConf != nil {
+		for _, geocoder := range conf.GeocodingConf.ReverseGeocoders {
+			if geocoder != "ors" && geocoder != "local" {
+				return fmt.Errorf("reverse geocoder must be either ors or local")
+			}
+		}
+
+		for _, geocoder := range conf.GeocodingConf.ForwardGeocoders {
+			if geocoder != "ors" && geocoder != "local" {
+				return fmt.Errorf("forward geocoder must be either ors or local")
+			}
+		}
+
+		if conf.GeocodingConf.LocalMaxDistanceKm < 0 {
+			return fmt.Errorf("local gazetteer max distance cannot be negative")
+		}
+
+		if conf.getGeocodeCacheTtlHours() < 0 || conf.getGeocodeCachePrecision() < 0 || conf.getGeocodeCachePrecision() > 6 {
+			return fmt.Errorf("geocode cache ttl cannot be negative, and precision must be between 0 and 6 decimals")
+		}
+
+		if conf.GeocodingConf.WarmCourseEveryKm < 0 || conf.GeocodingConf.CoursePlacesEveryKm < 0 {
+			return fmt.Errorf("course sampling intervals cannot be negative")
+		}
+	}
+
+	if conf.MapImageConf != nil {
+		if conf.MapImageConf.Width < 0 || conf.MapImageConf.Width > 4096 || conf.MapImageConf.Height < 0 || conf.MapImageConf.Height > 4096 {
+			return fmt.Errorf("map image width and height must be between 0 (default) and 4096 pixels")
+		}
+	}
+
 	if conf.ScheduledJobIntervalSec < 60 {
 		return fmt.Errorf("scheduled job interval must be at least 60 seconds")
 	}
 
+	if conf.ScheduledJobShutdownTimeoutSec < 0 {
+		return fmt.Errorf("scheduled job shutdown timeout cannot be negative")
+	}
+
+	for jobName, jobConf := range conf.ScheduledJobs {
+		if jobConf == nil {
+			return fmt.Errorf("schedule of job %s cannot be empty", jobName)
+		}
+
+		if _, err := jobConf.getSchedule(); err != nil {
+			return fmt.Errorf("invalid schedule of job %s: %w", jobName, err)
+		}
+
+		if jobConf.JitterSec < 0 {
+			return fmt.Errorf("jitter of job %s cannot be negative", jobName)
+		}
+	}
+
+	if conf.ActivityProcessingWorkers < 0 {
+		return fmt.Errorf("number of activity processing workers cannot be negative")
+	}
+
 	if len(conf.SupportedActivityTypes) == 0 {
 		return fmt.Errorf("list of supported activity types cannot be empty")
 	}
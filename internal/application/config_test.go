@@ -0,0 +1,31 @@
+package application
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGeocodeCacheDefaults(t *testing.T) {
+	tests := []struct {
+		json          string
+		wantTtlHours  int
+		wantPrecision int
+	}{
+		{`{}`, 720, 2},
+		{`{"geocoding_config": {"reverse_geocoders": ["local"]}}`, 720, 2},
+		{`{"geocoding_config": {"cache_ttl_hours": 24}}`, 24, 2},
+		{`{"geocoding_config": {"cache_precision": 3}}`, 720, 3},
+		{`{"geocoding_config": {"cache_ttl_hours": 0, "cache_precision": 0}}`, 0, 0}, // disabled, explicitly
+	}
+
+	for _, test := range tests {
+		var conf config
+		if err := json.Unmarshal([]byte(test.json), &conf); err != nil {
+			t.Fatal(err)
+		}
+
+		if ttlHours, precision := conf.getGeocodeCacheTtlHours(), conf.getGeocodeCachePrecision(); ttlHours != test.wantTtlHours || precision != test.wantPrecision {
+			t.Errorf("%s: ttl %d h, precision %d, want %d h, %d", test.json, ttlHours, precision, test.wantTtlHours, test.wantPrecision)
+		}
+	}
+}
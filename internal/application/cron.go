@@ -1,44 +1,213 @@
 package application
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"math/rand/v2"
 	"slices"
 	"sync"
 	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/model"
 )
 
-type CronJob func(app *App)
+// CronJob is the function of a scheduled job. Its context is cancelled when the application is shutting down (or when the
+// lease of the job is lost), long running jobs should stop at the first safe point then (e.g. between two processed items)
+// and return the context's error. Side effects which must not be performed twice are preceded by a check of the fence (see
+// JobFence).
+type CronJob func(ctx context.Context, app *App, fence *JobFence) error
+
+// ScheduledJob is a job registered with the Cron service under a unique name.
+type ScheduledJob struct {
+	Name string
+	Run  CronJob
+
+	Schedule Schedule      // nil runs the job at the default interval
+	Jitter   time.Duration // every run is delayed by a random duration up to this, so that jobs don't start at the same time
+}
+
+// JobStatus is a snapshot of a scheduled job, as shown to admins.
+type JobStatus struct {
+	Name     string
+	Schedule string
+	Jitter   time.Duration
+	NextRun  time.Time
+	Running  bool
+}
 
-// Cron is a "dumb" cron-like scheduler that runs jobs at a specified frequency.
-// It does not support complex scheduling like specific times or intervals.
-// It simply runs all registered jobs every `frequency` seconds, and it does not care about drift or missed executions.
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobRunning  = errors.New("job is already running")
+	ErrJobLeased   = errors.New("job is run by another instance")
+)
+
+// runs of scheduled jobs are kept this long
+const jobRunRetention = 30 * 24 * time.Hour
+
+const maxJobRunErrorLength = 500
+
+type scheduledJobState struct {
+	job        ScheduledJob
+	plannedRun time.Time // by the schedule
+	nextRun    time.Time // the planned run with the jitter
+	running    bool
+}
+
+// Cron runs every registered job on its own schedule: at an interval or by a cron expression, with optional jitter. Jobs
+// run in parallel to each other, but a job never overlaps with itself: if it's still running when it's due, that run is
+// skipped. Every run is recorded in the JobRun table. With several instances sharing the database, a job is run only by
+// the instance holding its lease (see cronlease.go).
 type Cron struct {
 	app *App
 
+	instance  string // unique id of this instance
+	hostname  string
+	startedAt time.Time
+
+	ctx    context.Context // cancelled on stop, passed to the jobs
+	cancel context.CancelFunc
+
+	shutdownTimeout time.Duration // how long Stop waits for running jobs
+
+	defaultSchedule Schedule
+	jobConfs        map[string]*scheduledJobConfig // schedules from the configuration, by job name
+
 	jobsMutex sync.Mutex
-	jobs      []CronJob
+	jobs      []*scheduledJobState
+	runs      map[string]*jobRun // runs in progress, by job name
 
-	frequency  int // Frequency in seconds
+	runningJobs sync.WaitGroup
+
+	wakeCh     chan bool // the schedule changed, e.g. a job was added
 	exitCh     chan bool
 	exitDoneCh chan bool
 }
 
-func NewCron(app *App, frequency int) *Cron {
+func NewCron(app *App, defaultFrequency int, shutdownTimeout time.Duration, jobConfs map[string]*scheduledJobConfig) *Cron {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	instance, hostname := newInstanceId()
+
 	return &Cron{
-		app:        app,
-		frequency:  frequency,
-		exitCh:     make(chan bool, 1),
-		exitDoneCh: make(chan bool, 1),
+		app:             app,
+		instance:        instance,
+		hostname:        hostname,
+		startedAt:       time.Now(),
+		ctx:             ctx,
+		cancel:          cancel,
+		shutdownTimeout: shutdownTimeout,
+		defaultSchedule: Every(time.Second * time.Duration(defaultFrequency)),
+		jobConfs:        jobConfs,
+		runs:            make(map[string]*jobRun),
+		wakeCh:          make(chan bool, 1),
+		exitCh:          make(chan bool, 1),
+		exitDoneCh:      make(chan bool, 1),
+	}
+}
+
+// AddJob registers the job. Its schedule and jitter are overridden by the configuration, if there are any for the job.
+func (c *Cron) AddJob(job ScheduledJob) error {
+	if jobConf, found := c.jobConfs[job.Name]; found {
+		schedule, err := jobConf.getSchedule()
+		if err != nil {
+			return fmt.Errorf("invalid schedule of job %s: %w", job.Name, err)
+		}
+
+		if schedule != nil {
+			job.Schedule = schedule
+		}
+
+		if jobConf.JitterSec > 0 {
+			job.Jitter = time.Second * time.Duration(jobConf.JitterSec)
+		}
 	}
+
+	if job.Schedule == nil {
+		job.Schedule = c.defaultSchedule
+	}
+
+	c.jobsMutex.Lock()
+	defer c.jobsMutex.Unlock()
+
+	if slices.ContainsFunc(c.jobs, func(state *scheduledJobState) bool { return state.job.Name == job.Name }) {
+		return fmt.Errorf("job %s is already registered", job.Name)
+	}
+
+	state := &scheduledJobState{job: job}
+	state.planNextRun(time.Now())
+
+	c.jobs = append(c.jobs, state)
+
+	c.wake()
+
+	return nil
 }
 
-func (c *Cron) AddJob(job CronJob) {
+// Jobs returns the status of all registered jobs.
+func (c *Cron) Jobs() []JobStatus {
 	c.jobsMutex.Lock()
 	defer c.jobsMutex.Unlock()
 
-	c.jobs = append(c.jobs, job)
+	var statuses []JobStatus
+	for _, state := range c.jobs {
+		statuses = append(statuses, JobStatus{
+			Name:     state.job.Name,
+			Schedule: state.job.Schedule.String(),
+			Jitter:   state.job.Jitter,
+			NextRun:  state.nextRun,
+			Running:  state.running,
+		})
+	}
+
+	return statuses
 }
 
+// RunNow starts the job right away, outside of its schedule, if this instance can take its lease.
+func (c *Cron) RunNow(name string) error {
+	c.jobsMutex.Lock()
+
+	index := slices.IndexFunc(c.jobs, func(state *scheduledJobState) bool { return state.job.Name == name })
+	if index < 0 {
+		c.jobsMutex.Unlock()
+
+		return ErrJobNotFound
+	}
+
+	state := c.jobs[index]
+	if state.running {
+		c.jobsMutex.Unlock()
+
+		return ErrJobRunning
+	}
+
+	// marked as running, so that the scheduler doesn't start it while the lease is taken
+	state.running = true
+	c.runningJobs.Add(1)
+
+	c.jobsMutex.Unlock()
+
+	// like in startJob, the database is not accessed with the jobs mutex locked
+	generation := c.acquireLease(name)
+	if generation == 0 {
+		c.finishJob(state)
+
+		return ErrJobLeased
+	}
+
+	go func() {
+		defer c.finishJob(state)
+
+		c.runJob(state.job, model.JobRunTriggerManual, generation)
+	}()
+
+	return nil
+}
+
+// Stop stops scheduling jobs and cancels the context of the running ones, then waits for them to finish, but at most for
+// the shutdown timeout. Leases are released once all jobs finish, runs of jobs that don't finish in time are marked as
+// failed by the next heartbeat of any instance.
 func (c *Cron) Stop() {
 	c.exitCh <- true
 	close(c.exitCh)
@@ -48,41 +217,282 @@ func (c *Cron) Stop() {
 	c.jobsMutex.Lock()
 	c.jobs = nil
 	c.jobsMutex.Unlock()
+
+	c.cancel()
+
+	jobsDoneCh := make(chan bool)
+	go func() {
+		c.runningJobs.Wait()
+
+		close(jobsDoneCh)
+	}()
+
+	// leases of the running jobs are still renewed
+	heartbeatTicker := time.NewTicker(heartbeatInterval)
+	defer heartbeatTicker.Stop()
+
+	deadline := time.After(c.shutdownTimeout)
+	for {
+		select {
+		case <-jobsDoneCh:
+			c.releaseLeases()
+
+			slog.Info("Cron service stopped.")
+
+			return
+		case <-heartbeatTicker.C:
+			c.heartbeat()
+		case <-deadline:
+			slog.Warn("Cron service stopped, but some scheduled jobs didn't finish in time.", "timeout", c.shutdownTimeout)
+
+			return
+		}
+	}
 }
 
 func (c *Cron) Start() {
+	c.heartbeat()
+
 	go func() {
-		slog.Info("Cron service started.", "frequency", c.frequency)
+		slog.Info("Cron service started.", "instance", c.instance)
 
-		timer := time.NewTimer(time.Second * time.Duration(c.frequency))
+		timer := time.NewTimer(c.untilNextRun())
+
+		heartbeatTicker := time.NewTicker(heartbeatInterval)
+		defer heartbeatTicker.Stop()
 
 	loop:
 		for {
 			select {
 			case <-c.exitCh:
 				break loop
+			case <-c.wakeCh:
 			case <-timer.C:
-				slog.Info("Running scheduled jobs...")
-
-				c.jobsMutex.Lock()
-				jobsCopy := slices.Clone(c.jobs)
-				c.jobsMutex.Unlock()
-
-				for _, job := range jobsCopy {
-					job(c.app)
-				}
+				c.startDueJobs()
+			case <-heartbeatTicker.C:
+				c.heartbeat()
 
-				timer.Reset(time.Second * time.Duration(c.frequency))
-
-				slog.Info("Scheduled jobs completed.")
+				continue // the schedule didn't change
 			}
+
+			timer.Stop()
+			timer.Reset(c.untilNextRun())
 		}
 
 		timer.Stop()
 
+		// running jobs are waited for in Stop
 		c.exitDoneCh <- true
 		close(c.exitDoneCh)
+	}()
+}
+
+func (c *Cron) wake() {
+	select {
+	case c.wakeCh <- true:
+	default:
+	}
+}
 
-		slog.Info("Cron service stopped.")
+// planNextRun plans the first run after the given time.
+func (state *scheduledJobState) planNextRun(after time.Time) {
+	state.plannedRun = state.job.Schedule.Next(after)
+	state.nextRun = state.plannedRun
+
+	if !state.nextRun.IsZero() && state.job.Jitter > 0 {
+		state.nextRun = state.nextRun.Add(rand.N(state.job.Jitter))
+	}
+}
+
+func (c *Cron) untilNextRun() time.Duration {
+	c.jobsMutex.Lock()
+	defer c.jobsMutex.Unlock()
+
+	// with no jobs, it's woken up when the first one is added
+	until := 24 * time.Hour
+
+	now := time.Now()
+	for _, state := range c.jobs {
+		if !state.nextRun.IsZero() && state.nextRun.Sub(now) < until {
+			until = max(state.nextRun.Sub(now), 0)
+		}
+	}
+
+	return until
+}
+
+func (c *Cron) startDueJobs() {
+	now := time.Now()
+
+	var skippedJobs []string
+
+	c.jobsMutex.Lock()
+	for _, state := range c.jobs {
+		if state.nextRun.IsZero() || state.nextRun.After(now) {
+			continue
+		}
+
+		// the next run is counted from the planned one (without the jitter), so that interval schedules don't drift, but
+		// missed runs (e.g. while the machine was suspended) are not made up for
+		state.planNextRun(state.plannedRun)
+		if state.plannedRun.Before(now) {
+			state.planNextRun(now)
+		}
+
+		if state.running {
+			slog.Warn("Skipping the scheduled job, its previous run is still in progress.", "job", state.job.Name)
+
+			skippedJobs = append(skippedJobs, state.job.Name)
+
+			continue
+		}
+
+		c.startJob(state, model.JobRunTriggerSchedule)
+	}
+	c.jobsMutex.Unlock()
+
+	// recorded after unlocking, so that a slow database doesn't block the other jobs and the admin page
+	for _, name := range skippedJobs {
+		c.recordSkippedRun(name, now)
+	}
+}
+
+// startJob runs the job in its own goroutine if this instance holds (or can take) its lease, and records the run. Must be
+// called with the jobs mutex locked.
+func (c *Cron) startJob(state *scheduledJobState, trigger string) {
+	state.running = true
+	c.runningJobs.Add(1)
+
+	go func() {
+		defer c.finishJob(state)
+
+		if generation := c.acquireLease(state.job.Name); generation > 0 {
+			c.runJob(state.job, trigger, generation)
+		}
 	}()
 }
+
+// finishJob marks the job started by startJob or RunNow as not running anymore.
+func (c *Cron) finishJob(state *scheduledJobState) {
+	c.jobsMutex.Lock()
+	state.running = false
+	c.jobsMutex.Unlock()
+
+	c.runningJobs.Done()
+}
+
+// runJob runs the job with the lease of the given generation. Its context is cancelled if the lease is lost.
+func (c *Cron) runJob(job ScheduledJob, trigger string, generation int) {
+	startedAt := time.Now()
+
+	ctx, cancel := context.WithCancelCause(c.ctx)
+	defer cancel(nil)
+
+	fence := &JobFence{app: c.app, job: job.Name, holder: c.instance, generation: generation}
+
+	c.jobsMutex.Lock()
+	c.runs[job.Name] = &jobRun{fence: fence, cancel: cancel, leaseExpiresAt: startedAt.Add(jobLeaseTtl)}
+	c.jobsMutex.Unlock()
+
+	defer func() {
+		c.jobsMutex.Lock()
+		delete(c.runs, job.Name)
+		c.jobsMutex.Unlock()
+	}()
+
+	slog.Info("Running scheduled job...", "job", job.Name, "trigger", trigger)
+
+	run := model.JobRun{
+		Job:         job.Name,
+		TriggeredBy: trigger,
+		Status:      model.JobRunRunning,
+		StartedAt:   int(startedAt.Unix()),
+		Instance:    c.instance,
+	}
+
+	// the job runs anyway, the history is only informative
+	if err := run.Save(c.app.SqlDb, nil); err != nil {
+		slog.Error("Failed to record the start of the scheduled job.", "job", job.Name, "error", err)
+	}
+
+	err := runRecoveringPanic(ctx, c.app, job.Run, fence)
+
+	finishedAt := time.Now()
+
+	run.FinishedAt = int(finishedAt.Unix())
+	run.DurationMs = int(finishedAt.Sub(startedAt).Milliseconds())
+	run.Status = model.JobRunSucceeded
+
+	if err != nil && errors.Is(err, context.Canceled) && errors.Is(context.Cause(ctx), ErrJobLeaseLost) {
+		run.Status = model.JobRunCancelled
+		run.Error = "interrupted, " + ErrJobLeaseLost.Error()
+
+		slog.Warn("Scheduled job cancelled, its lease was lost.", "job", job.Name, "duration", finishedAt.Sub(startedAt))
+	} else if err != nil && errors.Is(err, context.Canceled) {
+		run.Status = model.JobRunCancelled
+		run.Error = "interrupted, the application was shutting down"
+
+		slog.Info("Scheduled job cancelled.", "job", job.Name, "duration", finishedAt.Sub(startedAt))
+	} else if err != nil {
+		run.Status = model.JobRunFailed
+
+		run.Error = err.Error()
+		if len(run.Error) > maxJobRunErrorLength {
+			run.Error = run.Error[:maxJobRunErrorLength]
+		}
+
+		slog.Warn("Scheduled job failed.", "job", job.Name, "duration", finishedAt.Sub(startedAt), "error", err)
+	} else {
+		slog.Info("Scheduled job completed.", "job", job.Name, "duration", finishedAt.Sub(startedAt))
+	}
+
+	if err = run.Save(c.app.SqlDb, nil); err != nil {
+		slog.Error("Failed to record the end of the scheduled job.", "job", job.Name, "error", err)
+	}
+
+	c.pruneRuns(job.Name, finishedAt)
+}
+
+func runRecoveringPanic(ctx context.Context, app *App, job CronJob, fence *JobFence) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	return job(ctx, app, fence)
+}
+
+func (c *Cron) recordSkippedRun(name string, now time.Time) {
+	run := model.JobRun{
+		Job:         name,
+		TriggeredBy: model.JobRunTriggerSchedule,
+		Status:      model.JobRunSkipped,
+		StartedAt:   int(now.Unix()),
+		FinishedAt:  int(now.Unix()),
+		Error:       "the previous run was still in progress",
+		Instance:    c.instance,
+	}
+
+	if err := run.Save(c.app.SqlDb, nil); err != nil {
+		slog.Error("Failed to record the skipped run of the scheduled job.", "job", name, "error", err)
+	}
+}
+
+func (c *Cron) pruneRuns(name string, now time.Time) {
+	oldRuns, err := model.AllJobRuns(c.app.SqlDb, nil, map[string]any{
+		"job":        name,
+		"started_at": model.ComparationOperation{FieldValue: int(now.Add(-jobRunRetention).Unix()), Operation: "<"},
+	})
+	if err != nil {
+		slog.Error("Failed to load old runs of the scheduled job.", "job", name, "error", err)
+
+		return
+	}
+
+	for _, run := range oldRuns {
+		if err = run.Delete(c.app.SqlDb, nil); err != nil {
+			slog.Error("Failed to delete an old run of the scheduled job.", "job", name, "id", run.Id, "error", err)
+		}
+	}
+}
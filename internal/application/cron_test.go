@@ -0,0 +1,161 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/database/databasetest"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+func TestCronRunNow(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		app := &App{SqlDb: db}
+		cron := NewCron(app, 3600, time.Second, nil)
+		other := NewCron(app, 3600, time.Second, nil)
+
+		startedCh, releaseCh := make(chan bool), make(chan bool)
+		job := ScheduledJob{Name: "job", Run: func(ctx context.Context, app *App, fence *JobFence) error {
+			startedCh <- true
+			<-releaseCh
+
+			return nil
+		}}
+
+		for _, c := range []*Cron{cron, other} {
+			if err := c.AddJob(job); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := cron.RunNow("unknown"); !errors.Is(err, ErrJobNotFound) {
+			t.Errorf("RunNow() of an unknown job = %v, want ErrJobNotFound", err)
+		}
+
+		if err := cron.RunNow("job"); err != nil {
+			t.Fatalf("RunNow() = %v", err)
+		}
+
+		<-startedCh
+
+		if err := cron.RunNow("job"); !errors.Is(err, ErrJobRunning) {
+			t.Errorf("RunNow() of a running job = %v, want ErrJobRunning", err)
+		}
+
+		if err := other.RunNow("job"); !errors.Is(err, ErrJobLeased) {
+			t.Errorf("RunNow() of a job leased by another instance = %v, want ErrJobLeased", err)
+		}
+
+		if other.Jobs()[0].Running {
+			t.Error("the job is still marked as running after its lease wasn't taken")
+		}
+
+		close(releaseCh)
+		cron.runningJobs.Wait()
+
+		if cron.Jobs()[0].Running {
+			t.Error("the job is still marked as running after it finished")
+		}
+	})
+}
+
+func TestCronFencesOffRunsWhoseLeaseWasLost(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		app := &App{SqlDb: db}
+		cron := NewCron(app, 3600, time.Second, nil)
+
+		startedCh, checkCh := make(chan bool), make(chan error, 1)
+		job := ScheduledJob{Name: "job", Run: func(ctx context.Context, app *App, fence *JobFence) error {
+			startedCh <- true
+			<-ctx.Done()
+
+			checkCh <- fence.Check(nil)
+
+			return ctx.Err()
+		}}
+
+		if err := cron.AddJob(job); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := cron.RunNow("job"); err != nil {
+			t.Fatal(err)
+		}
+
+		<-startedCh
+
+		// the lease expires (e.g. the instance was paused) and another instance takes it over
+		later := time.Now().Add(jobLeaseTtl + time.Minute)
+		generation, err := model.AcquireJobLease("job", "other", int(later.Unix()), int(later.Add(jobLeaseTtl).Unix()), db, nil)
+		if err != nil || generation != 2 {
+			t.Fatalf("AcquireJobLease() by another instance = %d, %v, want 2", generation, err)
+		}
+
+		cron.heartbeat()
+		cron.runningJobs.Wait()
+
+		if err = <-checkCh; !errors.Is(err, ErrJobLeaseLost) {
+			t.Errorf("Check() after the takeover = %v, want ErrJobLeaseLost", err)
+		}
+
+		runs, err := model.AllJobRuns(db, nil, map[string]any{"job": "job"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(runs) != 1 || runs[0].Status != model.JobRunCancelled {
+			t.Errorf("runs %+v, want one cancelled run", runs)
+		}
+	})
+}
+
+func TestCronSkipsDueJobsWhichAreStillRunning(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		app := &App{SqlDb: db}
+		cron := NewCron(app, 3600, time.Second, nil)
+
+		startedCh, releaseCh := make(chan bool), make(chan bool)
+		job := ScheduledJob{Name: "job", Run: func(ctx context.Context, app *App, fence *JobFence) error {
+			startedCh <- true
+			<-releaseCh
+
+			return nil
+		}}
+
+		if err := cron.AddJob(job); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := cron.RunNow("job"); err != nil {
+			t.Fatal(err)
+		}
+
+		<-startedCh
+
+		// the run is still in progress when the job is due
+		cron.jobsMutex.Lock()
+		cron.jobs[0].nextRun = time.Now().Add(-time.Second)
+		cron.jobsMutex.Unlock()
+
+		cron.startDueJobs()
+
+		close(releaseCh)
+		cron.runningJobs.Wait()
+
+		runs, err := model.AllJobRuns(db, nil, map[string]any{"job": "job", "status": model.JobRunSkipped})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(runs) != 1 || runs[0].TriggeredBy != model.JobRunTriggerSchedule {
+			t.Errorf("skipped runs %+v, want one scheduled run", runs)
+		}
+
+		if next := cron.Jobs()[0].NextRun; !next.After(time.Now()) {
+			t.Errorf("next run %v, want a run in the future", next)
+		}
+	})
+}
@@ -0,0 +1,238 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+// Several instances of the application can share one database: each scheduled job is run only by the instance holding
+// its lease (see model.JobLease). Leases are renewed by the heartbeat of the instance, so an instance keeps running the
+// jobs it started running, until it stops; a lease of an instance that died expires after jobLeaseTtl, and the job is
+// taken over by the next instance that finds it due.
+//
+// An instance can lose a lease while its job is still running, e.g. if it was paused or couldn't reach the database for
+// longer than jobLeaseTtl. Every takeover increments the generation of the lease, so the run started with the previous
+// generation is fenced off: the heartbeat cancels its context, and its side effects are checked against the generation
+// (see JobFence).
+const (
+	jobLeaseTtl       = 2 * time.Minute
+	heartbeatInterval = 30 * time.Second
+
+	// heartbeats of stopped instances are kept for a while, so that admins can see them
+	stoppedInstanceRetention = 24 * time.Hour
+)
+
+// newInstanceId returns a unique id of this instance of the application, e.g. "myhost-1a2b3c4d".
+func newInstanceId() (string, string) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return hostname + "-" + uuid.New().String()[:8], hostname
+}
+
+// IsInstanceAlive returns true if the instance sent its heartbeat recently, so its leases are still valid.
+func IsInstanceAlive(heartbeat *model.InstanceHeartbeat, now time.Time) bool {
+	return now.Sub(time.Unix(int64(heartbeat.HeartbeatAt), 0)) < jobLeaseTtl
+}
+
+// Instance returns the id of this instance of the application, as it appears in leases and job runs.
+func (c *Cron) Instance() string {
+	return c.instance
+}
+
+// ErrJobLeaseLost means that the lease a run of a job was started with was taken over by another instance, or couldn't be
+// renewed in time.
+var ErrJobLeaseLost = errors.New("the lease of the job was lost, another instance may run it")
+
+// JobFence is the lease a run of a scheduled job was started with. Jobs check it before side effects which must not be
+// performed twice, by a run which kept going after its instance lost the lease and by the run of the instance which took
+// the lease over.
+type JobFence struct {
+	app        *App
+	job        string
+	holder     string
+	generation int
+}
+
+// Check renews the lease if it's still held with the generation the run was started with, otherwise it returns
+// ErrJobLeaseLost. Called in the transaction of the side effect, it also keeps other instances from taking the lease over
+// until the transaction ends.
+func (fence *JobFence) Check(tx *sql.Tx) error {
+	expiresAt := int(time.Now().Add(jobLeaseTtl).Unix())
+
+	renewed, err := model.RenewJobLease(fence.job, fence.holder, fence.generation, expiresAt, fence.app.SqlDb, tx)
+	if err != nil {
+		return err
+	}
+
+	if !renewed {
+		return ErrJobLeaseLost
+	}
+
+	return nil
+}
+
+// jobRun is a run of a job in progress on this instance.
+type jobRun struct {
+	fence          *JobFence
+	cancel         context.CancelCauseFunc
+	leaseExpiresAt time.Time // as of the last renewal
+}
+
+// acquireLease takes (or renews) the lease of the job for this instance. It returns the generation of the lease, or 0 if
+// another instance holds it.
+func (c *Cron) acquireLease(name string) int {
+	now := time.Now()
+
+	generation, err := model.AcquireJobLease(name, c.instance, int(now.Unix()), int(now.Add(jobLeaseTtl).Unix()), c.app.SqlDb, nil)
+	if err != nil {
+		slog.Error("Failed to acquire the lease of the scheduled job.", "job", name, "error", err)
+
+		return 0
+	}
+
+	if generation == 0 {
+		slog.Debug("Not running the scheduled job, it's run by another instance.", "job", name)
+	}
+
+	return generation
+}
+
+// renewRunLeases renews the leases of the running jobs, with the generations they were started with. Runs whose lease was
+// taken over are cancelled, and so are the ones whose lease couldn't be renewed and would expire before the next heartbeat.
+func (c *Cron) renewRunLeases(now time.Time) {
+	c.jobsMutex.Lock()
+	runs := slices.Collect(maps.Values(c.runs))
+	c.jobsMutex.Unlock()
+
+	for _, run := range runs {
+		expiresAt := now.Add(jobLeaseTtl)
+
+		renewed, err := model.RenewJobLease(run.fence.job, run.fence.holder, run.fence.generation, int(expiresAt.Unix()), c.app.SqlDb, nil)
+		if err != nil {
+			slog.Error("Failed to renew the lease of the running job.", "job", run.fence.job, "error", err)
+
+			if run.leaseExpiresAt.Before(now.Add(heartbeatInterval)) {
+				slog.Warn("Cancelling the running job, its lease expires before it can be renewed again.", "job", run.fence.job)
+
+				run.cancel(ErrJobLeaseLost)
+			}
+
+			continue
+		}
+
+		if !renewed {
+			slog.Warn("Cancelling the running job, its lease was taken over by another instance.", "job", run.fence.job)
+
+			run.cancel(ErrJobLeaseLost)
+
+			continue
+		}
+
+		run.leaseExpiresAt = expiresAt
+	}
+}
+
+// heartbeat shows that this instance is alive, renews its leases, and marks runs of instances that died as failed.
+func (c *Cron) heartbeat() {
+	now := time.Now()
+
+	heartbeat := model.InstanceHeartbeat{
+		Instance:    c.instance,
+		Hostname:    c.hostname,
+		StartedAt:   int(c.startedAt.Unix()),
+		HeartbeatAt: int(now.Unix()),
+	}
+
+	if err := heartbeat.Save(c.app.SqlDb, nil); err != nil {
+		slog.Error("Failed to save the heartbeat of the instance.", "instance", c.instance, "error", err)
+	}
+
+	if err := model.RenewJobLeases(c.instance, int(now.Add(jobLeaseTtl).Unix()), c.app.SqlDb, nil); err != nil {
+		slog.Error("Failed to renew the leases of scheduled jobs.", "instance", c.instance, "error", err)
+	}
+
+	c.renewRunLeases(now)
+
+	heartbeats, err := model.AllInstanceHeartbeats(c.app.SqlDb, nil, nil)
+	if err != nil {
+		slog.Error("Failed to load heartbeats of instances.", "error", err)
+
+		return
+	}
+
+	aliveInstances := make(map[string]bool)
+	for _, heartbeat := range heartbeats {
+		if IsInstanceAlive(&heartbeat, now) {
+			aliveInstances[heartbeat.Instance] = true
+		} else if now.Sub(time.Unix(int64(heartbeat.HeartbeatAt), 0)) > stoppedInstanceRetention {
+			if err = heartbeat.Delete(c.app.SqlDb, nil); err != nil {
+				slog.Error("Failed to delete the heartbeat of a stopped instance.", "instance", heartbeat.Instance, "error", err)
+			}
+		}
+	}
+
+	c.failInterruptedRuns(aliveInstances)
+}
+
+// releaseLeases gives up the leases of this instance when it stops, so that other instances can take the jobs over right
+// away, without waiting for the leases to expire.
+func (c *Cron) releaseLeases() {
+	leases, err := model.AllJobLeases(c.app.SqlDb, nil, map[string]any{"holder": c.instance})
+	if err != nil {
+		slog.Error("Failed to load the leases of scheduled jobs.", "instance", c.instance, "error", err)
+
+		return
+	}
+
+	for _, lease := range leases {
+		if err = lease.Delete(c.app.SqlDb, nil); err != nil {
+			slog.Error("Failed to release the lease of the scheduled job.", "job", lease.Job, "error", err)
+		}
+	}
+
+	heartbeat := model.InstanceHeartbeat{
+		Instance:    c.instance,
+		Hostname:    c.hostname,
+		StartedAt:   int(c.startedAt.Unix()),
+		HeartbeatAt: int(time.Now().Unix()) - int(jobLeaseTtl.Seconds()), // shown as stopped
+	}
+
+	if err = heartbeat.Save(c.app.SqlDb, nil); err != nil {
+		slog.Error("Failed to save the heartbeat of the instance.", "instance", c.instance, "error", err)
+	}
+}
+
+// failInterruptedRuns marks runs that were still in progress when their instance stopped (e.g. it crashed) as failed.
+func (c *Cron) failInterruptedRuns(aliveInstances map[string]bool) {
+	interruptedRuns, err := model.AllJobRuns(c.app.SqlDb, nil, map[string]any{"status": model.JobRunRunning})
+	if err != nil {
+		slog.Error("Failed to load interrupted runs of scheduled jobs.", "error", err)
+
+		return
+	}
+
+	for _, run := range interruptedRuns {
+		if run.Instance == c.instance || aliveInstances[run.Instance] {
+			continue
+		}
+
+		run.Status = model.JobRunFailed
+		run.Error = "interrupted, the instance running it stopped"
+
+		if err = run.Save(c.app.SqlDb, nil); err != nil {
+			slog.Error("Failed to mark an interrupted run of a scheduled job as failed.", "job", run.Job, "id", run.Id, "error", err)
+		}
+	}
+}
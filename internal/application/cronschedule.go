@@ -0,0 +1,208 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tells when a scheduled job runs next.
+type Schedule interface {
+	// Next returns the first run time after the given time, or the zero time if the job never runs again.
+	Next(after time.Time) time.Time
+	String() string
+}
+
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+// Every returns a schedule which runs the job at the given interval, counted from the previous (scheduled) run.
+func Every(interval time.Duration) Schedule {
+	return intervalSchedule{interval: interval}
+}
+
+func (schedule intervalSchedule) Next(after time.Time) time.Time {
+	return after.Add(schedule.interval)
+}
+
+func (schedule intervalSchedule) String() string {
+	return "every " + schedule.interval.String()
+}
+
+// cronSchedule is a standard 5 field cron expression (minute, hour, day of month, month, day of week), in UTC. Each field
+// is a bit set of the values it matches.
+type cronSchedule struct {
+	expression string
+
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+
+	// if both day fields are restricted, a day matching either of them is matched (like in the classic cron)
+	dayOfMonthRestricted, dayOfWeekRestricted bool
+}
+
+var cronDescriptors = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// ParseCronExpression parses a cron expression, e.g. "*/15 * * * *" or "30 3 * * 1-5". Fields support *, numbers, ranges
+// (1-5), lists (1,3,5) and steps (*/15, 0-30/10). Days of week are 0-7, both 0 and 7 being Sunday. The @hourly, @daily,
+// @weekly, @monthly and @yearly descriptors are supported too.
+func ParseCronExpression(expression string) (Schedule, error) {
+	fieldsExpression := strings.TrimSpace(expression)
+	if descriptor, found := cronDescriptors[fieldsExpression]; found {
+		fieldsExpression = descriptor
+	}
+
+	fields := strings.Fields(fieldsExpression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expression)
+	}
+
+	schedule := cronSchedule{expression: expression}
+
+	var err error
+	if schedule.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid minute in cron expression %q: %w", expression, err)
+	}
+
+	if schedule.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid hour in cron expression %q: %w", expression, err)
+	}
+
+	if schedule.dayOfMonth, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid day of month in cron expression %q: %w", expression, err)
+	}
+
+	if schedule.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid month in cron expression %q: %w", expression, err)
+	}
+
+	if schedule.dayOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid day of week in cron expression %q: %w", expression, err)
+	}
+
+	// 7 is Sunday too
+	if schedule.dayOfWeek&(1<<7) != 0 {
+		schedule.dayOfWeek |= 1
+	}
+
+	schedule.dayOfMonthRestricted = !strings.HasPrefix(fields[2], "*")
+	schedule.dayOfWeekRestricted = !strings.HasPrefix(fields[4], "*")
+
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("cron expression %q never matches", expression)
+	}
+
+	return schedule, nil
+}
+
+// parseCronField returns the bit set of values matched by the field.
+func parseCronField(field string, min int, max int) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		first, last := min, max
+		if rangePart != "*" {
+			firstPart, lastPart, isRange := strings.Cut(rangePart, "-")
+
+			var err error
+			if first, err = strconv.Atoi(firstPart); err != nil {
+				return 0, fmt.Errorf("invalid value %q", firstPart)
+			}
+
+			last = first
+			if isRange {
+				if last, err = strconv.Atoi(lastPart); err != nil {
+					return 0, fmt.Errorf("invalid value %q", lastPart)
+				}
+			} else if hasStep {
+				// 5/15 means from 5 to the end, every 15
+				last = max
+			}
+		}
+
+		if first < min || last > max || first > last {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for value := first; value <= last; value += step {
+			bits |= 1 << value
+		}
+	}
+
+	if bits == 0 {
+		return 0, errors.New("empty field")
+	}
+
+	return bits, nil
+}
+
+func (schedule cronSchedule) dayMatches(t time.Time) bool {
+	dayOfMonthMatches := schedule.dayOfMonth&(1<<t.Day()) != 0
+	dayOfWeekMatches := schedule.dayOfWeek&(1<<int(t.Weekday())) != 0
+
+	if schedule.dayOfMonthRestricted && schedule.dayOfWeekRestricted {
+		return dayOfMonthMatches || dayOfWeekMatches
+	}
+
+	return dayOfMonthMatches && dayOfWeekMatches
+}
+
+func (schedule cronSchedule) Next(after time.Time) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+
+	// the expression might match e.g. only on February 29th, a few years are enough to find it
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if schedule.month&(1<<int(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+
+			continue
+		}
+
+		if !schedule.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+
+			continue
+		}
+
+		if schedule.hour&(1<<t.Hour()) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+
+			continue
+		}
+
+		if schedule.minute&(1<<t.Minute()) == 0 {
+			t = t.Add(time.Minute)
+
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (schedule cronSchedule) String() string {
+	return "cron " + schedule.expression
+}
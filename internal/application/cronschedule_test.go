@@ -0,0 +1,100 @@
+package application
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronExpressionRejectsInvalidExpressions(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"1,,2 * * * *",
+		"* * 31 2 *", // never matches
+		"* * 30 2 *",
+		"@reboot",
+	} {
+		if _, err := ParseCronExpression(expression); err == nil {
+			t.Errorf("ParseCronExpression(%q) succeeded, want an error", expression)
+		}
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	date := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		expression string
+		after      time.Time
+		want       []time.Time // consecutive runs
+	}{
+		{"* * * * *", date(2025, 1, 1, 10, 0).Add(30 * time.Second), []time.Time{date(2025, 1, 1, 10, 1), date(2025, 1, 1, 10, 2)}},
+		{"*/15 * * * *", date(2025, 1, 1, 10, 1), []time.Time{date(2025, 1, 1, 10, 15), date(2025, 1, 1, 10, 30), date(2025, 1, 1, 10, 45), date(2025, 1, 1, 11, 0)}},
+		{"5/15 * * * *", date(2025, 1, 1, 10, 50), []time.Time{date(2025, 1, 1, 11, 5), date(2025, 1, 1, 11, 20), date(2025, 1, 1, 11, 35), date(2025, 1, 1, 11, 50)}},
+		{"0-30/10 * * * *", date(2025, 1, 1, 10, 25), []time.Time{date(2025, 1, 1, 10, 30), date(2025, 1, 1, 11, 0), date(2025, 1, 1, 11, 10)}},
+		{"0 8,12-13,18 * * *", date(2025, 1, 1, 9, 0), []time.Time{date(2025, 1, 1, 12, 0), date(2025, 1, 1, 13, 0), date(2025, 1, 1, 18, 0), date(2025, 1, 2, 8, 0)}},
+		{"30 3 * * 1-5", date(2025, 1, 3, 4, 0), []time.Time{date(2025, 1, 6, 3, 30), date(2025, 1, 7, 3, 30)}}, // Friday after the run
+		{"0 0 * * 7", date(2025, 1, 1, 0, 0), []time.Time{date(2025, 1, 5, 0, 0), date(2025, 1, 12, 0, 0)}},
+		{"0 0 * * 0", date(2025, 1, 1, 0, 0), []time.Time{date(2025, 1, 5, 0, 0), date(2025, 1, 12, 0, 0)}},
+		{"0 0 * * 5-7", date(2025, 1, 1, 0, 0), []time.Time{date(2025, 1, 3, 0, 0), date(2025, 1, 4, 0, 0), date(2025, 1, 5, 0, 0), date(2025, 1, 10, 0, 0)}},
+		// both day fields restricted, either of them matches
+		{"0 0 13 * 5", date(2025, 6, 1, 0, 0), []time.Time{date(2025, 6, 6, 0, 0), date(2025, 6, 13, 0, 0), date(2025, 6, 20, 0, 0)}},
+		// a step over * doesn't restrict the field (like in the classic cron), so both of them have to match
+		{"0 0 */10 * 1", date(2025, 6, 1, 0, 0), []time.Time{date(2025, 7, 21, 0, 0), date(2025, 8, 11, 0, 0)}},
+		{"0 0 31 * *", date(2025, 1, 31, 12, 0), []time.Time{date(2025, 3, 31, 0, 0), date(2025, 5, 31, 0, 0)}},
+		{"59 23 31 12 *", date(2025, 12, 31, 23, 59), []time.Time{date(2026, 12, 31, 23, 59)}},
+		{"0 12 29 2 *", date(2025, 3, 1, 0, 0), []time.Time{date(2028, 2, 29, 12, 0), date(2032, 2, 29, 12, 0)}},
+		{"0 0 1 1,7 *", date(2025, 2, 1, 0, 0), []time.Time{date(2025, 7, 1, 0, 0), date(2026, 1, 1, 0, 0)}},
+		{"@hourly", date(2025, 1, 1, 10, 0), []time.Time{date(2025, 1, 1, 11, 0)}},
+		{"@daily", date(2025, 1, 1, 10, 0), []time.Time{date(2025, 1, 2, 0, 0)}},
+		{"@weekly", date(2025, 1, 1, 10, 0), []time.Time{date(2025, 1, 5, 0, 0)}},
+		{"@monthly", date(2025, 1, 1, 10, 0), []time.Time{date(2025, 2, 1, 0, 0)}},
+		{"@yearly", date(2025, 1, 1, 10, 0), []time.Time{date(2026, 1, 1, 0, 0)}},
+	}
+
+	for _, test := range tests {
+		schedule, err := ParseCronExpression(test.expression)
+		if err != nil {
+			t.Errorf("ParseCronExpression(%q) failed: %v", test.expression, err)
+
+			continue
+		}
+
+		after := test.after
+		for _, want := range test.want {
+			if got := schedule.Next(after); !got.Equal(want) {
+				t.Errorf("%q: Next(%v) = %v, want %v", test.expression, after, got, want)
+
+				break
+			}
+
+			after = want
+		}
+	}
+}
+
+func TestCronScheduleNextInOtherTimeZone(t *testing.T) {
+	schedule, err := ParseCronExpression("0 3 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	belgrade := time.FixedZone("CET", 3600)
+
+	got := schedule.Next(time.Date(2025, 1, 1, 3, 30, 0, 0, belgrade)) // 2:30 UTC
+	if want := time.Date(2025, 1, 1, 3, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next() = %v, want %v", got, want)
+	}
+}
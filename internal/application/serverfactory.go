@@ -18,6 +18,9 @@ import (
 type Server interface {
 	ListenAndServe()
 	AddRoute(pattern string, handler func(http.ResponseWriter, *http.Request))
+	// OnShutdown registers a function called when the shutdown starts, e.g. to end long-lived requests, which the
+	// shutdown waits for otherwise
+	OnShutdown(f func())
 }
 
 // concrete implementations of the Server interface
@@ -43,6 +46,10 @@ func (s *HTTPServer) AddRoute(pattern string, handler func(http.ResponseWriter,
 	s.httpMux.HandleFunc(pattern, handler)
 }
 
+func (s *HTTPServer) OnShutdown(f func()) {
+	s.httpServer.RegisterOnShutdown(f)
+}
+
 func (s *HTTPServer) ListenAndServe() {
 	// termination handling
 	quit := make(chan os.Signal, 1)
@@ -95,6 +102,10 @@ func (s *HTTPSServer) AddRoute(pattern string, handler func(http.ResponseWriter,
 	s.httpsMux.HandleFunc(pattern, handler)
 }
 
+func (s *HTTPSServer) OnShutdown(f func()) {
+	s.httpsServer.RegisterOnShutdown(f)
+}
+
 func (s *HTTPSServer) ListenAndServe() {
 	// termination handling
 	quit := make(chan os.Signal, 1)
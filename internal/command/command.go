@@ -0,0 +1,66 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+)
+
+type commandFunc func(app *application.App, args []string) error
+
+type command struct {
+	usage string
+	run   commandFunc
+}
+
+// commands are grouped by the object they work with, e.g. "places import <file>"
+var commands = map[string]map[string]command{
+	"places": {
+		"import": {usage: "places import <file.txt|file.csv>", run: importPlaces},
+	},
+	"locations": {
+		"import": {usage: "locations import <file.gpx|file.geojson|file.csv> [dedupe distance km]", run: importLocations},
+		"export": {usage: "locations export <file.gpx|file.geojson|file.csv>", run: exportLocations},
+	},
+	"course": {
+		"import-pois": {usage: "course import-pois <start location id> <end location id> <file.gpx|file.geojson|file.csv> [max distance km]", run: importCoursePois},
+	},
+	"db": {
+		"copy": {usage: "db copy <sqlite file>", run: copySqliteDatabase},
+	},
+}
+
+// Run executes the maintenance command given in command line arguments, instead of starting the server.
+func Run(app *application.App, args []string) error {
+	if len(args) < 2 {
+		return usageError()
+	}
+
+	group, ok := commands[args[0]]
+	if !ok {
+		return usageError()
+	}
+
+	cmd, ok := group[args[1]]
+	if !ok {
+		return usageError()
+	}
+
+	if err := cmd.run(app, args[2:]); err != nil {
+		return fmt.Errorf("%s: %w", cmd.usage, err)
+	}
+
+	return nil
+}
+
+func usageError() error {
+	usage := "usage:"
+	for _, group := range commands {
+		for _, cmd := range group {
+			usage += "\n  " + cmd.usage
+		}
+	}
+
+	return errors.New(usage)
+}
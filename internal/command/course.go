@@ -0,0 +1,78 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+// importCoursePois replaces places along the course between two locations with points of interest from a file.
+// Points farther than max distance (2 km by default) from the course are skipped.
+func importCoursePois(app *application.App, args []string) error {
+	if len(args) != 3 && len(args) != 4 {
+		return errors.New("start and end location ids and a file have to be provided")
+	}
+
+	startLocationId, err := strconv.Atoi(args[0])
+	if err != nil {
+		return err
+	}
+
+	endLocationId, err := strconv.Atoi(args[1])
+	if err != nil {
+		return err
+	}
+
+	maxDistanceKm := 2.0
+	if len(args) == 4 {
+		if maxDistanceKm, err = strconv.ParseFloat(args[3], 64); err != nil {
+			return err
+		}
+	}
+
+	courseDbName := model.CourseDbName(startLocationId, endLocationId)
+
+	exists, err := app.FileDb.Exists("course", courseDbName)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return errors.New("course between these locations was not fetched yet, start an adventure on it first")
+	}
+
+	route := model.NewDirectionsRoute()
+	if err = app.FileDb.Read("course", courseDbName, route); err != nil {
+		return err
+	}
+
+	routePolyline, _, err := helper.DecodePolyline(route.Geometry, route.Elevation, false)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(args[2])
+	if err != nil {
+		return err
+	}
+
+	waypoints, err := helper.ParseWaypointsFile(args[2], content)
+	if err != nil {
+		return err
+	}
+
+	coursePlaces := helper.SnapWaypointsToCourse(routePolyline, waypoints, maxDistanceKm)
+
+	if err = app.FileDb.Write("courseplaces", courseDbName, &coursePlaces); err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d of %d points of interest.\n", len(coursePlaces.Places), len(waypoints))
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package command
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/database"
+)
+
+// copiedTables are copied in this order, so that rows are copied after the rows they reference
+var copiedTables = []string{
+	"Athlete", "StravaCredential", "AthleteSettings", "Activity", "PendingActivity",
+	"Location", "Adventure", "ImportedRoute", "Place", "GeocodeCache", "AdventurePassedPlace", "CuratedAdventure",
+	"AdventureMapImage", "AdventurePublicLink", "ApiToken", "WebhookEndpoint", "WebhookDelivery", "ActivitySync",
+	"OutboxMessage", "JobRun", "JobLease", "InstanceHeartbeat",
+}
+
+// tables with generated ids, their sequences have to continue after the copied ids
+var identityTables = []string{
+	"Location", "ImportedRoute", "Place", "CuratedAdventure", "ApiToken", "WebhookEndpoint", "WebhookDelivery",
+	"ActivitySync", "OutboxMessage", "JobRun",
+}
+
+// copySqliteDatabase copies all data from a SQLite database file into the configured database (e.g. when moving to
+// PostgreSQL), in one transaction. The configured database has to be empty, its schema is created on startup.
+func copySqliteDatabase(app *application.App, args []string) error {
+	if len(args) != 1 {
+		return errors.New("exactly one SQLite database file has to be provided")
+	}
+
+	// opening a missing file would create an empty database
+	if _, err := os.Stat(args[0]); err != nil {
+		return err
+	}
+
+	source := database.CreateSQLiteDatabase(args[0])
+	defer source.Close()
+
+	// the source gets the schema of this version first, as the target has it
+	if err := database.Migrate(source, database.DriverSQLite); err != nil {
+		return fmt.Errorf("failed to migrate the source database: %w", err)
+	}
+
+	sourceTables, err := sqliteTables(source)
+	if err != nil {
+		return err
+	}
+
+	tx, err := app.SqlDb.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range copiedTables {
+		if !sourceTables[table] {
+			fmt.Printf("Skipped %s, it's missing in the source database.\n", table)
+
+			continue
+		}
+
+		copied, err := copyTable(table, source, tx)
+		if err != nil {
+			return fmt.Errorf("failed to copy %s: %w", table, err)
+		}
+
+		fmt.Printf("Copied %d rows of %s.\n", copied, table)
+	}
+
+	if app.SqlDbDriver == database.DriverPostgres {
+		for _, table := range identityTables {
+			if _, err = tx.Exec(fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', 'id'), MAX(id)) FROM %s", table, table)); err != nil {
+				return fmt.Errorf("failed to reset the id sequence of %s: %w", table, err)
+			}
+		}
+	}
+
+	return database.CommitOrRollbackTransaction(tx)
+}
+
+func sqliteTables(db *sql.DB) (map[string]bool, error) {
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type='table'")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tables := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+
+		tables[name] = true
+	}
+
+	return tables, rows.Err()
+}
+
+// copyTable copies all rows of the table, column by column as named in the source, it refuses to copy into a table which
+// already has rows.
+func copyTable(table string, source *sql.DB, tx *sql.Tx) (int, error) {
+	var existing int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&existing); err != nil {
+		return 0, err
+	}
+
+	if existing > 0 {
+		return 0, errors.New("the table is not empty in the target database")
+	}
+
+	rows, err := source.Query("SELECT * FROM " + table)
+	if err != nil {
+		return 0, err
+	}
+
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	insert, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s(%s) VALUES(%s)", table, strings.Join(columns, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")))
+	if err != nil {
+		return 0, err
+	}
+
+	defer insert.Close()
+
+	values := make([]any, len(columns))
+	valuePtrs := make([]any, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	copied := 0
+	for rows.Next() {
+		if err = rows.Scan(valuePtrs...); err != nil {
+			return copied, err
+		}
+
+		if _, err = insert.Exec(values...); err != nil {
+			return copied, err
+		}
+
+		copied++
+	}
+
+	return copied, rows.Err()
+}
@@ -0,0 +1,72 @@
+package command
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/database"
+	"github.com/miki208/stravaadventuregame/internal/database/databasetest"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+func TestCopySqliteDatabase(t *testing.T) {
+	sourcePath := filepath.Join(t.TempDir(), "app.db")
+
+	source := database.CreateSQLiteDatabase(sourcePath)
+	if err := database.Migrate(source, database.DriverSQLite); err != nil {
+		t.Fatal(err)
+	}
+
+	athlete := model.Athlete{Id: 1, FirstName: "Jane"}
+	if err := athlete.Save(source, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	start, end := model.Location{Name: "Belgrade", Lat: 44.8, Lon: 20.4}, model.Location{Name: "Novi Sad", Lat: 45.2, Lon: 19.8}
+	for _, location := range []*model.Location{&start, &end} {
+		if err := location.Save(source, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	adventure := model.Adventure{AthleteId: 1, StartLocation: start.Id, EndLocation: end.Id, TotalDistance: 94, CurrentDistance: 12.5}
+	if err := adventure.Insert(source, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	source.Close()
+
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		app := &application.App{SqlDb: db, SqlDbDriver: driver}
+
+		if err := copySqliteDatabase(app, []string{sourcePath}); err != nil {
+			t.Fatalf("copySqliteDatabase() failed: %v", err)
+		}
+
+		var copied model.Adventure
+		found, err := copied.Load(1, start.Id, end.Id, db, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !found || copied.CurrentDistance != 12.5 {
+			t.Errorf("copied adventure = %v, %+v, want the adventure with 12.5 km", found, copied)
+		}
+
+		// generated ids continue after the copied ones
+		location := model.Location{Name: "Nis", Lat: 43.3, Lon: 21.9}
+		if err = location.Save(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if location.Id <= end.Id {
+			t.Errorf("id of a new location = %d, want more than %d", location.Id, end.Id)
+		}
+
+		if err = copySqliteDatabase(app, []string{sourcePath}); err == nil {
+			t.Error("copySqliteDatabase() into a database with rows succeeded, want an error")
+		}
+	})
+}
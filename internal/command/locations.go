@@ -0,0 +1,92 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/database"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+// default distance under which locations with the same name are considered duplicates
+const defaultDedupeDistanceKm = 10
+
+func importLocations(app *application.App, args []string) error {
+	if len(args) != 1 && len(args) != 2 {
+		return errors.New("a file has to be provided")
+	}
+
+	dedupeDistanceKm := float64(defaultDedupeDistanceKm)
+	if len(args) == 2 {
+		var err error
+		if dedupeDistanceKm, err = strconv.ParseFloat(args[1], 64); err != nil {
+			return err
+		}
+	}
+
+	content, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	waypoints, err := helper.ParseWaypointsFile(args[0], content)
+	if err != nil {
+		return err
+	}
+
+	tx, err := app.SqlDb.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := helper.ImportLocations(waypoints, dedupeDistanceKm, app.SqlDb, tx)
+	if err != nil {
+		return err
+	}
+
+	if err = database.CommitOrRollbackTransaction(tx); err != nil {
+		return err
+	}
+
+	for _, location := range result.Created {
+		fmt.Printf("Created %s (id %d).\n", location.Name, location.Id)
+	}
+
+	for _, skipped := range result.Skipped {
+		fmt.Printf("Skipped %q: %s.\n", skipped.Waypoint.Name, skipped.Reason)
+	}
+
+	fmt.Printf("Created %d, skipped %d locations.\n", len(result.Created), len(result.Skipped))
+
+	return nil
+}
+
+func exportLocations(app *application.App, args []string) error {
+	if len(args) != 1 {
+		return errors.New("exactly one file has to be provided")
+	}
+
+	// private locations of athletes are not a part of the catalogue
+	locations, err := model.AllLocations(app.SqlDb, nil, map[string]any{"owner_id": 0})
+	if err != nil {
+		return err
+	}
+
+	content, err := helper.WriteWaypointsFile(args[0], helper.LocationsToWaypoints(locations))
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(args[0], content, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d locations.\n", len(locations))
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/database"
+	"github.com/miki208/stravaadventuregame/internal/service/geocoding"
+)
+
+func importPlaces(app *application.App, args []string) error {
+	if len(args) != 1 {
+		return errors.New("exactly one file has to be provided")
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	tx, err := app.SqlDb.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var imported int
+	switch strings.ToLower(filepath.Ext(args[0])) {
+	case ".txt", ".tsv":
+		imported, err = geocoding.ImportGeoNames(file, app.SqlDb, tx)
+	case ".csv":
+		imported, err = geocoding.ImportPlacesCsv(file, app.SqlDb, tx)
+	default:
+		return errors.New("unsupported file format, expected a GeoNames dump (.txt) or a csv file")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err = database.CommitOrRollbackTransaction(tx); err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d places.\n", imported)
+
+	return nil
+}
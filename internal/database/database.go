@@ -0,0 +1,125 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// supported SQL databases, each has its own migrations in migrations/<driver>/
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+//go:embed migrations
+var migrations embed.FS
+
+// CreateDatabase opens the SQL database of the driver; source is the path of the SQLite file, or the connection string
+// of PostgreSQL.
+func CreateDatabase(driver, source string) *sql.DB {
+	switch driver {
+	case DriverSQLite:
+		return CreateSQLiteDatabase(source)
+	case DriverPostgres:
+		return CreatePostgresDatabase(source)
+	default:
+		panic(fmt.Errorf("unsupported database driver %s", driver))
+	}
+}
+
+// CommitOrRollbackTransaction commits the transaction, or rolls it back if the commit fails.
+func CommitOrRollbackTransaction(tx *sql.Tx) error {
+	err := tx.Commit()
+	if err != nil {
+		tx.Rollback()
+
+		return err
+	}
+
+	return nil
+}
+
+// Migrate brings the schema of the database up to date: migrations of the driver which weren't applied yet are applied
+// in order of their versions (file names), each in its own transaction, and recorded in SchemaMigration.
+func Migrate(db *sql.DB, driver string) error {
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS SchemaMigration (version TEXT NOT NULL PRIMARY KEY, applied_at BIGINT NOT NULL)")
+	if err != nil {
+		return err
+	}
+
+	applied := make(map[string]bool)
+
+	rows, err := db.Query("SELECT version FROM SchemaMigration")
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var version string
+		if err = rows.Scan(&version); err != nil {
+			return err
+		}
+
+		applied[version] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	fileNames, err := fs.Glob(migrations, "migrations/"+driver+"/*.sql")
+	if err != nil {
+		return err
+	}
+
+	if len(fileNames) == 0 {
+		return fmt.Errorf("no migrations for database driver %s", driver)
+	}
+
+	sort.Strings(fileNames)
+
+	for _, fileName := range fileNames {
+		version := strings.TrimSuffix(fileName[strings.LastIndex(fileName, "/")+1:], ".sql")
+		if applied[version] {
+			continue
+		}
+
+		script, err := migrations.ReadFile(fileName)
+		if err != nil {
+			return err
+		}
+
+		slog.Info("Applying database migration...", "driver", driver, "version", version)
+
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.Exec(string(script)); err != nil {
+			tx.Rollback()
+
+			return fmt.Errorf("migration %s failed: %w", version, err)
+		}
+
+		if _, err = tx.Exec("INSERT INTO SchemaMigration(version, applied_at) VALUES(?, ?)", version, time.Now().Unix()); err != nil {
+			tx.Rollback()
+
+			return err
+		}
+
+		if err = CommitOrRollbackTransaction(tx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
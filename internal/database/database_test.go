@@ -0,0 +1,259 @@
+package database_test
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miki208/stravaadventuregame/internal/database"
+	"github.com/miki208/stravaadventuregame/internal/database/databasetest"
+)
+
+func TestMigrateUpgradesBaselineSQLiteDatabase(t *testing.T) {
+	db := databasetest.Open(t, database.DriverSQLite)
+
+	baseline, err := os.ReadFile("testdata/baseline.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = db.Exec(string(baseline)); err != nil {
+		t.Fatalf("failed to create the baseline database: %v", err)
+	}
+
+	if err = database.Migrate(db, database.DriverSQLite); err != nil {
+		t.Fatalf("Migrate() failed: %v", err)
+	}
+
+	// the rows are kept, new columns get their defaults
+	var name, country string
+	var ownerId int
+	err = db.QueryRow("SELECT name, country, owner_id FROM Location WHERE id=?", 2).Scan(&name, &country, &ownerId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name != "Novi Sad" || country != "" || ownerId != 0 {
+		t.Errorf("location 2 = (%q, %q, %d), want (\"Novi Sad\", \"\", 0)", name, country, ownerId)
+	}
+
+	var currentLocationName string
+	err = db.QueryRow("SELECT current_location_name FROM Adventure WHERE athlete_id=? AND start_location=? AND end_location=?", 1, 1, 2).Scan(&currentLocationName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if currentLocationName != "Stara Pazova" {
+		t.Errorf("current location of the adventure = %q, want \"Stara Pazova\"", currentLocationName)
+	}
+
+	// locations used by adventures can't be deleted anymore, the adventures used to be deleted with them
+	if _, err = db.Exec("DELETE FROM Location WHERE id=?", 1); err == nil {
+		t.Error("deleting a location used by an adventure succeeded, want a foreign key error")
+	}
+
+	if _, err = db.Exec("DELETE FROM Location WHERE id=?", 3); err != nil {
+		t.Errorf("deleting an unused location failed: %v", err)
+	}
+
+	// ids of deleted locations are not reused
+	var id int
+	if err = db.QueryRow("INSERT INTO Location(lat, lon, name) VALUES(?, ?, ?) RETURNING id", 44.0, 20.9, "Kragujevac").Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+
+	if id != 4 {
+		t.Errorf("id of the new location = %d, want 4", id)
+	}
+
+	// tables added after the baseline exist
+	if _, err = db.Exec("INSERT INTO AdventurePassedPlace VALUES(1, 1, 2, 10.5, 'Zemun', 100, 1700000100)"); err != nil {
+		t.Errorf("failed to insert into a new table: %v", err)
+	}
+
+	assertMigrationsApplied(t, db, database.DriverSQLite)
+}
+
+func TestMigrate(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		assertMigrationsApplied(t, db, driver)
+	})
+}
+
+func TestMigrateCreatesSQLiteDatabase(t *testing.T) {
+	db := databasetest.OpenMigrated(t, database.DriverSQLite)
+
+	var onDelete string
+	err := db.QueryRow("SELECT on_delete FROM pragma_foreign_key_list('Adventure') WHERE \"table\"='Location' LIMIT 1").Scan(&onDelete)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if onDelete != "RESTRICT" {
+		t.Errorf("Adventure references Location with ON DELETE %s, want RESTRICT", onDelete)
+	}
+}
+
+// The models rely on RETURNING for generated ids and on ON CONFLICT for upserts, both databases have to support them
+// with the same syntax.
+func TestReturningAndOnConflict(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		var first, second int64
+		if err := db.QueryRow("INSERT INTO Location(lat, lon, name) VALUES(?, ?, ?) RETURNING id", 44.8, 20.4, "Belgrade").Scan(&first); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := db.QueryRow("INSERT INTO Location(lat, lon, name) VALUES(?, ?, ?) RETURNING id", 45.2, 19.8, "Novi Sad").Scan(&second); err != nil {
+			t.Fatal(err)
+		}
+
+		if first <= 0 || second <= first {
+			t.Errorf("returned ids %d and %d, want increasing positive ids", first, second)
+		}
+
+		upsert := "INSERT INTO GeocodeCache(key, name, created_at) VALUES(?, ?, ?) ON CONFLICT(key) DO UPDATE SET name=excluded.name, created_at=excluded.created_at"
+		for _, name := range []string{"Zemun", "Batajnica"} {
+			if _, err := db.Exec(upsert, "44.85,20.40", name, 1700000000); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var name string
+		if err := db.QueryRow("SELECT name FROM GeocodeCache WHERE key=?", "44.85,20.40").Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+
+		if name != "Batajnica" {
+			t.Errorf("name after the upsert = %q, want \"Batajnica\"", name)
+		}
+
+		insertIgnored := "INSERT INTO JobLease(job, holder, acquired_at, expires_at) VALUES(?, ?, ?, ?) ON CONFLICT(job) DO NOTHING"
+		for _, holder := range []string{"a", "b"} {
+			if _, err := db.Exec(insertIgnored, "job", holder, 1, 2); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var holder string
+		if err := db.QueryRow("SELECT holder FROM JobLease WHERE job=?", "job").Scan(&holder); err != nil {
+			t.Fatal(err)
+		}
+
+		if holder != "a" {
+			t.Errorf("holder after the ignored insert = %q, want \"a\"", holder)
+		}
+	})
+}
+
+func TestMigrateMovesRetryStateToPendingActivity(t *testing.T) {
+	versions := map[string]string{
+		database.DriverSQLite:   "0003_pending_activity_retry_state",
+		database.DriverPostgres: "0002_pending_activity_retry_state",
+	}
+
+	for _, driver := range databasetest.Drivers {
+		t.Run(driver, func(t *testing.T) {
+			db := databasetest.Open(t, driver)
+			migrateBefore(t, db, driver, versions[driver])
+
+			for _, query := range []string{
+				"INSERT INTO PendingActivity(id, owner_id, aspect_type, event_time) VALUES(1, 10, 'create', 100)",
+				"INSERT INTO PendingActivity(id, owner_id, aspect_type, event_time) VALUES(2, 10, 'update', 200)",
+				"INSERT INTO PendingActivityRetry(activity_id, attempts, last_error, next_attempt_at, dead, updated_at) VALUES(1, 6, 'failed', 0, 1, 300)",
+			} {
+				if _, err := db.Exec(query); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := database.Migrate(db, driver); err != nil {
+				t.Fatalf("Migrate() failed: %v", err)
+			}
+
+			tests := []struct {
+				id       int
+				attempts int
+				lastErr  string
+				dead     int
+				failedAt int
+			}{
+				{1, 6, "failed", 1, 300},
+				{2, 0, "", 0, 0},
+			}
+
+			for _, test := range tests {
+				var attempts, dead, failedAt int
+				var lastErr string
+				err := db.QueryRow("SELECT attempts, last_error, dead, failed_at FROM PendingActivity WHERE id=?", test.id).Scan(&attempts, &lastErr, &dead, &failedAt)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if attempts != test.attempts || lastErr != test.lastErr || dead != test.dead || failedAt != test.failedAt {
+					t.Errorf("event %d: (%d, %q, %d, %d), want (%d, %q, %d, %d)", test.id, attempts, lastErr, dead, failedAt,
+						test.attempts, test.lastErr, test.dead, test.failedAt)
+				}
+			}
+		})
+	}
+}
+
+// migrateBefore applies the migrations of the driver older than the version, as Migrate would have done before the
+// version was added.
+func migrateBefore(t *testing.T, db *sql.DB, driver, version string) {
+	t.Helper()
+
+	if _, err := db.Exec("CREATE TABLE SchemaMigration (version TEXT NOT NULL PRIMARY KEY, applied_at BIGINT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+
+	fileNames, err := filepath.Glob(filepath.Join("migrations", driver, "*.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fileName := range fileNames {
+		olderVersion := strings.TrimSuffix(filepath.Base(fileName), ".sql")
+		if olderVersion >= version {
+			break
+		}
+
+		script, err := os.ReadFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = db.Exec(string(script)); err != nil {
+			t.Fatalf("migration %s failed: %v", olderVersion, err)
+		}
+
+		if _, err = db.Exec("INSERT INTO SchemaMigration(version, applied_at) VALUES(?, 0)", olderVersion); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// assertMigrationsApplied checks that all migrations of the driver are recorded, and that migrating again changes nothing.
+func assertMigrationsApplied(t *testing.T, db *sql.DB, driver string) {
+	t.Helper()
+
+	fileNames, err := filepath.Glob(filepath.Join("migrations", driver, "*.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var applied int
+	if err = db.QueryRow("SELECT COUNT(*) FROM SchemaMigration").Scan(&applied); err != nil {
+		t.Fatal(err)
+	}
+
+	if applied != len(fileNames) {
+		t.Errorf("%d migrations recorded, want %d", applied, len(fileNames))
+	}
+
+	if err = database.Migrate(db, driver); err != nil {
+		t.Errorf("migrating again failed: %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+// Package databasetest opens databases of every supported driver for tests, so that the same test runs on SQLite and
+// PostgreSQL.
+package databasetest
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miki208/stravaadventuregame/internal/database"
+)
+
+// PostgresDsnVariable is the environment variable with the connection string of a PostgreSQL database used by tests.
+// Tests on PostgreSQL are skipped if it's not set. A database for them is started by docker-compose.test.yml in the root
+// of the repository. Each test works in its own schema, which is dropped after the test.
+const PostgresDsnVariable = "POSTGRES_TEST_DSN"
+
+// Drivers are the database drivers tests run on.
+var Drivers = []string{database.DriverSQLite, database.DriverPostgres}
+
+// Run runs the test as a subtest for each driver, with a migrated database.
+func Run(t *testing.T, test func(t *testing.T, driver string, db *sql.DB)) {
+	for _, driver := range Drivers {
+		t.Run(driver, func(t *testing.T) {
+			test(t, driver, OpenMigrated(t, driver))
+		})
+	}
+}
+
+// OpenMigrated opens an empty database of the driver, with the schema created by the migrations.
+func OpenMigrated(t *testing.T, driver string) *sql.DB {
+	t.Helper()
+
+	db := Open(t, driver)
+
+	if err := database.Migrate(db, driver); err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+
+	return db
+}
+
+// Open opens an empty database of the driver, which is closed (and dropped) after the test. It skips the test if the
+// database is not available.
+func Open(t *testing.T, driver string) *sql.DB {
+	t.Helper()
+
+	var db *sql.DB
+	switch driver {
+	case database.DriverSQLite:
+		db = database.CreateSQLiteDatabase(filepath.Join(t.TempDir(), "app.db"))
+	case database.DriverPostgres:
+		db = openPostgres(t)
+	default:
+		t.Fatalf("unsupported database driver %s", driver)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func openPostgres(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv(PostgresDsnVariable)
+	if dsn == "" {
+		t.Skipf("%s is not set", PostgresDsnVariable)
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		t.Fatal(err)
+	}
+
+	schema := "test_" + hex.EncodeToString(suffix)
+
+	admin := database.CreatePostgresDatabase(dsn)
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		admin.Close()
+
+		t.Fatalf("failed to create schema %s: %v", schema, err)
+	}
+
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("failed to drop schema %s: %v", schema, err)
+		}
+
+		admin.Close()
+	})
+
+	// unknown connection parameters are sent to the server as run-time parameters, for all connections of the pool
+	if strings.Contains(dsn, "://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+
+		dsn += separator + "search_path=" + schema
+	} else {
+		dsn += " search_path=" + schema
+	}
+
+	return database.CreatePostgresDatabase(dsn)
+}
@@ -0,0 +1,253 @@
+CREATE TABLE IF NOT EXISTS Athlete (
+	id BIGINT NOT NULL,
+	first_name TEXT NOT NULL DEFAULT '',
+	last_name TEXT NOT NULL DEFAULT '',
+	city TEXT NOT NULL DEFAULT '',
+	country TEXT NOT NULL DEFAULT '',
+	sex TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY(id)
+);
+CREATE TABLE IF NOT EXISTS StravaCredential (
+	athlete_id BIGINT NOT NULL,
+	access_token TEXT NOT NULL,
+	refresh_token TEXT NOT NULL,
+	expires_at BIGINT NOT NULL,
+	PRIMARY KEY(athlete_id),
+	FOREIGN KEY(athlete_id) REFERENCES Athlete(id) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS AthleteSettings (
+	athlete_id BIGINT NOT NULL,
+	auto_update_activity_description BIGINT NOT NULL DEFAULT 0,
+	is_admin BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY(athlete_id),
+	FOREIGN KEY(athlete_id) REFERENCES Athlete(id) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS Activity (
+	id BIGINT NOT NULL,
+	athlete_id BIGINT NOT NULL,
+	type TEXT NOT NULL,
+	distance DOUBLE PRECISION NOT NULL,
+	start_date BIGINT NOT NULL,
+	moving_time BIGINT NOT NULL,
+	elapsed_time BIGINT NOT NULL,
+	elevation_gain DOUBLE PRECISION NOT NULL DEFAULT 0,
+	description TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY(id),
+	FOREIGN KEY(athlete_id) REFERENCES Athlete(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS ActivityAthlete ON Activity (athlete_id);
+CREATE TABLE IF NOT EXISTS PendingActivity (
+	id BIGINT NOT NULL,
+	owner_id BIGINT NOT NULL,
+	aspect_type TEXT NOT NULL,
+	event_time BIGINT NOT NULL,
+	PRIMARY KEY(id)
+);
+CREATE TABLE IF NOT EXISTS Location (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY,
+	lat DOUBLE PRECISION NOT NULL,
+	lon DOUBLE PRECISION NOT NULL,
+	name TEXT NOT NULL,
+	country TEXT NOT NULL DEFAULT '',
+	description TEXT NOT NULL DEFAULT '',
+	image_url TEXT NOT NULL DEFAULT '',
+	category TEXT NOT NULL DEFAULT '',
+	owner_id BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY(id)
+);
+CREATE INDEX IF NOT EXISTS LocationOwner ON Location (owner_id);
+CREATE TABLE IF NOT EXISTS Adventure (
+	athlete_id BIGINT NOT NULL,
+	start_location BIGINT NOT NULL,
+	end_location BIGINT NOT NULL,
+	current_location_lat DOUBLE PRECISION NOT NULL,
+	current_location_lon DOUBLE PRECISION NOT NULL,
+	current_location_index_on_route BIGINT NOT NULL,
+	current_location_name TEXT NOT NULL,
+	current_distance DOUBLE PRECISION NOT NULL DEFAULT 0,
+	total_distance DOUBLE PRECISION NOT NULL,
+	completed BIGINT NOT NULL DEFAULT 0,
+	start_date BIGINT NOT NULL,
+	end_date BIGINT,
+	PRIMARY KEY(athlete_id,start_location,end_location),
+	FOREIGN KEY(end_location) REFERENCES Location(id) ON DELETE RESTRICT,
+	FOREIGN KEY(start_location) REFERENCES Location(id) ON DELETE RESTRICT,
+	FOREIGN KEY(athlete_id) REFERENCES Athlete(id) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS ImportedRoute (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY,
+	name TEXT NOT NULL,
+	start_location BIGINT NOT NULL,
+	end_location BIGINT NOT NULL,
+	distance DOUBLE PRECISION NOT NULL,
+	created_at BIGINT NOT NULL,
+	PRIMARY KEY(id),
+	FOREIGN KEY(end_location) REFERENCES Location(id) ON DELETE RESTRICT,
+	FOREIGN KEY(start_location) REFERENCES Location(id) ON DELETE RESTRICT
+);
+CREATE TABLE IF NOT EXISTS Place (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY,
+	name TEXT NOT NULL,
+	country TEXT NOT NULL DEFAULT '',
+	lat DOUBLE PRECISION NOT NULL,
+	lon DOUBLE PRECISION NOT NULL,
+	population BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY(id)
+);
+CREATE INDEX IF NOT EXISTS PlaceLatLon ON Place (lat, lon);
+CREATE TABLE IF NOT EXISTS GeocodeCache (
+	key TEXT NOT NULL,
+	name TEXT NOT NULL,
+	created_at BIGINT NOT NULL,
+	PRIMARY KEY(key)
+);
+CREATE TABLE IF NOT EXISTS AdventurePassedPlace (
+	athlete_id BIGINT NOT NULL,
+	start_location BIGINT NOT NULL,
+	end_location BIGINT NOT NULL,
+	km DOUBLE PRECISION NOT NULL,
+	name TEXT NOT NULL,
+	activity_id BIGINT NOT NULL,
+	passed_date BIGINT NOT NULL,
+	PRIMARY KEY(athlete_id,start_location,end_location,km),
+	FOREIGN KEY(athlete_id,start_location,end_location) REFERENCES Adventure(athlete_id,start_location,end_location) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS CuratedAdventure (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY,
+	title TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	difficulty TEXT NOT NULL,
+	start_location BIGINT NOT NULL,
+	end_location BIGINT NOT NULL,
+	distance DOUBLE PRECISION NOT NULL,
+	cover_image_url TEXT NOT NULL DEFAULT '',
+	tags TEXT NOT NULL DEFAULT '',
+	mode TEXT NOT NULL,
+	created_at BIGINT NOT NULL,
+	PRIMARY KEY(id),
+	FOREIGN KEY(end_location) REFERENCES Location(id) ON DELETE RESTRICT,
+	FOREIGN KEY(start_location) REFERENCES Location(id) ON DELETE RESTRICT
+);
+CREATE TABLE IF NOT EXISTS AdventureMapImage (
+	athlete_id BIGINT NOT NULL,
+	start_location BIGINT NOT NULL,
+	end_location BIGINT NOT NULL,
+	image_id TEXT NOT NULL UNIQUE,
+	progress TEXT NOT NULL DEFAULT '',
+	rendered_at BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY(athlete_id,start_location,end_location),
+	FOREIGN KEY(athlete_id,start_location,end_location) REFERENCES Adventure(athlete_id,start_location,end_location) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS AdventurePublicLink (
+	athlete_id BIGINT NOT NULL,
+	start_location BIGINT NOT NULL,
+	end_location BIGINT NOT NULL,
+	slug TEXT NOT NULL UNIQUE,
+	created_at BIGINT NOT NULL,
+	PRIMARY KEY(athlete_id,start_location,end_location),
+	FOREIGN KEY(athlete_id,start_location,end_location) REFERENCES Adventure(athlete_id,start_location,end_location) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS ApiToken (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY,
+	athlete_id BIGINT NOT NULL,
+	name TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	scopes TEXT NOT NULL,
+	created_at BIGINT NOT NULL,
+	last_used_at BIGINT NOT NULL DEFAULT 0,
+	expires_at BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY(id),
+	FOREIGN KEY(athlete_id) REFERENCES Athlete(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS ApiTokenAthlete ON ApiToken (athlete_id);
+CREATE TABLE IF NOT EXISTS WebhookEndpoint (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY,
+	athlete_id BIGINT NOT NULL DEFAULT 0,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	events TEXT NOT NULL,
+	created_at BIGINT NOT NULL,
+	PRIMARY KEY(id)
+);
+CREATE INDEX IF NOT EXISTS WebhookEndpointAthlete ON WebhookEndpoint (athlete_id);
+CREATE TABLE IF NOT EXISTS WebhookDelivery (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY,
+	endpoint_id BIGINT NOT NULL,
+	event TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	status TEXT NOT NULL,
+	attempts BIGINT NOT NULL DEFAULT 0,
+	next_attempt_at BIGINT NOT NULL,
+	last_attempt_at BIGINT NOT NULL DEFAULT 0,
+	response_code BIGINT NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at BIGINT NOT NULL,
+	PRIMARY KEY(id),
+	FOREIGN KEY(endpoint_id) REFERENCES WebhookEndpoint(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS WebhookDeliveryStatus ON WebhookDelivery (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS WebhookDeliveryEndpoint ON WebhookDelivery (endpoint_id);
+CREATE TABLE IF NOT EXISTS ActivitySync (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY,
+	athlete_id BIGINT NOT NULL,
+	activity_id BIGINT NOT NULL,
+	aspect_type TEXT NOT NULL,
+	sport_type TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL,
+	reason TEXT NOT NULL DEFAULT '',
+	received_at BIGINT NOT NULL,
+	process_at BIGINT NOT NULL,
+	processed_at BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY(id)
+);
+CREATE INDEX IF NOT EXISTS ActivitySyncAthlete ON ActivitySync (athlete_id, activity_id);
+CREATE INDEX IF NOT EXISTS ActivitySyncStatus ON ActivitySync (status);
+CREATE TABLE IF NOT EXISTS PendingActivityRetry (
+	activity_id BIGINT NOT NULL,
+	attempts BIGINT NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	next_attempt_at BIGINT NOT NULL DEFAULT 0,
+	dead BIGINT NOT NULL DEFAULT 0,
+	updated_at BIGINT NOT NULL,
+	PRIMARY KEY(activity_id)
+);
+CREATE TABLE IF NOT EXISTS OutboxMessage (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY,
+	kind TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	status TEXT NOT NULL,
+	attempts BIGINT NOT NULL DEFAULT 0,
+	next_attempt_at BIGINT NOT NULL,
+	last_attempt_at BIGINT NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at BIGINT NOT NULL,
+	PRIMARY KEY(id)
+);
+CREATE INDEX IF NOT EXISTS OutboxMessageStatus ON OutboxMessage (status, next_attempt_at);
+CREATE TABLE IF NOT EXISTS JobRun (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY,
+	job TEXT NOT NULL,
+	triggered_by TEXT NOT NULL,
+	status TEXT NOT NULL,
+	started_at BIGINT NOT NULL,
+	finished_at BIGINT NOT NULL DEFAULT 0,
+	duration_ms BIGINT NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT '',
+	instance TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY(id)
+);
+CREATE INDEX IF NOT EXISTS JobRunJob ON JobRun (job, started_at);
+CREATE TABLE IF NOT EXISTS JobLease (
+	job TEXT NOT NULL,
+	holder TEXT NOT NULL,
+	acquired_at BIGINT NOT NULL,
+	expires_at BIGINT NOT NULL,
+	PRIMARY KEY(job)
+);
+CREATE TABLE IF NOT EXISTS InstanceHeartbeat (
+	instance TEXT NOT NULL,
+	hostname TEXT NOT NULL,
+	started_at BIGINT NOT NULL,
+	heartbeat_at BIGINT NOT NULL,
+	PRIMARY KEY(instance)
+);
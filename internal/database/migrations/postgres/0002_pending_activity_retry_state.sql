@@ -0,0 +1,11 @@
+-- The retry state of queued activity events moves from PendingActivityRetry to the events themselves, so that it goes
+-- away with them and can be updated only while they are queued.
+ALTER TABLE PendingActivity ADD COLUMN attempts BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE PendingActivity ADD COLUMN last_error TEXT NOT NULL DEFAULT '';
+ALTER TABLE PendingActivity ADD COLUMN next_attempt_at BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE PendingActivity ADD COLUMN dead BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE PendingActivity ADD COLUMN failed_at BIGINT NOT NULL DEFAULT 0;
+UPDATE PendingActivity SET attempts=r.attempts, last_error=r.last_error, next_attempt_at=r.next_attempt_at,
+	dead=r.dead, failed_at=r.updated_at
+	FROM PendingActivityRetry AS r WHERE r.activity_id=PendingActivity.id;
+DROP TABLE PendingActivityRetry;
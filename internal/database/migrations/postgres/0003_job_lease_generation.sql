@@ -0,0 +1,3 @@
+-- The generation of a lease is incremented whenever another instance takes it over, runs of jobs started with an older
+-- generation must not perform their side effects anymore.
+ALTER TABLE JobLease ADD COLUMN generation BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,72 @@
+-- The schema of app.db.sql, from before migrations were introduced. Databases created from it already have these
+-- tables, so they are only created if they don't exist, and upgraded by the following migrations.
+CREATE TABLE IF NOT EXISTS "Athlete" (
+	"id"	INTEGER NOT NULL,
+	"first_name"	TEXT NOT NULL DEFAULT '',
+	"last_name"	TEXT NOT NULL DEFAULT '',
+	"city"	TEXT NOT NULL DEFAULT '',
+	"country"	TEXT NOT NULL DEFAULT '',
+	"sex"	TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY("id")
+);
+CREATE TABLE IF NOT EXISTS "StravaCredential" (
+	"athlete_id"	INTEGER NOT NULL,
+	"access_token"	TEXT NOT NULL,
+	"refresh_token"	TEXT NOT NULL,
+	"expires_at"	INTEGER NOT NULL,
+	PRIMARY KEY("athlete_id"),
+	FOREIGN KEY("athlete_id") REFERENCES "Athlete"("id") ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS "AthleteSettings" (
+	"athlete_id"	INTEGER NOT NULL,
+	"auto_update_activity_description"	INTEGER NOT NULL DEFAULT 0,
+	"is_admin"	INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY("athlete_id"),
+	FOREIGN KEY("athlete_id") REFERENCES "Athlete"("id") ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS "Activity" (
+	"id"	INTEGER NOT NULL,
+	"athlete_id"	INTEGER NOT NULL,
+	"type"	TEXT NOT NULL,
+	"distance"	REAL NOT NULL,
+	"start_date"	INTEGER NOT NULL,
+	"moving_time"	INTEGER NOT NULL,
+	"elapsed_time"	INTEGER NOT NULL,
+	"elevation_gain"	REAL NOT NULL DEFAULT 0,
+	"description"	TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY("id"),
+	FOREIGN KEY("athlete_id") REFERENCES "Athlete"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "ActivityAthlete" ON "Activity" ("athlete_id");
+CREATE TABLE IF NOT EXISTS "PendingActivity" (
+	"id"	INTEGER NOT NULL,
+	"owner_id"	INTEGER NOT NULL,
+	"aspect_type"	TEXT NOT NULL,
+	"event_time"	INTEGER NOT NULL,
+	PRIMARY KEY("id")
+);
+CREATE TABLE IF NOT EXISTS "Location" (
+	"id"	INTEGER NOT NULL,
+	"lat"	REAL NOT NULL,
+	"lon"	REAL NOT NULL,
+	"name"	TEXT NOT NULL,
+	PRIMARY KEY("id")
+);
+CREATE TABLE IF NOT EXISTS "Adventure" (
+	"athlete_id"	INTEGER NOT NULL,
+	"start_location"	INTEGER NOT NULL,
+	"end_location"	INTEGER NOT NULL,
+	"current_location_lat"	REAL NOT NULL,
+	"current_location_lon"	REAL NOT NULL,
+	"current_location_index_on_route"	INTEGER NOT NULL,
+	"current_location_name"	TEXT NOT NULL,
+	"current_distance"	REAL NOT NULL DEFAULT 0,
+	"total_distance"	REAL NOT NULL,
+	"completed"	INTEGER NOT NULL DEFAULT 0,
+	"start_date"	INTEGER NOT NULL,
+	"end_date"	INTEGER,
+	PRIMARY KEY("athlete_id","start_location","end_location"),
+	FOREIGN KEY("end_location") REFERENCES "Location"("id") ON DELETE CASCADE,
+	FOREIGN KEY("start_location") REFERENCES "Location"("id") ON DELETE CASCADE,
+	FOREIGN KEY("athlete_id") REFERENCES "Athlete"("id") ON DELETE CASCADE
+);
@@ -0,0 +1,220 @@
+-- Upgrades the schema created by app.db.sql (0001) to the one of the current models. Location gets its details, an
+-- owner and AUTOINCREMENT ids (ids of deleted locations must not be reused, courses are stored by location ids), and
+-- deleting a location which is used by adventures is refused instead of deleting the adventures. Both tables are
+-- rebuilt, as SQLite can't alter constraints.
+CREATE TABLE "LocationNew" (
+	"id"	INTEGER NOT NULL,
+	"lat"	REAL NOT NULL,
+	"lon"	REAL NOT NULL,
+	"name"	TEXT NOT NULL,
+	"country"	TEXT NOT NULL DEFAULT '',
+	"description"	TEXT NOT NULL DEFAULT '',
+	"image_url"	TEXT NOT NULL DEFAULT '',
+	"category"	TEXT NOT NULL DEFAULT '',
+	"owner_id"	INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+INSERT INTO "LocationNew"("id", "lat", "lon", "name") SELECT "id", "lat", "lon", "name" FROM "Location";
+-- the foreign keys reference LocationNew until it's renamed, renaming updates them
+CREATE TABLE "AdventureNew" (
+	"athlete_id"	INTEGER NOT NULL,
+	"start_location"	INTEGER NOT NULL,
+	"end_location"	INTEGER NOT NULL,
+	"current_location_lat"	REAL NOT NULL,
+	"current_location_lon"	REAL NOT NULL,
+	"current_location_index_on_route"	INTEGER NOT NULL,
+	"current_location_name"	TEXT NOT NULL,
+	"current_distance"	REAL NOT NULL DEFAULT 0,
+	"total_distance"	REAL NOT NULL,
+	"completed"	INTEGER NOT NULL DEFAULT 0,
+	"start_date"	INTEGER NOT NULL,
+	"end_date"	INTEGER,
+	PRIMARY KEY("athlete_id","start_location","end_location"),
+	FOREIGN KEY("end_location") REFERENCES "LocationNew"("id") ON DELETE RESTRICT,
+	FOREIGN KEY("start_location") REFERENCES "LocationNew"("id") ON DELETE RESTRICT,
+	FOREIGN KEY("athlete_id") REFERENCES "Athlete"("id") ON DELETE CASCADE
+);
+INSERT INTO "AdventureNew" SELECT * FROM "Adventure";
+-- the old Adventure goes first, otherwise dropping the old Location would cascade to it
+DROP TABLE "Adventure";
+DROP TABLE "Location";
+ALTER TABLE "LocationNew" RENAME TO "Location";
+ALTER TABLE "AdventureNew" RENAME TO "Adventure";
+CREATE INDEX "LocationOwner" ON "Location" ("owner_id");
+CREATE TABLE "ImportedRoute" (
+	"id"	INTEGER NOT NULL,
+	"name"	TEXT NOT NULL,
+	"start_location"	INTEGER NOT NULL,
+	"end_location"	INTEGER NOT NULL,
+	"distance"	REAL NOT NULL,
+	"created_at"	INTEGER NOT NULL,
+	PRIMARY KEY("id" AUTOINCREMENT),
+	FOREIGN KEY("end_location") REFERENCES "Location"("id") ON DELETE RESTRICT,
+	FOREIGN KEY("start_location") REFERENCES "Location"("id") ON DELETE RESTRICT
+);
+CREATE TABLE "Place" (
+	"id"	INTEGER NOT NULL,
+	"name"	TEXT NOT NULL,
+	"country"	TEXT NOT NULL DEFAULT '',
+	"lat"	REAL NOT NULL,
+	"lon"	REAL NOT NULL,
+	"population"	INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE INDEX "PlaceLatLon" ON "Place" ("lat", "lon");
+CREATE TABLE "GeocodeCache" (
+	"key"	TEXT NOT NULL,
+	"name"	TEXT NOT NULL,
+	"created_at"	INTEGER NOT NULL,
+	PRIMARY KEY("key")
+);
+CREATE TABLE "AdventurePassedPlace" (
+	"athlete_id"	INTEGER NOT NULL,
+	"start_location"	INTEGER NOT NULL,
+	"end_location"	INTEGER NOT NULL,
+	"km"	REAL NOT NULL,
+	"name"	TEXT NOT NULL,
+	"activity_id"	INTEGER NOT NULL,
+	"passed_date"	INTEGER NOT NULL,
+	PRIMARY KEY("athlete_id","start_location","end_location","km"),
+	FOREIGN KEY("athlete_id","start_location","end_location") REFERENCES "Adventure"("athlete_id","start_location","end_location") ON DELETE CASCADE
+);
+CREATE TABLE "CuratedAdventure" (
+	"id"	INTEGER NOT NULL,
+	"title"	TEXT NOT NULL,
+	"description"	TEXT NOT NULL DEFAULT '',
+	"difficulty"	TEXT NOT NULL,
+	"start_location"	INTEGER NOT NULL,
+	"end_location"	INTEGER NOT NULL,
+	"distance"	REAL NOT NULL,
+	"cover_image_url"	TEXT NOT NULL DEFAULT '',
+	"tags"	TEXT NOT NULL DEFAULT '',
+	"mode"	TEXT NOT NULL,
+	"created_at"	INTEGER NOT NULL,
+	PRIMARY KEY("id" AUTOINCREMENT),
+	FOREIGN KEY("end_location") REFERENCES "Location"("id") ON DELETE RESTRICT,
+	FOREIGN KEY("start_location") REFERENCES "Location"("id") ON DELETE RESTRICT
+);
+CREATE TABLE "AdventureMapImage" (
+	"athlete_id"	INTEGER NOT NULL,
+	"start_location"	INTEGER NOT NULL,
+	"end_location"	INTEGER NOT NULL,
+	"image_id"	TEXT NOT NULL UNIQUE,
+	"progress"	TEXT NOT NULL DEFAULT '',
+	"rendered_at"	INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY("athlete_id","start_location","end_location"),
+	FOREIGN KEY("athlete_id","start_location","end_location") REFERENCES "Adventure"("athlete_id","start_location","end_location") ON DELETE CASCADE
+);
+CREATE TABLE "AdventurePublicLink" (
+	"athlete_id"	INTEGER NOT NULL,
+	"start_location"	INTEGER NOT NULL,
+	"end_location"	INTEGER NOT NULL,
+	"slug"	TEXT NOT NULL UNIQUE,
+	"created_at"	INTEGER NOT NULL,
+	PRIMARY KEY("athlete_id","start_location","end_location"),
+	FOREIGN KEY("athlete_id","start_location","end_location") REFERENCES "Adventure"("athlete_id","start_location","end_location") ON DELETE CASCADE
+);
+CREATE TABLE "ApiToken" (
+	"id"	INTEGER NOT NULL,
+	"athlete_id"	INTEGER NOT NULL,
+	"name"	TEXT NOT NULL,
+	"token_hash"	TEXT NOT NULL UNIQUE,
+	"scopes"	TEXT NOT NULL,
+	"created_at"	INTEGER NOT NULL,
+	"last_used_at"	INTEGER NOT NULL DEFAULT 0,
+	"expires_at"	INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY("id" AUTOINCREMENT),
+	FOREIGN KEY("athlete_id") REFERENCES "Athlete"("id") ON DELETE CASCADE
+);
+CREATE INDEX "ApiTokenAthlete" ON "ApiToken" ("athlete_id");
+CREATE TABLE "WebhookEndpoint" (
+	"id"	INTEGER NOT NULL,
+	"athlete_id"	INTEGER NOT NULL DEFAULT 0,
+	"url"	TEXT NOT NULL,
+	"secret"	TEXT NOT NULL,
+	"events"	TEXT NOT NULL,
+	"created_at"	INTEGER NOT NULL,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE INDEX "WebhookEndpointAthlete" ON "WebhookEndpoint" ("athlete_id");
+CREATE TABLE "WebhookDelivery" (
+	"id"	INTEGER NOT NULL,
+	"endpoint_id"	INTEGER NOT NULL,
+	"event"	TEXT NOT NULL,
+	"payload"	TEXT NOT NULL,
+	"status"	TEXT NOT NULL,
+	"attempts"	INTEGER NOT NULL DEFAULT 0,
+	"next_attempt_at"	INTEGER NOT NULL,
+	"last_attempt_at"	INTEGER NOT NULL DEFAULT 0,
+	"response_code"	INTEGER NOT NULL DEFAULT 0,
+	"last_error"	TEXT NOT NULL DEFAULT '',
+	"created_at"	INTEGER NOT NULL,
+	PRIMARY KEY("id" AUTOINCREMENT),
+	FOREIGN KEY("endpoint_id") REFERENCES "WebhookEndpoint"("id") ON DELETE CASCADE
+);
+CREATE INDEX "WebhookDeliveryStatus" ON "WebhookDelivery" ("status", "next_attempt_at");
+CREATE INDEX "WebhookDeliveryEndpoint" ON "WebhookDelivery" ("endpoint_id");
+CREATE TABLE "ActivitySync" (
+	"id"	INTEGER NOT NULL,
+	"athlete_id"	INTEGER NOT NULL,
+	"activity_id"	INTEGER NOT NULL,
+	"aspect_type"	TEXT NOT NULL,
+	"sport_type"	TEXT NOT NULL DEFAULT '',
+	"status"	TEXT NOT NULL,
+	"reason"	TEXT NOT NULL DEFAULT '',
+	"received_at"	INTEGER NOT NULL,
+	"process_at"	INTEGER NOT NULL,
+	"processed_at"	INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE INDEX "ActivitySyncAthlete" ON "ActivitySync" ("athlete_id", "activity_id");
+CREATE INDEX "ActivitySyncStatus" ON "ActivitySync" ("status");
+CREATE TABLE "PendingActivityRetry" (
+	"activity_id"	INTEGER NOT NULL,
+	"attempts"	INTEGER NOT NULL DEFAULT 0,
+	"last_error"	TEXT NOT NULL DEFAULT '',
+	"next_attempt_at"	INTEGER NOT NULL DEFAULT 0,
+	"dead"	INTEGER NOT NULL DEFAULT 0,
+	"updated_at"	INTEGER NOT NULL,
+	PRIMARY KEY("activity_id")
+);
+CREATE TABLE "OutboxMessage" (
+	"id"	INTEGER NOT NULL,
+	"kind"	TEXT NOT NULL,
+	"payload"	TEXT NOT NULL,
+	"status"	TEXT NOT NULL,
+	"attempts"	INTEGER NOT NULL DEFAULT 0,
+	"next_attempt_at"	INTEGER NOT NULL,
+	"last_attempt_at"	INTEGER NOT NULL DEFAULT 0,
+	"last_error"	TEXT NOT NULL DEFAULT '',
+	"created_at"	INTEGER NOT NULL,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE INDEX "OutboxMessageStatus" ON "OutboxMessage" ("status", "next_attempt_at");
+CREATE TABLE "JobRun" (
+	"id"	INTEGER NOT NULL,
+	"job"	TEXT NOT NULL,
+	"triggered_by"	TEXT NOT NULL,
+	"status"	TEXT NOT NULL,
+	"started_at"	INTEGER NOT NULL,
+	"finished_at"	INTEGER NOT NULL DEFAULT 0,
+	"duration_ms"	INTEGER NOT NULL DEFAULT 0,
+	"error"	TEXT NOT NULL DEFAULT '',
+	"instance"	TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE INDEX "JobRunJob" ON "JobRun" ("job", "started_at");
+CREATE TABLE "JobLease" (
+	"job"	TEXT NOT NULL,
+	"holder"	TEXT NOT NULL,
+	"acquired_at"	INTEGER NOT NULL,
+	"expires_at"	INTEGER NOT NULL,
+	PRIMARY KEY("job")
+);
+CREATE TABLE "InstanceHeartbeat" (
+	"instance"	TEXT NOT NULL,
+	"hostname"	TEXT NOT NULL,
+	"started_at"	INTEGER NOT NULL,
+	"heartbeat_at"	INTEGER NOT NULL,
+	PRIMARY KEY("instance")
+);
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+func CreatePostgresDatabase(dsn string) *sql.DB {
+	slog.Info("Initializing PostgreSQL database...")
+
+	connector, err := stdlib.GetDefaultDriver().(*stdlib.Driver).OpenConnector(dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	db := sql.OpenDB(&rebindConnector{Connector: connector})
+
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+
+		panic(err)
+	}
+
+	return db
+}
+
+// Models are written with ? placeholders (SQLite), PostgreSQL expects $1, $2, ... instead. Connections to PostgreSQL
+// rewrite the placeholders of every statement, so that the same queries run on both databases.
+type rebindConnector struct {
+	driver.Connector
+}
+
+func (connector *rebindConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := connector.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rebindConn{Conn: conn.(*stdlib.Conn)}, nil
+}
+
+type rebindConn struct {
+	*stdlib.Conn
+}
+
+func (conn *rebindConn) Prepare(query string) (driver.Stmt, error) {
+	return conn.Conn.Prepare(Rebind(query))
+}
+
+func (conn *rebindConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return conn.Conn.PrepareContext(ctx, Rebind(query))
+}
+
+func (conn *rebindConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return conn.Conn.ExecContext(ctx, Rebind(query), args)
+}
+
+func (conn *rebindConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return conn.Conn.QueryContext(ctx, Rebind(query), args)
+}
+
+// Rebind replaces ? placeholders in the query with $1, $2, ..., skipping question marks in quoted strings and identifiers.
+func Rebind(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+
+	var rebound strings.Builder
+	rebound.Grow(len(query) + 16)
+
+	param := 0
+	var quote byte // the quote of the string or identifier we are in, 0 if none
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		switch {
+		case quote != 0:
+			// a doubled quote inside a string is escaped, so it's handled as closing and reopening it
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			param++
+			rebound.WriteString("$" + strconv.Itoa(param))
+
+			continue
+		}
+
+		rebound.WriteByte(c)
+	}
+
+	return rebound.String()
+}
@@ -0,0 +1,69 @@
+package database_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/miki208/stravaadventuregame/internal/database"
+	"github.com/miki208/stravaadventuregame/internal/database/databasetest"
+)
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT * FROM Location", "SELECT * FROM Location"},
+		{"SELECT * FROM Location WHERE id=?", "SELECT * FROM Location WHERE id=$1"},
+		{"UPDATE Location SET name=?, lat=? WHERE id=?", "UPDATE Location SET name=$1, lat=$2 WHERE id=$3"},
+		{"INSERT INTO Location(lat, lon, name) VALUES(?, ?, ?) RETURNING id", "INSERT INTO Location(lat, lon, name) VALUES($1, $2, $3) RETURNING id"},
+		{"SELECT * FROM Place WHERE LOWER(name) LIKE LOWER(?) ESCAPE '\\' LIMIT ?", "SELECT * FROM Place WHERE LOWER(name) LIKE LOWER($1) ESCAPE '\\' LIMIT $2"},
+		{"SELECT '?' FROM Location WHERE name=?", "SELECT '?' FROM Location WHERE name=$1"},
+		{"SELECT \"a?b\" FROM Location WHERE id=?", "SELECT \"a?b\" FROM Location WHERE id=$1"},
+		{"SELECT 'it''s ?' WHERE a=? AND b='?''?'", "SELECT 'it''s ?' WHERE a=$1 AND b='?''?'"},
+		{"SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?", "SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11"},
+	}
+
+	for _, test := range tests {
+		if got := database.Rebind(test.query); got != test.want {
+			t.Errorf("Rebind(%q) = %q, want %q", test.query, got, test.want)
+		}
+	}
+}
+
+// Placeholders are rewritten by the connections to PostgreSQL, so the same statements (question marks in strings
+// included) have to give the same results on both databases.
+func TestPlaceholders(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		if _, err := db.Exec("INSERT INTO Location(lat, lon, name) VALUES(?, ?, ?)", 44.8, 20.4, "What?"); err != nil {
+			t.Fatal(err)
+		}
+
+		var name, literal string
+		err := db.QueryRow("SELECT name, 'it''s ?' FROM Location WHERE name=? AND name<>'?' AND lat>?", "What?", 40.0).Scan(&name, &literal)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if name != "What?" || literal != "it's ?" {
+			t.Errorf("got (%q, %q), want (\"What?\", \"it's ?\")", name, literal)
+		}
+
+		// prepared statements are rewritten too
+		stmt, err := db.Prepare("SELECT COUNT(*) FROM Location WHERE name=?")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer stmt.Close()
+
+		var count int
+		if err = stmt.QueryRow("What?").Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+
+		if count != 1 {
+			t.Errorf("count = %d, want 1", count)
+		}
+	})
+}
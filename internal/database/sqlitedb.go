@@ -11,7 +11,8 @@ import (
 func CreateSQLiteDatabase(dbFilePath string) *sql.DB {
 	slog.Info("Initializing SQLite database...", "dbFilePath", dbFilePath)
 
-	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=foreign_keys(1)", dbFilePath))
+	// busy timeout lets background writers (e.g. geocode cache warming) wait for each other instead of failing
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)", dbFilePath))
 	if err != nil {
 		panic(err)
 	}
@@ -23,14 +24,3 @@ func CreateSQLiteDatabase(dbFilePath string) *sql.DB {
 
 	return db
 }
-
-func CommitOrRollbackSQLiteTransaction(tx *sql.Tx) error {
-	err := tx.Commit()
-	if err != nil {
-		tx.Rollback()
-
-		return err
-	}
-
-	return nil
-}
@@ -0,0 +1,82 @@
+-- A database as it was created before migrations were introduced: app.db.sql of that time, with the tables that the
+-- models of that time used, and a few rows.
+CREATE TABLE "Athlete" (
+	"id"	INTEGER NOT NULL,
+	"first_name"	TEXT NOT NULL DEFAULT '',
+	"last_name"	TEXT NOT NULL DEFAULT '',
+	"city"	TEXT NOT NULL DEFAULT '',
+	"country"	TEXT NOT NULL DEFAULT '',
+	"sex"	TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY("id")
+);
+CREATE TABLE "StravaCredential" (
+	"athlete_id"	INTEGER NOT NULL,
+	"access_token"	TEXT NOT NULL,
+	"refresh_token"	TEXT NOT NULL,
+	"expires_at"	INTEGER NOT NULL,
+	PRIMARY KEY("athlete_id"),
+	FOREIGN KEY("athlete_id") REFERENCES "Athlete"("id") ON DELETE CASCADE
+);
+CREATE TABLE "AthleteSettings" (
+	"athlete_id"	INTEGER NOT NULL,
+	"auto_update_activity_description"	INTEGER NOT NULL DEFAULT 0,
+	"is_admin"	INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY("athlete_id"),
+	FOREIGN KEY("athlete_id") REFERENCES "Athlete"("id") ON DELETE CASCADE
+);
+CREATE TABLE "Activity" (
+	"id"	INTEGER NOT NULL,
+	"athlete_id"	INTEGER NOT NULL,
+	"type"	TEXT NOT NULL,
+	"distance"	REAL NOT NULL,
+	"start_date"	INTEGER NOT NULL,
+	"moving_time"	INTEGER NOT NULL,
+	"elapsed_time"	INTEGER NOT NULL,
+	"elevation_gain"	REAL NOT NULL DEFAULT 0,
+	"description"	TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY("id"),
+	FOREIGN KEY("athlete_id") REFERENCES "Athlete"("id") ON DELETE CASCADE
+);
+CREATE TABLE "PendingActivity" (
+	"id"	INTEGER NOT NULL,
+	"owner_id"	INTEGER NOT NULL,
+	"aspect_type"	TEXT NOT NULL,
+	"event_time"	INTEGER NOT NULL,
+	PRIMARY KEY("id")
+);
+CREATE TABLE "Location" (
+	"id"	INTEGER NOT NULL,
+	"lat"	REAL NOT NULL,
+	"lon"	REAL NOT NULL,
+	"name"	TEXT NOT NULL,
+	PRIMARY KEY("id")
+);
+BEGIN TRANSACTION;
+DROP TABLE IF EXISTS "Adventure";
+CREATE TABLE IF NOT EXISTS "Adventure" (
+	"athlete_id"	INTEGER NOT NULL,
+	"start_location"	INTEGER NOT NULL,
+	"end_location"	INTEGER NOT NULL,
+	"current_location_lat"	REAL NOT NULL,
+	"current_location_lon"	REAL NOT NULL,
+	"current_location_index_on_route"	INTEGER NOT NULL,
+	"current_location_name"	TEXT NOT NULL,
+	"current_distance"	REAL NOT NULL DEFAULT 0,
+	"total_distance"	REAL NOT NULL,
+	"completed"	INTEGER NOT NULL DEFAULT 0,
+	"start_date"	INTEGER NOT NULL,
+	"end_date"	INTEGER,
+	PRIMARY KEY("athlete_id","start_location","end_location"),
+	FOREIGN KEY("end_location") REFERENCES "Location"("id") ON DELETE CASCADE,
+	FOREIGN KEY("start_location") REFERENCES "Location"("id") ON DELETE CASCADE,
+	FOREIGN KEY("athlete_id") REFERENCES "Athlete"("id") ON DELETE CASCADE
+);
+COMMIT;
+INSERT INTO "Athlete" VALUES(1, 'Jane', 'Doe', 'Belgrade', 'Serbia', 'F');
+INSERT INTO "AthleteSettings" VALUES(1, 1, 1);
+INSERT INTO "Location" VALUES(1, 44.8125, 20.4612, 'Belgrade');
+INSERT INTO "Location" VALUES(2, 45.2671, 19.8335, 'Novi Sad');
+INSERT INTO "Location" VALUES(3, 43.3209, 21.8958, 'Nis');
+INSERT INTO "Adventure" VALUES(1, 1, 2, 44.9, 20.3, 12, 'Stara Pazova', 21.5, 94.3, 0, 1700000000, 0);
+INSERT INTO "Activity" VALUES(100, 1, 'Run', 21.5, 1700000100, 7200, 7300, 120, '');
+INSERT INTO "PendingActivity" VALUES(101, 1, 'create', 1700000200);
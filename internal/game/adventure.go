@@ -0,0 +1,187 @@
+// Package game contains actions of athletes that are available both from the web pages and from the JSON API.
+// Errors are returned as handler errors, carrying the http status code that fits them.
+package game
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/database"
+	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+// StartAdventure starts a new adventure of the athlete between two locations visible to them. The course is fetched
+// from the routing provider if no athlete went between these two locations before.
+func StartAdventure(athleteId int64, startLocationId int, endLocationId int, app *application.App) (*model.Adventure, error) {
+	if startLocationId == endLocationId {
+		return nil, handler.NewHandlerError(http.StatusBadRequest, errors.New("start and stop location can't be the same"))
+	}
+
+	// make sure that user is not already on an adventure
+	adventuresStarted, err := model.AllAdventures(app.SqlDb, nil, map[string]any{"athlete_id": athleteId, "completed": 0})
+	if err != nil {
+		return nil, handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+	if len(adventuresStarted) > 0 {
+		return nil, handler.NewHandlerError(http.StatusBadRequest, errors.New("active adventure already exists"))
+	}
+
+	// make sure that user isn't already finished this adventure
+	adventureAlreadyExists, err := model.AdventureExists(athleteId, startLocationId, endLocationId, app.SqlDb, nil)
+	if err != nil {
+		return nil, handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+	if adventureAlreadyExists {
+		return nil, handler.NewHandlerError(http.StatusBadRequest, errors.New("adventure already exists"))
+	}
+
+	var startLocation model.Location
+	found, err := startLocation.Load(startLocationId, app.SqlDb, nil)
+	if err != nil {
+		return nil, handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	// private locations of other athletes can't be used
+	if !found || !helper.IsLocationVisibleTo(&startLocation, athleteId) {
+		return nil, handler.NewHandlerError(http.StatusNotFound, errors.New("location not found"))
+	}
+
+	var stopLocation model.Location
+	found, err = stopLocation.Load(endLocationId, app.SqlDb, nil)
+	if err != nil {
+		return nil, handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !found || !helper.IsLocationVisibleTo(&stopLocation, athleteId) {
+		return nil, handler.NewHandlerError(http.StatusNotFound, errors.New("location not found"))
+	}
+
+	// we should check if we have this route in the database before getting it via rest api
+	dbName := model.CourseDbName(startLocationId, endLocationId)
+
+	exists, err := app.FileDb.Exists("course", dbName)
+	if err != nil {
+		return nil, handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	var adventureCourse *model.DirectionsRoute
+	if !exists {
+		// courses are stored in the direction from the location with the lower id to the one with the higher id
+		courseStart, courseEnd := &startLocation, &stopLocation
+		if startLocationId > endLocationId {
+			courseStart, courseEnd = courseEnd, courseStart
+		}
+
+		// no route, retrieve it
+		route, err := app.RoutingSvc.GetDirections(courseStart.Lat, courseStart.Lon, courseEnd.Lat, courseEnd.Lon, "km")
+		if err != nil {
+			statusCode := http.StatusFailedDependency
+			if serviceErr, ok := err.(interface{ StatusCode() int }); ok {
+				statusCode = serviceErr.StatusCode()
+			}
+
+			return nil, handler.NewHandlerError(statusCode, err)
+		}
+
+		// write it to the database
+		err = app.FileDb.Write("course", dbName, route)
+		if err != nil {
+			return nil, handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+
+		// new course, other athletes will probably follow it too, so names along it can be fetched in advance
+		if app.GeocodeCacheSvc != nil {
+			routePolyline, _, err := helper.DecodePolyline(route.Geometry, route.Elevation, false)
+			if err != nil {
+				return nil, handler.NewHandlerError(http.StatusInternalServerError, err)
+			}
+
+			go app.GeocodeCacheSvc.WarmCourse(routePolyline)
+		}
+
+		// we have the course
+		adventureCourse = route
+	} else {
+		// just read the course from the database
+		adventureCourse = model.NewDirectionsRoute()
+
+		if err = app.FileDb.Read("course", dbName, adventureCourse); err != nil {
+			return nil, handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+	}
+
+	// create adventure and save it to the database
+	adventure := model.Adventure{
+		AthleteId:                   athleteId,
+		StartLocation:               startLocationId,
+		EndLocation:                 endLocationId,
+		CurrentLocationLat:          startLocation.Lat,
+		CurrentLocationLon:          startLocation.Lon,
+		CurrentLocationIndexOnRoute: 0,
+		CurrentLocationName:         startLocation.Name,
+		CurrentDistance:             0,
+		TotalDistance:               adventureCourse.Summary.Distance,
+		Completed:                   0,
+		StartDate:                   int(time.Now().Unix()),
+		EndDate:                     0,
+	}
+
+	tx, err := app.SqlDb.Begin()
+	if err != nil {
+		return nil, handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+	defer tx.Rollback()
+
+	if err = adventure.Insert(app.SqlDb, tx); err != nil {
+		return nil, handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if err = helper.EnqueueWebhookEvent(helper.WebhookEventAdventureStarted, &adventure, nil, nil, app.SqlDb, tx); err != nil {
+		return nil, handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if err = database.CommitOrRollbackTransaction(tx); err != nil {
+		return nil, handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	return &adventure, nil
+}
+
+// AbandonAdventure deletes an ongoing adventure of the athlete, together with everything recorded about it.
+// Completed adventures can't be abandoned.
+func AbandonAdventure(athleteId int64, startLocationId int, endLocationId int, app *application.App) error {
+	tx, err := app.SqlDb.Begin()
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+	defer tx.Rollback()
+
+	var adventure model.Adventure
+	found, err := adventure.Load(athleteId, startLocationId, endLocationId, app.SqlDb, tx)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !found {
+		return handler.NewHandlerError(http.StatusNotFound, errors.New("adventure not found"))
+	}
+
+	if adventure.Completed == 1 {
+		return handler.NewHandlerError(http.StatusConflict, errors.New("completed adventure can't be abandoned"))
+	}
+
+	// passed places, map image and public link are deleted by the database (on delete cascade)
+	if err = adventure.Delete(app.SqlDb, tx); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if err = database.CommitOrRollbackTransaction(tx); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
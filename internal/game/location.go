@@ -0,0 +1,89 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/database"
+	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+const maxPrivateLocationsPerAthlete = 50
+
+// CreatePrivateLocation validates and saves a location visible only to its owner (OwnerId has to be set).
+func CreatePrivateLocation(location *model.Location, app *application.App) error {
+	if location.OwnerId == 0 {
+		return handler.NewHandlerError(http.StatusInternalServerError, errors.New("private location has to have an owner"))
+	}
+
+	if err := helper.ValidateLocation(location); err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	tx, err := app.SqlDb.Begin()
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+	defer tx.Rollback()
+
+	privateLocations, err := model.AllLocations(app.SqlDb, tx, map[string]any{"owner_id": location.OwnerId})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if len(privateLocations) >= maxPrivateLocationsPerAthlete {
+		return handler.NewHandlerError(http.StatusBadRequest, fmt.Errorf("you can't have more than %d private locations", maxPrivateLocationsPerAthlete))
+	}
+
+	if err = location.Save(app.SqlDb, tx); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if err = database.CommitOrRollbackTransaction(tx); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// DeletePrivateLocation deletes a private location of the athlete, unless it's used by any of their adventures.
+func DeletePrivateLocation(athleteId int64, id int, app *application.App) error {
+	tx, err := app.SqlDb.Begin()
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+	defer tx.Rollback()
+
+	var location model.Location
+	found, err := location.Load(id, app.SqlDb, tx)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !found || location.OwnerId != athleteId {
+		return handler.NewHandlerError(http.StatusNotFound, errors.New("location not found"))
+	}
+
+	inUse, err := model.LocationInUse(id, app.SqlDb, tx)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if inUse {
+		return handler.NewHandlerError(http.StatusConflict, errors.New("location is used by your adventures and can't be deleted"))
+	}
+
+	if err = location.Delete(app.SqlDb, tx); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if err = database.CommitOrRollbackTransaction(tx); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,327 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/game"
+	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+type CurrentLocation struct {
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+}
+
+type Adventure struct {
+	StartLocation     Location        `json:"start_location"`
+	EndLocation       Location        `json:"end_location"`
+	CurrentLocation   CurrentLocation `json:"current_location"`
+	CurrentDistanceKm float32         `json:"current_distance_km"`
+	TotalDistanceKm   float32         `json:"total_distance_km"`
+	Completed         bool            `json:"completed"`
+	StartDate         string          `json:"start_date"`
+	EndDate           string          `json:"end_date,omitempty"`
+}
+
+type AdventureDetail struct {
+	Adventure
+	PassedPlaces []string `json:"passed_places"`
+	MapImageUrl  string   `json:"map_image_url,omitempty"` // empty until the adventure has a map image
+	PublicUrl    string   `json:"public_url,omitempty"`
+}
+
+type NewAdventure struct {
+	StartLocation int `json:"start_location"`
+	EndLocation   int `json:"end_location"`
+}
+
+type Activity struct {
+	Id                 int64   `json:"id"`
+	SportType          string  `json:"sport_type"`
+	DistanceKm         float32 `json:"distance_km"`
+	MovingTime         int     `json:"moving_time"`
+	ElapsedTime        int     `json:"elapsed_time"`
+	TotalElevationGain float32 `json:"total_elevation_gain"`
+	StartDate          string  `json:"start_date"`
+}
+
+func adventureToJson(adventure *model.Adventure, app *application.App) (Adventure, error) {
+	var startLocation, endLocation model.Location
+
+	found, err := startLocation.Load(adventure.StartLocation, app.SqlDb, nil)
+	if err != nil {
+		return Adventure{}, err
+	}
+	if !found {
+		return Adventure{}, fmt.Errorf("start location %d not found", adventure.StartLocation)
+	}
+
+	found, err = endLocation.Load(adventure.EndLocation, app.SqlDb, nil)
+	if err != nil {
+		return Adventure{}, err
+	}
+	if !found {
+		return Adventure{}, fmt.Errorf("end location %d not found", adventure.EndLocation)
+	}
+
+	adventureJson := Adventure{
+		StartLocation: locationToJson(&startLocation),
+		EndLocation:   locationToJson(&endLocation),
+		CurrentLocation: CurrentLocation{
+			Name: adventure.CurrentLocationName,
+			Lat:  adventure.CurrentLocationLat,
+			Lon:  adventure.CurrentLocationLon,
+		},
+		CurrentDistanceKm: adventure.CurrentDistance,
+		TotalDistanceKm:   adventure.TotalDistance,
+		Completed:         adventure.Completed == 1,
+		StartDate:         formatDate(adventure.StartDate),
+	}
+
+	if adventure.Completed == 1 {
+		adventureJson.EndDate = formatDate(adventure.EndDate)
+	}
+
+	return adventureJson, nil
+}
+
+// Adventures lists (GET) adventures of the logged in athlete, the most recent first, optionally filtered by the
+// completed query parameter, or starts (POST) a new one.
+func Adventures(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	switch req.Method {
+	case http.MethodGet:
+		filter := map[string]any{"athlete_id": resp.Session().UserId}
+		if value := req.URL.Query().Get("completed"); value != "" {
+			completed, err := strconv.ParseBool(value)
+			if err != nil {
+				return handler.NewHandlerError(http.StatusBadRequest, errors.New("completed has to be true or false"))
+			}
+
+			filter["completed"] = 0
+			if completed {
+				filter["completed"] = 1
+			}
+		}
+
+		pageReq, err := parsePageRequest(req)
+		if err != nil {
+			return err
+		}
+
+		adventures, err := model.AllAdventuresByStartDate(filter, pageReq.perPage, pageReq.offset(), app.SqlDb, nil)
+		if err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+
+		total, err := model.CountAdventures(app.SqlDb, nil, filter)
+		if err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+
+		adventuresJson := make([]Adventure, 0, len(adventures))
+		for i := range adventures {
+			adventureJson, err := adventureToJson(&adventures[i], app)
+			if err != nil {
+				return handler.NewHandlerError(http.StatusInternalServerError, err)
+			}
+
+			adventuresJson = append(adventuresJson, adventureJson)
+		}
+
+		return writeJson(resp, http.StatusOK, newPage(adventuresJson, pageReq, total))
+	case http.MethodPost:
+		var newAdventure NewAdventure
+		if err := readJson(resp, req, &newAdventure); err != nil {
+			return err
+		}
+
+		adventure, err := game.StartAdventure(resp.Session().UserId, newAdventure.StartLocation, newAdventure.EndLocation, app)
+		if err != nil {
+			return err
+		}
+
+		adventureJson, err := adventureToJson(adventure, app)
+		if err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+
+		return writeJson(resp, http.StatusCreated, adventureJson)
+	default:
+		return methodNotAllowed()
+	}
+}
+
+// loadAdventureFromPath loads the adventure of the logged in athlete identified by start and end path values.
+func loadAdventureFromPath(resp *handler.ResponseWithSession, req *http.Request, app *application.App) (*model.Adventure, error) {
+	startLocationId, err := strconv.Atoi(req.PathValue("start"))
+	if err != nil {
+		return nil, handler.NewHandlerError(http.StatusBadRequest, errors.New("start location id has to be an integer"))
+	}
+
+	endLocationId, err := strconv.Atoi(req.PathValue("end"))
+	if err != nil {
+		return nil, handler.NewHandlerError(http.StatusBadRequest, errors.New("end location id has to be an integer"))
+	}
+
+	var adventure model.Adventure
+	found, err := adventure.Load(resp.Session().UserId, startLocationId, endLocationId, app.SqlDb, nil)
+	if err != nil {
+		return nil, handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !found {
+		return nil, handler.NewHandlerError(http.StatusNotFound, errors.New("adventure not found"))
+	}
+
+	return &adventure, nil
+}
+
+// AdventureByLocations returns (GET) details of an adventure of the logged in athlete, or abandons it (DELETE).
+func AdventureByLocations(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodGet != req.Method && http.MethodDelete != req.Method {
+		return methodNotAllowed()
+	}
+
+	adventure, err := loadAdventureFromPath(resp, req, app)
+	if err != nil {
+		return err
+	}
+
+	if http.MethodDelete == req.Method {
+		if err = game.AbandonAdventure(adventure.AthleteId, adventure.StartLocation, adventure.EndLocation, app); err != nil {
+			return err
+		}
+
+		resp.WriteHeader(http.StatusNoContent)
+
+		return nil
+	}
+
+	adventureJson, err := adventureToJson(adventure, app)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	detail := AdventureDetail{Adventure: adventureJson, PassedPlaces: []string{}}
+
+	passedPlaces, err := model.AllAdventurePassedPlaces(app.SqlDb, nil, map[string]any{"athlete_id": adventure.AthleteId, "start_location": adventure.StartLocation, "end_location": adventure.EndLocation})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	for _, passedPlace := range passedPlaces {
+		detail.PassedPlaces = append(detail.PassedPlaces, passedPlace.Name)
+	}
+
+	// reads don't create the map image, it's left out until e.g. an activity is applied
+	var mapImage model.AdventureMapImage
+	found, err := mapImage.Load(adventure.AthleteId, adventure.StartLocation, adventure.EndLocation, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if found {
+		detail.MapImageUrl = app.GetFullAdventureMapImageUrl(mapImage.ImageId)
+	}
+
+	var publicLink model.AdventurePublicLink
+	found, err = publicLink.Load(adventure.AthleteId, adventure.StartLocation, adventure.EndLocation, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if found {
+		detail.PublicUrl = app.GetFullPublicAdventureUrl(publicLink.Slug)
+	}
+
+	return writeJson(resp, http.StatusOK, detail)
+}
+
+// AdventureActivities lists activities that counted towards an adventure of the logged in athlete, the most recent first.
+func AdventureActivities(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodGet != req.Method {
+		return methodNotAllowed()
+	}
+
+	adventure, err := loadAdventureFromPath(resp, req, app)
+	if err != nil {
+		return err
+	}
+
+	pageReq, err := parsePageRequest(req)
+	if err != nil {
+		return err
+	}
+
+	activities, err := helper.AdventureContributions(adventure, pageReq.perPage, pageReq.offset(), app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	total, err := helper.CountAdventureContributions(adventure, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	activitiesJson := make([]Activity, 0, len(activities))
+	for _, activity := range activities {
+		activitiesJson = append(activitiesJson, Activity{
+			Id:                 activity.Id,
+			SportType:          activity.SportType,
+			DistanceKm:         activity.Distance,
+			MovingTime:         activity.MovingTime,
+			ElapsedTime:        activity.ElapsedTime,
+			TotalElevationGain: activity.TotalElevationGain,
+			StartDate:          formatDate(activity.StartDate),
+		})
+	}
+
+	return writeJson(resp, http.StatusOK, newPage(activitiesJson, pageReq, total))
+}
+
+// ExportAdventure returns the course and the progress of an adventure of the logged in athlete as a GPX, KML or
+// GeoJSON file, depending on the format query parameter.
+func ExportAdventure(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodGet != req.Method {
+		return methodNotAllowed()
+	}
+
+	adventure, err := loadAdventureFromPath(resp, req, app)
+	if err != nil {
+		return err
+	}
+
+	format := req.URL.Query().Get("format")
+	contentType, ok := helper.AdventureExportContentTypes[format]
+	if !ok {
+		return handler.NewHandlerError(http.StatusBadRequest, errors.New("unsupported export format, expected gpx, kml or geojson"))
+	}
+
+	adventureExport, err := helper.LoadAdventureExport(adventure, app.SqlDb, nil, app.FileDb)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	fileName := fmt.Sprintf("adventure-%d-%d.%s", adventure.StartLocation, adventure.EndLocation, format)
+
+	content, err := helper.WriteAdventureFile(fileName, adventureExport)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	resp.Header().Set("Content-Type", contentType)
+	resp.Header().Set("Content-Disposition", "attachment; filename="+fileName)
+
+	if _, err = resp.Write(content); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+// Package api implements the versioned JSON API (/api/v1). All handlers respond with json, errors included
+// (see handler.WriteApiError).
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+const defaultPerPage = 20
+
+const maxPerPage = 100
+
+const maxRequestBodyBytes = 1 << 20
+
+// Page is the body of every response with a list of items.
+type Page[T any] struct {
+	Items   []T `json:"items"`
+	Page    int `json:"page"`
+	PerPage int `json:"per_page"`
+	Total   int `json:"total"`
+}
+
+// pageRequest is the page requested by the page (starting from 1) and per_page query parameters.
+type pageRequest struct {
+	page    int
+	perPage int
+}
+
+func parsePageRequest(req *http.Request) (pageRequest, error) {
+	pageReq := pageRequest{page: 1, perPage: defaultPerPage}
+
+	var err error
+	if value := req.URL.Query().Get("per_page"); value != "" {
+		if pageReq.perPage, err = strconv.Atoi(value); err != nil || pageReq.perPage < 1 || pageReq.perPage > maxPerPage {
+			return pageRequest{}, handler.NewHandlerError(http.StatusBadRequest, fmt.Errorf("per_page has to be between 1 and %d", maxPerPage))
+		}
+	}
+
+	if value := req.URL.Query().Get("page"); value != "" {
+		if pageReq.page, err = strconv.Atoi(value); err != nil || pageReq.page < 1 {
+			return pageRequest{}, handler.NewHandlerError(http.StatusBadRequest, errors.New("page has to be a positive integer"))
+		}
+
+		// the offset of the page has to fit into an int
+		if pageReq.page-1 > math.MaxInt/pageReq.perPage {
+			return pageRequest{}, handler.NewHandlerError(http.StatusBadRequest, errors.New("page is too large"))
+		}
+	}
+
+	return pageReq, nil
+}
+
+func (pageReq pageRequest) offset() int {
+	return (pageReq.page - 1) * pageReq.perPage
+}
+
+// newPage returns the page of items, which are loaded from the database by the page request, out of total items.
+func newPage[T any](items []T, pageReq pageRequest, total int) *Page[T] {
+	// empty list instead of null
+	if items == nil {
+		items = []T{}
+	}
+
+	return &Page[T]{Items: items, Page: pageReq.page, PerPage: pageReq.perPage, Total: total}
+}
+
+func writeJson(resp http.ResponseWriter, statusCode int, body any) error {
+	content, err := json.Marshal(body)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(statusCode)
+
+	if _, err = resp.Write(content); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+func readJson(resp http.ResponseWriter, req *http.Request, body any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(resp, req.Body, maxRequestBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(body); err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, fmt.Errorf("invalid json body: %w", err))
+	}
+
+	return nil
+}
+
+func methodNotAllowed() error {
+	return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+}
+
+func formatDate(unixTime int) string {
+	if unixTime == 0 {
+		return ""
+	}
+
+	return time.Unix(int64(unixTime), 0).UTC().Format(time.RFC3339)
+}
+
+type Location struct {
+	Id          int     `json:"id"`
+	Name        string  `json:"name"`
+	Lat         float64 `json:"lat"`
+	Lon         float64 `json:"lon"`
+	Country     string  `json:"country"`
+	Category    string  `json:"category"`
+	Description string  `json:"description"`
+	ImageUrl    string  `json:"image_url"`
+	Private     bool    `json:"private"`
+}
+
+func locationToJson(location *model.Location) Location {
+	return Location{
+		Id:          location.Id,
+		Name:        location.Name,
+		Lat:         location.Lat,
+		Lon:         location.Lon,
+		Country:     location.Country,
+		Category:    location.Category,
+		Description: location.Description,
+		ImageUrl:    location.ImageUrl,
+		Private:     location.OwnerId != 0,
+	}
+}
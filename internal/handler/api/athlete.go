@@ -0,0 +1,133 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+type Athlete struct {
+	Id        int64  `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	City      string `json:"city"`
+	Country   string `json:"country"`
+	Sex       string `json:"sex"`
+}
+
+type Settings struct {
+	AutoUpdateActivityDescription bool `json:"auto_update_activity_description"`
+	IsAdmin                       bool `json:"is_admin"`
+}
+
+type SettingsUpdate struct {
+	AutoUpdateActivityDescription *bool `json:"auto_update_activity_description"`
+}
+
+type Stats struct {
+	AdventuresStarted    int     `json:"adventures_started"`
+	AdventuresCompleted  int     `json:"adventures_completed"`
+	DistanceTraveledKm   float64 `json:"distance_traveled_km"`
+	Activities           int     `json:"activities"`
+	ActivitiesDistanceKm float64 `json:"activities_distance_km"`
+}
+
+// GetAthlete returns the profile of the logged in athlete.
+func GetAthlete(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodGet != req.Method {
+		return methodNotAllowed()
+	}
+
+	athlete := model.NewAthlete()
+	found, err := athlete.Load(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !found {
+		return handler.NewHandlerError(http.StatusNotFound, errors.New("athlete not found"))
+	}
+
+	return writeJson(resp, http.StatusOK, Athlete{
+		Id:        athlete.Id,
+		FirstName: athlete.FirstName,
+		LastName:  athlete.LastName,
+		City:      athlete.City,
+		Country:   athlete.Country,
+		Sex:       athlete.Sex,
+	})
+}
+
+// AthleteSettings returns (GET) or updates (PUT) settings of the logged in athlete.
+func AthleteSettings(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodGet != req.Method && http.MethodPut != req.Method {
+		return methodNotAllowed()
+	}
+
+	var athleteSettings model.AthleteSettings
+	found, err := athleteSettings.Load(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !found {
+		return handler.NewHandlerError(http.StatusInternalServerError, errors.New("settings not found"))
+	}
+
+	if http.MethodPut == req.Method {
+		var update SettingsUpdate
+		if err = readJson(resp, req, &update); err != nil {
+			return err
+		}
+
+		if update.AutoUpdateActivityDescription != nil {
+			athleteSettings.AutoUpdateActivityDescription = 0
+			if *update.AutoUpdateActivityDescription {
+				athleteSettings.AutoUpdateActivityDescription = 1
+			}
+		}
+
+		if err = athleteSettings.Save(app.SqlDb, nil); err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+	}
+
+	return writeJson(resp, http.StatusOK, Settings{
+		AutoUpdateActivityDescription: athleteSettings.AutoUpdateActivityDescription == 1,
+		IsAdmin:                       athleteSettings.IsAdmin == 1,
+	})
+}
+
+// GetStats returns totals over all adventures and activities of the logged in athlete.
+func GetStats(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodGet != req.Method {
+		return methodNotAllowed()
+	}
+
+	adventures, err := model.AllAdventures(app.SqlDb, nil, map[string]any{"athlete_id": resp.Session().UserId})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	activities, err := model.AllActivities(app.SqlDb, nil, map[string]any{"athlete_id": resp.Session().UserId})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	var stats Stats
+	for _, adventure := range adventures {
+		stats.AdventuresStarted++
+		stats.AdventuresCompleted += adventure.Completed
+		stats.DistanceTraveledKm += float64(adventure.CurrentDistance)
+	}
+
+	for _, activity := range activities {
+		stats.Activities++
+		stats.ActivitiesDistanceKm += float64(activity.Distance)
+	}
+
+	return writeJson(resp, http.StatusOK, stats)
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/game"
+	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+type NewLocation struct {
+	Name    string   `json:"name"`
+	Lat     *float64 `json:"lat"`
+	Lon     *float64 `json:"lon"`
+	Country string   `json:"country"`
+}
+
+// Locations lists (GET) locations visible to the logged in athlete, or creates (POST) a private location.
+func Locations(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	switch req.Method {
+	case http.MethodGet:
+		pageReq, err := parsePageRequest(req)
+		if err != nil {
+			return err
+		}
+
+		locations, err := model.AllLocationsVisibleTo(resp.Session().UserId, pageReq.perPage, pageReq.offset(), app.SqlDb, nil)
+		if err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+
+		total, err := model.CountLocationsVisibleTo(resp.Session().UserId, app.SqlDb, nil)
+		if err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+
+		locationsJson := make([]Location, 0, len(locations))
+		for i := range locations {
+			locationsJson = append(locationsJson, locationToJson(&locations[i]))
+		}
+
+		return writeJson(resp, http.StatusOK, newPage(locationsJson, pageReq, total))
+	case http.MethodPost:
+		var newLocation NewLocation
+		if err := readJson(resp, req, &newLocation); err != nil {
+			return err
+		}
+
+		if newLocation.Lat == nil || newLocation.Lon == nil {
+			return handler.NewHandlerError(http.StatusBadRequest, errors.New("lat and lon are required"))
+		}
+
+		location := model.Location{
+			Name:    newLocation.Name,
+			Lat:     *newLocation.Lat,
+			Lon:     *newLocation.Lon,
+			Country: newLocation.Country,
+			OwnerId: resp.Session().UserId,
+		}
+
+		if err := game.CreatePrivateLocation(&location, app); err != nil {
+			return err
+		}
+
+		return writeJson(resp, http.StatusCreated, locationToJson(&location))
+	default:
+		return methodNotAllowed()
+	}
+}
+
+// LocationById returns (GET) a location visible to the logged in athlete, or deletes (DELETE) their private location.
+func LocationById(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	id, err := strconv.Atoi(req.PathValue("id"))
+	if err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, errors.New("location id has to be an integer"))
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		var location model.Location
+		found, err := location.Load(id, app.SqlDb, nil)
+		if err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+
+		if !found || !helper.IsLocationVisibleTo(&location, resp.Session().UserId) {
+			return handler.NewHandlerError(http.StatusNotFound, errors.New("location not found"))
+		}
+
+		return writeJson(resp, http.StatusOK, locationToJson(&location))
+	case http.MethodDelete:
+		if err = game.DeletePrivateLocation(resp.Session().UserId, id, app); err != nil {
+			return err
+		}
+
+		resp.WriteHeader(http.StatusNoContent)
+
+		return nil
+	default:
+		return methodNotAllowed()
+	}
+}
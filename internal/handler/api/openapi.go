@@ -0,0 +1,28 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/handler"
+)
+
+//go:embed openapi.json
+var openApiDocument []byte
+
+// OpenApi serves the OpenAPI document describing the API. It's public, so that clients can be generated without
+// logging in.
+func OpenApi(resp http.ResponseWriter, req *http.Request, app *application.App) error {
+	if http.MethodGet != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+
+	if _, err := resp.Write(openApiDocument); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,315 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/database"
+	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+// entries shown in the sync log
+const maxActivitySyncsShown = 200
+
+// ActivitySyncView is an activity sync log entry as shown to the athlete or admin.
+type ActivitySyncView struct {
+	model.ActivitySync
+
+	ReceivedAtFormatted  string
+	ProcessAtFormatted   string
+	ProcessedAtFormatted string
+}
+
+// ActivitySyncCount is the number of log entries with the status.
+type ActivitySyncCount struct {
+	Status string
+	Count  int
+}
+
+// FailedActivityEventView is a queued activity event whose processing failed, as shown to admins.
+type FailedActivityEventView struct {
+	model.StravaWebhookEvent
+
+	FailedAtFormatted      string
+	NextAttemptAtFormatted string
+}
+
+func formatSyncTime(unixTime int) string {
+	if unixTime == 0 {
+		return ""
+	}
+
+	return time.Unix(int64(unixTime), 0).UTC().Format(time.DateTime)
+}
+
+// ActivitySync shows the athlete activity webhook events received from Strava recently, and what happened to them.
+func ActivitySync(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodGet != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	return renderActivitySync(resp, app, false, resp.Session().UserId, "")
+}
+
+// AdminActivitySync shows the sync log of all athletes, optionally filtered by athlete (athlete query parameter) and
+// status (status query parameter).
+func AdminActivitySync(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	isAdmin, err := helper.IsAthleteAdmin(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !isAdmin {
+		http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+		return nil
+	}
+
+	if http.MethodGet != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	var athleteId int64
+	if req.URL.Query().Get("athlete") != "" {
+		athleteId, err = strconv.ParseInt(req.URL.Query().Get("athlete"), 10, 64)
+		if err != nil {
+			return handler.NewHandlerError(http.StatusBadRequest, err)
+		}
+	}
+
+	status := req.URL.Query().Get("status")
+	if status != "" && !slices.Contains(model.ActivitySyncStatuses, status) {
+		return handler.NewHandlerError(http.StatusBadRequest, fmt.Errorf("unknown status %q", status))
+	}
+
+	return renderActivitySync(resp, app, true, athleteId, status)
+}
+
+// renderActivitySync renders the sync log of the athlete (0 for all athletes) with counts per status, and the entries with
+// the given status (all if empty). global is set for the admin view.
+func renderActivitySync(resp *handler.ResponseWithSession, app *application.App, global bool, athleteId int64, status string) error {
+	filter := map[string]any{}
+	if athleteId != 0 {
+		filter["athlete_id"] = athleteId
+	}
+
+	activitySyncs, err := model.AllActivitySyncs(app.SqlDb, nil, filter)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	countByStatus := make(map[string]int)
+	var entries []ActivitySyncView
+	for _, activitySync := range activitySyncs {
+		countByStatus[activitySync.Status]++
+
+		if status != "" && activitySync.Status != status {
+			continue
+		}
+
+		if len(entries) < maxActivitySyncsShown {
+			entries = append(entries, ActivitySyncView{
+				ActivitySync:         activitySync,
+				ReceivedAtFormatted:  formatSyncTime(activitySync.ReceivedAt),
+				ProcessAtFormatted:   formatSyncTime(activitySync.ProcessAt),
+				ProcessedAtFormatted: formatSyncTime(activitySync.ProcessedAt),
+			})
+		}
+	}
+
+	var counts []ActivitySyncCount
+	for _, syncStatus := range model.ActivitySyncStatuses {
+		counts = append(counts, ActivitySyncCount{Status: syncStatus, Count: countByStatus[syncStatus]})
+	}
+
+	// events in the queue right now, they are processed together with the later events of the same activity
+	pendingEvents, err := model.AllStravaWebhookEvents(app.SqlDb, nil, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	queued := 0
+	for _, pendingEvent := range pendingEvents {
+		if athleteId == 0 || pendingEvent.OwnerId == athleteId {
+			queued++
+		}
+	}
+
+	var failedEvents []FailedActivityEventView
+	if global {
+		failedEvents, err = failedActivityEvents(app, athleteId)
+		if err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+	}
+
+	backPage := app.GetDefaultPageLoggedInUsers()
+	if global {
+		backPage = app.GetAdminPanelPage()
+	}
+
+	err = app.Templates.ExecuteTemplate(resp, "activitysync.html", struct {
+		ProxyPathPrefix string
+		BackPage        string
+		Global          bool
+		AthleteId       int64
+		Status          string
+		Queued          int
+		Counts          []ActivitySyncCount
+		Entries         []ActivitySyncView
+		FailedEvents    []FailedActivityEventView
+	}{
+		ProxyPathPrefix: app.ProxyPathPrefix,
+		BackPage:        backPage,
+		Global:          global,
+		AthleteId:       athleteId,
+		Status:          status,
+		Queued:          queued,
+		Counts:          counts,
+		Entries:         entries,
+		FailedEvents:    failedEvents,
+	})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// failedActivityEvents returns queued events of the athlete (0 for all athletes) that failed at least once, the given up
+// (dead) ones first.
+func failedActivityEvents(app *application.App, athleteId int64) ([]FailedActivityEventView, error) {
+	filter := map[string]any{"attempts": model.ComparationOperation{FieldValue: 0, Operation: ">"}}
+	if athleteId != 0 {
+		filter["owner_id"] = athleteId
+	}
+
+	pendingEvents, err := model.AllStravaWebhookEvents(app.SqlDb, nil, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var failedEvents []FailedActivityEventView
+	for _, pendingEvent := range pendingEvents {
+		failedEvent := FailedActivityEventView{
+			StravaWebhookEvent: pendingEvent,
+			FailedAtFormatted:  formatSyncTime(pendingEvent.FailedAt),
+		}
+
+		if pendingEvent.Dead == 0 {
+			failedEvent.NextAttemptAtFormatted = formatSyncTime(pendingEvent.NextAttemptAt)
+		}
+
+		failedEvents = append(failedEvents, failedEvent)
+	}
+
+	slices.SortFunc(failedEvents, func(a, b FailedActivityEventView) int {
+		if a.Dead != b.Dead {
+			return b.Dead - a.Dead
+		}
+
+		return b.FailedAt - a.FailedAt
+	})
+
+	return failedEvents, nil
+}
+
+// RequeueActivityEvent gives a failed (or given up) queued activity event a fresh start: it's processed on the next run,
+// with its attempts reset.
+func RequeueActivityEvent(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodPost != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	isAdmin, err := helper.IsAthleteAdmin(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !isAdmin {
+		http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+		return nil
+	}
+
+	activityId, err := strconv.ParseInt(req.FormValue("activity"), 10, 64)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	found, err := model.ResetStravaWebhookEventRetryState(activityId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !found {
+		return handler.NewHandlerError(http.StatusNotFound, errors.New("queued event not found"))
+	}
+
+	http.Redirect(resp, req, app.ProxyPathPrefix+"/admin/sync", http.StatusFound)
+
+	return nil
+}
+
+// DiscardActivityEvent removes a failed (or given up) activity event from the queue, without processing it.
+func DiscardActivityEvent(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodPost != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	isAdmin, err := helper.IsAthleteAdmin(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !isAdmin {
+		http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+		return nil
+	}
+
+	activityId, err := strconv.ParseInt(req.FormValue("activity"), 10, 64)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	tx, err := app.SqlDb.Begin()
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+	defer tx.Rollback()
+
+	var pendingEvent model.StravaWebhookEvent
+	found, err := pendingEvent.Load(activityId, app.SqlDb, tx)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !found {
+		return handler.NewHandlerError(http.StatusNotFound, errors.New("queued event not found"))
+	}
+
+	if err = pendingEvent.Delete(app.SqlDb, tx); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	err = helper.ResolveActivitySync(pendingEvent.OwnerId, activityId, model.ActivitySyncDropped, "", "discarded by an admin after failed processing", app.SqlDb, tx)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if err = database.CommitOrRollbackTransaction(tx); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	http.Redirect(resp, req, app.ProxyPathPrefix+"/admin/sync", http.StatusFound)
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/database"
+	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+func AdminCuratedAdventures(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	isAdmin, err := helper.IsAthleteAdmin(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !isAdmin {
+		http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+		return nil
+	}
+
+	curatedAdventures, err := model.AllCuratedAdventures(app.SqlDb, nil, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	publicLocations, err := model.AllLocations(app.SqlDb, nil, map[string]any{"owner_id": 0})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	locationNames := make(map[int]string)
+	for _, location := range publicLocations {
+		locationNames[location.Id] = location.Name
+	}
+
+	// curated adventure to edit is selected by the id query parameter, otherwise the form is used to add a new one
+	var curatedToEdit model.CuratedAdventure
+	if idParam := req.URL.Query().Get("id"); idParam != "" {
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			return handler.NewHandlerError(http.StatusBadRequest, err)
+		}
+
+		found, err := curatedToEdit.Load(id, app.SqlDb, nil)
+		if err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+
+		if !found {
+			return handler.NewHandlerError(http.StatusNotFound, errors.New("curated adventure not found"))
+		}
+	}
+
+	err = app.Templates.ExecuteTemplate(resp, "admincuratedadventures.html", struct {
+		ProxyPathPrefix   string
+		AdminPanelPage    string
+		CuratedAdventures []model.CuratedAdventure
+		Locations         []model.Location
+		LocationNames     map[int]string
+		Difficulties      []string
+		Modes             []string
+		CuratedToEdit     model.CuratedAdventure
+	}{
+		ProxyPathPrefix:   app.ProxyPathPrefix,
+		AdminPanelPage:    app.GetAdminPanelPage(),
+		CuratedAdventures: curatedAdventures,
+		Locations:         publicLocations,
+		LocationNames:     locationNames,
+		Difficulties:      helper.CuratedAdventureDifficulties,
+		Modes:             helper.CuratedAdventureModes,
+		CuratedToEdit:     curatedToEdit,
+	})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+func SaveCuratedAdventure(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodPost != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	isAdmin, err := helper.IsAthleteAdmin(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !isAdmin {
+		http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+		return nil
+	}
+
+	curated := model.CuratedAdventure{
+		Title:         req.FormValue("title"),
+		Description:   req.FormValue("description"),
+		Difficulty:    req.FormValue("difficulty"),
+		CoverImageUrl: req.FormValue("coverImageUrl"),
+		Tags:          req.FormValue("tags"),
+		Mode:          req.FormValue("mode"),
+		CreatedAt:     int(time.Now().Unix()),
+	}
+
+	if idParam := req.FormValue("id"); idParam != "" && idParam != "0" {
+		if curated.Id, err = strconv.Atoi(idParam); err != nil {
+			return handler.NewHandlerError(http.StatusBadRequest, err)
+		}
+	}
+
+	if curated.StartLocation, err = strconv.Atoi(req.FormValue("start")); err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, errors.New("start location is not populated"))
+	}
+
+	if curated.EndLocation, err = strconv.Atoi(req.FormValue("end")); err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, errors.New("end location is not populated"))
+	}
+
+	distance, err := strconv.ParseFloat(req.FormValue("distance"), 32)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, errors.New("distance is not a number"))
+	}
+
+	curated.Distance = float32(distance)
+
+	tx, err := app.SqlDb.Begin()
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+	defer tx.Rollback()
+
+	if curated.Id != 0 {
+		var existingCurated model.CuratedAdventure
+
+		found, err := existingCurated.Load(curated.Id, app.SqlDb, tx)
+		if err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+
+		if !found {
+			return handler.NewHandlerError(http.StatusNotFound, errors.New("curated adventure not found"))
+		}
+
+		curated.CreatedAt = existingCurated.CreatedAt
+	}
+
+	if err = helper.ValidateCuratedAdventure(&curated, app.SqlDb, tx); err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	if err = curated.Save(app.SqlDb, tx); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if err = database.CommitOrRollbackTransaction(tx); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	http.Redirect(resp, req, app.ProxyPathPrefix+"/admin/adventures", http.StatusFound)
+
+	return nil
+}
+
+func DeleteCuratedAdventure(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodPost != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	isAdmin, err := helper.IsAthleteAdmin(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !isAdmin {
+		http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+		return nil
+	}
+
+	id, err := strconv.Atoi(req.FormValue("id"))
+	if err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	// adventures started from the catalogue are kept, they are not tied to the curated adventure
+	curated := model.CuratedAdventure{Id: id}
+	if err = curated.Delete(app.SqlDb, nil); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	http.Redirect(resp, req, app.ProxyPathPrefix+"/admin/adventures", http.StatusFound)
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+// runs shown in the run history
+const maxJobRunsShown = 50
+
+// ScheduledJobView is a scheduled job with its last run, as shown to admins.
+type ScheduledJobView struct {
+	application.JobStatus
+
+	NextRunFormatted string
+	LastRun          *JobRunView
+	LeaseHolder      string // the instance running the job, empty if no instance holds its lease
+}
+
+// JobRunView is a run of a scheduled job, as shown to admins.
+type JobRunView struct {
+	model.JobRun
+
+	StartedAtFormatted string
+}
+
+// InstanceView is an instance of the application sharing the database, as shown to admins.
+type InstanceView struct {
+	model.InstanceHeartbeat
+
+	StartedAtFormatted   string
+	HeartbeatAtFormatted string
+	Alive                bool
+	Current              bool // the instance serving the page
+}
+
+// AdminJobs shows the scheduled jobs with their schedules, last and next runs and lease holders, the recent run history,
+// and the instances of the application.
+func AdminJobs(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	isAdmin, err := helper.IsAthleteAdmin(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !isAdmin {
+		http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+		return nil
+	}
+
+	if http.MethodGet != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	// newest first
+	runs, err := model.AllJobRuns(app.SqlDb, nil, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	lastRunByJob := make(map[string]*JobRunView)
+	var recentRuns []JobRunView
+	for _, run := range runs {
+		runView := JobRunView{
+			JobRun:             run,
+			StartedAtFormatted: formatSyncTime(run.StartedAt),
+		}
+
+		if _, found := lastRunByJob[run.Job]; !found {
+			lastRunByJob[run.Job] = &runView
+		}
+
+		if len(recentRuns) < maxJobRunsShown {
+			recentRuns = append(recentRuns, runView)
+		}
+	}
+
+	leases, err := model.AllJobLeases(app.SqlDb, nil, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	now := time.Now()
+
+	leaseHolderByJob := make(map[string]string)
+	for _, lease := range leases {
+		if int64(lease.ExpiresAt) > now.Unix() {
+			leaseHolderByJob[lease.Job] = lease.Holder
+		}
+	}
+
+	var jobs []ScheduledJobView
+	for _, jobStatus := range app.CronSvc.Jobs() {
+		jobView := ScheduledJobView{
+			JobStatus:   jobStatus,
+			LastRun:     lastRunByJob[jobStatus.Name],
+			LeaseHolder: leaseHolderByJob[jobStatus.Name],
+		}
+
+		if !jobStatus.NextRun.IsZero() {
+			jobView.NextRunFormatted = formatSyncTime(int(jobStatus.NextRun.Unix()))
+		}
+
+		jobs = append(jobs, jobView)
+	}
+
+	heartbeats, err := model.AllInstanceHeartbeats(app.SqlDb, nil, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	var instances []InstanceView
+	for _, heartbeat := range heartbeats {
+		instances = append(instances, InstanceView{
+			InstanceHeartbeat:    heartbeat,
+			StartedAtFormatted:   formatSyncTime(heartbeat.StartedAt),
+			HeartbeatAtFormatted: formatSyncTime(heartbeat.HeartbeatAt),
+			Alive:                application.IsInstanceAlive(&heartbeat, now),
+			Current:              heartbeat.Instance == app.CronSvc.Instance(),
+		})
+	}
+
+	err = app.Templates.ExecuteTemplate(resp, "adminjobs.html", struct {
+		ProxyPathPrefix string
+		BackPage        string
+		Instance        string
+		Jobs            []ScheduledJobView
+		Runs            []JobRunView
+		Instances       []InstanceView
+	}{
+		ProxyPathPrefix: app.ProxyPathPrefix,
+		BackPage:        app.GetAdminPanelPage(),
+		Instance:        app.CronSvc.Instance(),
+		Jobs:            jobs,
+		Runs:            recentRuns,
+		Instances:       instances,
+	})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// RunJobNow starts the scheduled job (name form field) right away, outside of its schedule. It fails if another instance
+// of the application holds the lease of the job.
+func RunJobNow(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodPost != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	isAdmin, err := helper.IsAthleteAdmin(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !isAdmin {
+		http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+		return nil
+	}
+
+	err = app.CronSvc.RunNow(req.FormValue("name"))
+	if errors.Is(err, application.ErrJobNotFound) {
+		return handler.NewHandlerError(http.StatusNotFound, err)
+	} else if errors.Is(err, application.ErrJobRunning) || errors.Is(err, application.ErrJobLeased) {
+		return handler.NewHandlerError(http.StatusConflict, err)
+	} else if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	http.Redirect(resp, req, app.ProxyPathPrefix+"/admin/jobs", http.StatusFound)
+
+	return nil
+}
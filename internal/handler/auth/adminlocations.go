@@ -0,0 +1,378 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/database"
+	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+func AdminLocations(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	isAdmin, err := helper.IsAthleteAdmin(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !isAdmin {
+		http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+		return nil
+	}
+
+	// location to edit is selected by the id query parameter, otherwise the form is used to add a new location
+	var locationToEdit model.Location
+	if idParam := req.URL.Query().Get("id"); idParam != "" {
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			return handler.NewHandlerError(http.StatusBadRequest, err)
+		}
+
+		found, err := locationToEdit.Load(id, app.SqlDb, nil)
+		if err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+
+		if !found {
+			return handler.NewHandlerError(http.StatusNotFound, errors.New("location not found"))
+		}
+	}
+
+	return renderAdminLocations(resp, app, locationToEdit, nil)
+}
+
+// renderAdminLocations renders the locations page, with the result of the last import if there is one.
+func renderAdminLocations(resp *handler.ResponseWithSession, app *application.App, locationToEdit model.Location, importResult *helper.LocationImportResult) error {
+	locations, err := model.AllLocations(app.SqlDb, nil, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	slices.SortFunc(locations, func(a, b model.Location) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+
+	var categories []string
+	for _, location := range locations {
+		if location.Category != "" && !slices.Contains(categories, location.Category) {
+			categories = append(categories, location.Category)
+		}
+	}
+
+	err = app.Templates.ExecuteTemplate(resp, "adminlocations.html", struct {
+		ProxyPathPrefix string
+		AdminPanelPage  string
+		Locations       []model.Location
+		Categories      []string
+		LocationToEdit  model.Location
+		ImportResult    *helper.LocationImportResult
+	}{
+		ProxyPathPrefix: app.ProxyPathPrefix,
+		AdminPanelPage:  app.GetAdminPanelPage(),
+		Locations:       locations,
+		Categories:      categories,
+		LocationToEdit:  locationToEdit,
+		ImportResult:    importResult,
+	})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+func SaveLocation(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodPost != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	isAdmin, err := helper.IsAthleteAdmin(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !isAdmin {
+		http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+		return nil
+	}
+
+	location := model.Location{
+		Name:        req.FormValue("name"),
+		Country:     req.FormValue("country"),
+		Description: req.FormValue("description"),
+		ImageUrl:    req.FormValue("imageUrl"),
+		Category:    req.FormValue("category"),
+	}
+
+	if idParam := req.FormValue("id"); idParam != "" && idParam != "0" {
+		if location.Id, err = strconv.Atoi(idParam); err != nil {
+			return handler.NewHandlerError(http.StatusBadRequest, err)
+		}
+	}
+
+	if location.Lat, err = strconv.ParseFloat(strings.TrimSpace(req.FormValue("lat")), 64); err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, fmt.Errorf("latitude is not a number: %w", err))
+	}
+
+	if location.Lon, err = strconv.ParseFloat(strings.TrimSpace(req.FormValue("lon")), 64); err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, fmt.Errorf("longitude is not a number: %w", err))
+	}
+
+	if err = helper.ValidateLocation(&location); err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	tx, err := app.SqlDb.Begin()
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+	defer tx.Rollback()
+
+	if location.Id != 0 {
+		var existingLocation model.Location
+
+		found, err := existingLocation.Load(location.Id, app.SqlDb, tx)
+		if err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+
+		if !found {
+			return handler.NewHandlerError(http.StatusNotFound, errors.New("location not found"))
+		}
+
+		// editing doesn't change visibility, private locations are made public only by promoting them
+		location.OwnerId = existingLocation.OwnerId
+
+		// courses are fetched once and kept by location ids, so moving a used location would leave them pointing to the old place
+		if existingLocation.Lat != location.Lat || existingLocation.Lon != location.Lon {
+			inUse, err := model.LocationInUse(location.Id, app.SqlDb, tx)
+			if err != nil {
+				return handler.NewHandlerError(http.StatusInternalServerError, err)
+			}
+
+			if inUse {
+				return handler.NewHandlerError(http.StatusConflict, errors.New("coordinates of a location used by adventures or routes can't be changed"))
+			}
+		}
+	}
+
+	if err = location.Save(app.SqlDb, tx); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if err = database.CommitOrRollbackTransaction(tx); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	http.Redirect(resp, req, app.ProxyPathPrefix+"/admin/locations", http.StatusFound)
+
+	return nil
+}
+
+func DeleteLocation(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodPost != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	isAdmin, err := helper.IsAthleteAdmin(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !isAdmin {
+		http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+		return nil
+	}
+
+	id, err := strconv.Atoi(req.FormValue("id"))
+	if err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	tx, err := app.SqlDb.Begin()
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+	defer tx.Rollback()
+
+	inUse, err := model.LocationInUse(id, app.SqlDb, tx)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if inUse {
+		return handler.NewHandlerError(http.StatusConflict, errors.New("location is used by adventures or routes and can't be deleted"))
+	}
+
+	location := model.Location{Id: id}
+	if err = location.Delete(app.SqlDb, tx); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if err = database.CommitOrRollbackTransaction(tx); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	http.Redirect(resp, req, app.ProxyPathPrefix+"/admin/locations", http.StatusFound)
+
+	return nil
+}
+
+func ImportLocations(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodPost != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	isAdmin, err := helper.IsAthleteAdmin(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !isAdmin {
+		http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+		return nil
+	}
+
+	if err = req.ParseMultipartForm(maxRouteFileSize); err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	dedupeDistanceKm, err := strconv.ParseFloat(req.FormValue("dedupeDistanceKm"), 64)
+	if err != nil || dedupeDistanceKm < 0 {
+		return handler.NewHandlerError(http.StatusBadRequest, errors.New("dedupe distance has to be a non-negative number"))
+	}
+
+	file, fileHeader, err := req.FormFile("locationsFile")
+	if err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, fmt.Errorf("locations file is missing: %w", err))
+	}
+
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	waypoints, err := helper.ParseWaypointsFile(fileHeader.Filename, content)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	tx, err := app.SqlDb.Begin()
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+	defer tx.Rollback()
+
+	result, err := helper.ImportLocations(waypoints, dedupeDistanceKm, app.SqlDb, tx)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if err = database.CommitOrRollbackTransaction(tx); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	return renderAdminLocations(resp, app, model.Location{}, result)
+}
+
+func ExportLocations(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	isAdmin, err := helper.IsAthleteAdmin(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !isAdmin {
+		http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+		return nil
+	}
+
+	format := req.URL.Query().Get("format")
+	contentTypes := map[string]string{
+		"csv":     "text/csv",
+		"geojson": "application/geo+json",
+		"gpx":     "application/gpx+xml",
+	}
+
+	contentType, ok := contentTypes[format]
+	if !ok {
+		return handler.NewHandlerError(http.StatusBadRequest, errors.New("unsupported export format, expected csv, geojson or gpx"))
+	}
+
+	// private locations of athletes are not a part of the catalogue
+	locations, err := model.AllLocations(app.SqlDb, nil, map[string]any{"owner_id": 0})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	fileName := "locations." + format
+
+	content, err := helper.WriteWaypointsFile(fileName, helper.LocationsToWaypoints(locations))
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	resp.Header().Set("Content-Type", contentType)
+	resp.Header().Set("Content-Disposition", "attachment; filename="+fileName)
+
+	if _, err = resp.Write(content); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// PromoteLocation makes a private location of an athlete visible to everyone.
+func PromoteLocation(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodPost != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	isAdmin, err := helper.IsAthleteAdmin(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !isAdmin {
+		http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+		return nil
+	}
+
+	id, err := strconv.Atoi(req.FormValue("id"))
+	if err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	var location model.Location
+	found, err := location.Load(id, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !found {
+		return handler.NewHandlerError(http.StatusNotFound, errors.New("location not found"))
+	}
+
+	location.OwnerId = 0
+	if err = location.Save(app.SqlDb, nil); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	http.Redirect(resp, req, app.ProxyPathPrefix+"/admin/locations", http.StatusFound)
+
+	return nil
+}
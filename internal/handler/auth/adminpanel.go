@@ -46,13 +46,20 @@ func AdminPanel(resp *handler.ResponseWithSession, req *http.Request, app *appli
 		}
 	}
 
+	importedRoutes, err := model.AllImportedRoutes(app.SqlDb, nil, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
 	// render the admin panel page
 	err = app.Templates.ExecuteTemplate(resp, "adminpanel.html", struct {
 		ProxyPathPrefix     string
 		WebhookSubscription model.StravaWebhookSubscription
+		ImportedRoutes      []model.ImportedRoute
 	}{
 		ProxyPathPrefix:     app.ProxyPathPrefix,
 		WebhookSubscription: webhooksubscription,
+		ImportedRoutes:      importedRoutes,
 	})
 	if err != nil {
 		return handler.NewHandlerError(http.StatusInternalServerError, err)
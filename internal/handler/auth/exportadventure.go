@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+)
+
+// ExportAdventure lets the athlete download the course of one of their adventures, with the completed part, the
+// remaining part and the current location, as a GPX, KML or GeoJSON file.
+func ExportAdventure(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	format := req.URL.Query().Get("format")
+	contentType, ok := helper.AdventureExportContentTypes[format]
+	if !ok {
+		return handler.NewHandlerError(http.StatusBadRequest, errors.New("unsupported export format, expected gpx, kml or geojson"))
+	}
+
+	adventure, err := loadAdventureFromForm(resp, req, app)
+	if err != nil {
+		return err
+	}
+
+	adventureExport, err := helper.LoadAdventureExport(adventure, app.SqlDb, nil, app.FileDb)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	fileName := fmt.Sprintf("adventure-%d-%d.%s", adventure.StartLocation, adventure.EndLocation, format)
+
+	content, err := helper.WriteAdventureFile(fileName, adventureExport)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	resp.Header().Set("Content-Type", contentType)
+	resp.Header().Set("Content-Disposition", "attachment; filename="+fileName)
+
+	if _, err = resp.Write(content); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
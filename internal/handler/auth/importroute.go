@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/database"
+	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/service/routing"
+)
+
+const maxRouteFileSize = 20 << 20
+
+func ImportRoute(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodPost != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	isAdmin, err := helper.IsAthleteAdmin(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !isAdmin {
+		http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+		return nil
+	}
+
+	if err = req.ParseMultipartForm(maxRouteFileSize); err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	name := req.FormValue("name")
+	startName := req.FormValue("startName")
+	endName := req.FormValue("endName")
+	if name == "" || startName == "" || endName == "" {
+		return handler.NewHandlerError(http.StatusBadRequest, errors.New("route name, start and end names are required"))
+	}
+
+	file, fileHeader, err := req.FormFile("routeFile")
+	if err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, fmt.Errorf("route file is missing: %w", err))
+	}
+
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	line, elevations, err := helper.ParseRouteFile(fileHeader.Filename, content)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	tx, err := app.SqlDb.Begin()
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+	defer tx.Rollback()
+
+	importedRoute, err := routing.SaveImportedRoute(name, startName, endName, line, elevations, int(time.Now().Unix()), app.SqlDb, tx, app.FileDb)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if err = database.CommitOrRollbackTransaction(tx); err != nil {
+		// the id can be given to another route later, which mustn't find this course
+		if deleteErr := routing.DeleteImportedRouteCourse(importedRoute.Id, app.FileDb); deleteErr != nil {
+			slog.Error("ImportRoute > Failed to remove the course of a route which wasn't imported.", "importedRouteId", importedRoute.Id, "error", deleteErr)
+		}
+
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	http.Redirect(resp, req, app.GetAdminPanelPage(), http.StatusFound)
+
+	return nil
+}
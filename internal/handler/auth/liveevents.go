@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+// keeps the connection open through proxies which close idle connections
+const liveEventsHeartbeatInterval = 25 * time.Second
+
+// LiveEvents streams live events of the athlete (Server-Sent Events): states of their activities on the way from
+// Strava into the adventure, and new positions of the ongoing adventure. Activities waiting in the queue are sent
+// right after connecting. The stream ends when the server is shutting down, browsers reconnect by themselves.
+func LiveEvents(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodGet != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	// subscribe before reading the queue, so that no state change is missed in between
+	events, unsubscribe := app.EventBus.Subscribe(resp.Session().UserId)
+	defer unsubscribe()
+
+	pendingEvents, err := model.AllStravaWebhookEvents(app.SqlDb, nil, map[string]any{"owner_id": resp.Session().UserId})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	responseController := http.NewResponseController(resp)
+
+	resp.Header().Set("Content-Type", "text/event-stream")
+	resp.Header().Set("Cache-Control", "no-cache")
+	resp.Header().Set("X-Accel-Buffering", "no") // nginx would buffer the stream otherwise
+	resp.WriteHeader(http.StatusOK)
+
+	for _, pendingEvent := range pendingEvents {
+		stateUpdate := helper.QueuedActivityState(&pendingEvent, app.StravaSvc.GetProcessWebhookEventsAfterSec())
+
+		err = writeLiveEvent(resp, helper.LiveEvent{Type: helper.LiveEventActivity, Activity: &stateUpdate})
+		if err != nil {
+			return nil // the client is gone
+		}
+	}
+
+	// the response has started, errors can't be reported to the client anymore
+	if err = responseController.Flush(); err != nil {
+		slog.Info("LiveEvents > Failed to flush the stream.", "athlete_id", resp.Session().UserId, "error", err)
+
+		return nil
+	}
+
+	heartbeat := time.NewTicker(liveEventsHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return nil
+		case <-app.EventBus.Done():
+			return nil // the server is shutting down, it waits for the open streams otherwise
+		case event := <-events:
+			err = writeLiveEvent(resp, event)
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(resp, ": heartbeat\n\n")
+		}
+
+		if err == nil {
+			err = responseController.Flush()
+		}
+
+		if err != nil {
+			slog.Info("LiveEvents > The stream ended.", "athlete_id", resp.Session().UserId, "error", err)
+
+			return nil // the client is gone
+		}
+	}
+}
+
+func writeLiveEvent(resp http.ResponseWriter, event helper.LiveEvent) error {
+	content, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(resp, "event: %s\ndata: %s\n\n", event.Type, content)
+
+	return err
+}
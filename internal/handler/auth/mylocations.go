@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/game"
+	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+const maxSearchResults = 8
+
+// SearchLocations looks up places by name (for creating private locations), and returns them as json.
+func SearchLocations(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	text := strings.TrimSpace(req.URL.Query().Get("q"))
+	if len([]rune(text)) < 2 {
+		return handler.NewHandlerError(http.StatusBadRequest, errors.New("search text has to have at least 2 characters"))
+	}
+
+	results, err := app.SearchSvc.Search(text, maxSearchResults)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusFailedDependency, err)
+	}
+
+	resultsJson, err := json.Marshal(results)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+
+	if _, err = resp.Write(resultsJson); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// CreateMyLocation creates a location visible only to the athlete who created it.
+func CreateMyLocation(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodPost != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	location := model.Location{
+		Name:    req.FormValue("name"),
+		Country: req.FormValue("country"),
+		OwnerId: resp.Session().UserId,
+	}
+
+	var err error
+	if location.Lat, err = strconv.ParseFloat(strings.TrimSpace(req.FormValue("lat")), 64); err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, fmt.Errorf("latitude is not a number: %w", err))
+	}
+
+	if location.Lon, err = strconv.ParseFloat(strings.TrimSpace(req.FormValue("lon")), 64); err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, fmt.Errorf("longitude is not a number: %w", err))
+	}
+
+	if err = game.CreatePrivateLocation(&location, app); err != nil {
+		return err
+	}
+
+	http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+	return nil
+}
+
+// DeleteMyLocation deletes a private location of the athlete, if no adventure uses it.
+func DeleteMyLocation(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodPost != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	id, err := strconv.Atoi(req.FormValue("id"))
+	if err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	if err = game.DeletePrivateLocation(resp.Session().UserId, id, app); err != nil {
+		return err
+	}
+
+	http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+	return nil
+}
@@ -4,38 +4,96 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/database"
 	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/helper"
 	"github.com/miki208/stravaadventuregame/internal/model"
 )
 
+const maxApiTokensPerAthlete = 20
+
+// ApiTokenView is an API token as shown on the settings page.
+type ApiTokenView struct {
+	Id                  int
+	Name                string
+	Scopes              string
+	CreatedAtFormatted  string
+	LastUsedAtFormatted string
+	ExpiresAtFormatted  string
+	Expired             bool
+}
+
+func formatApiTokenDate(unixTime int) string {
+	if unixTime == 0 {
+		return "never"
+	}
+
+	return time.Unix(int64(unixTime), 0).UTC().Format(time.DateOnly)
+}
+
+// renderSettings renders the settings page. newApiToken is shown only once, right after the token is created.
+func renderSettings(resp *handler.ResponseWithSession, app *application.App, newApiToken string) error {
+	var athleteSettings model.AthleteSettings
+	settingsFound, err := athleteSettings.Load(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !settingsFound {
+		return handler.NewHandlerError(http.StatusInternalServerError, fmt.Errorf("settings not found"))
+	}
+
+	apiTokens, err := model.AllApiTokens(app.SqlDb, nil, map[string]any{"athlete_id": resp.Session().UserId})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	now := time.Now()
+
+	apiTokenViews := make([]ApiTokenView, 0, len(apiTokens))
+	for i := range apiTokens {
+		apiTokenViews = append(apiTokenViews, ApiTokenView{
+			Id:                  apiTokens[i].Id,
+			Name:                apiTokens[i].Name,
+			Scopes:              apiTokens[i].Scopes,
+			CreatedAtFormatted:  formatApiTokenDate(apiTokens[i].CreatedAt),
+			LastUsedAtFormatted: formatApiTokenDate(apiTokens[i].LastUsedAt),
+			ExpiresAtFormatted:  formatApiTokenDate(apiTokens[i].ExpiresAt),
+			Expired:             helper.IsApiTokenExpired(&apiTokens[i], now),
+		})
+	}
+
+	err = app.Templates.ExecuteTemplate(resp, "settings.html", struct {
+		ProxyPathPrefix string
+		AthleteSettings model.AthleteSettings
+		ApiTokens       []ApiTokenView
+		ApiTokenScopes  []string
+		NewApiToken     string
+	}{
+		ProxyPathPrefix: app.ProxyPathPrefix,
+		AthleteSettings: athleteSettings,
+		ApiTokens:       apiTokenViews,
+		ApiTokenScopes:  helper.ApiTokenScopes,
+		NewApiToken:     newApiToken,
+	})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
 func Settings(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
 	if http.MethodGet != req.Method && http.MethodPost != req.Method {
 		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
 	}
 
 	if http.MethodGet == req.Method {
-		var athleteSettings model.AthleteSettings
-		settingsFound, err := athleteSettings.Load(resp.Session().UserId, app.SqlDb, nil)
-		if err != nil {
-			return handler.NewHandlerError(http.StatusInternalServerError, err)
-		}
-
-		if !settingsFound {
-			return handler.NewHandlerError(http.StatusInternalServerError, fmt.Errorf("settings not found"))
-		}
-
-		err = app.Templates.ExecuteTemplate(resp, "settings.html", struct {
-			ProxyPathPrefix string
-			AthleteSettings model.AthleteSettings
-		}{
-			ProxyPathPrefix: app.ProxyPathPrefix,
-			AthleteSettings: athleteSettings,
-		})
-		if err != nil {
-			return handler.NewHandlerError(http.StatusInternalServerError, err)
-		}
+		return renderSettings(resp, app, "")
 	} else if http.MethodPost == req.Method {
 		if err := req.ParseForm(); err != nil {
 			return handler.NewHandlerError(http.StatusBadRequest, err)
@@ -68,3 +126,76 @@ func Settings(resp *handler.ResponseWithSession, req *http.Request, app *applica
 
 	return nil
 }
+
+// CreateApiToken issues a new API token and shows it on the settings page. This is the only time the token is shown.
+func CreateApiToken(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodPost != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	if err := req.ParseForm(); err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	expiresInDays, err := strconv.Atoi(req.FormValue("expiresInDays"))
+	if err != nil || expiresInDays < 0 {
+		return handler.NewHandlerError(http.StatusBadRequest, errors.New("expiration has to be a non-negative number of days"))
+	}
+
+	tx, err := app.SqlDb.Begin()
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+	defer tx.Rollback()
+
+	apiTokens, err := model.AllApiTokens(app.SqlDb, tx, map[string]any{"athlete_id": resp.Session().UserId})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if len(apiTokens) >= maxApiTokensPerAthlete {
+		return handler.NewHandlerError(http.StatusBadRequest, fmt.Errorf("you can't have more than %d API tokens", maxApiTokensPerAthlete))
+	}
+
+	token, _, err := helper.CreateApiToken(resp.Session().UserId, req.FormValue("name"), req.Form["scopes"],
+		time.Duration(expiresInDays)*24*time.Hour, app.SqlDb, tx)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	if err = database.CommitOrRollbackTransaction(tx); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	return renderSettings(resp, app, token)
+}
+
+// RevokeApiToken deletes an API token of the athlete, so it can't be used anymore.
+func RevokeApiToken(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodPost != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	id, err := strconv.Atoi(req.FormValue("id"))
+	if err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	var apiToken model.ApiToken
+	found, err := apiToken.Load(id, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !found || apiToken.AthleteId != resp.Session().UserId {
+		return handler.NewHandlerError(http.StatusNotFound, errors.New("API token not found"))
+	}
+
+	if err = apiToken.Delete(app.SqlDb, nil); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	http.Redirect(resp, req, app.ProxyPathPrefix+"/settings", http.StatusFound)
+
+	return nil
+}
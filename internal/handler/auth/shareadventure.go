@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+// ShareAdventure makes an adventure of the athlete public. If it's already public, the link is rotated, together with
+// the map image, so that neither of the old links works anymore.
+func ShareAdventure(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodPost != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	adventure, err := loadAdventureFromForm(resp, req, app)
+	if err != nil {
+		return err
+	}
+
+	isPublic, err := model.AdventurePublicLinkExists(adventure.AthleteId, adventure.StartLocation, adventure.EndLocation, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if _, err = helper.PublishAdventure(adventure, app.SqlDb, nil); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if isPublic {
+		if err = helper.RotateAdventureMapImage(adventure, app.MapImageDirectory, app.SqlDb, nil); err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+	}
+
+	http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+	return nil
+}
+
+// UnshareAdventure revokes the public link of an adventure of the athlete. The map image is rotated too, since the public
+// page links to it.
+func UnshareAdventure(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodPost != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	adventure, err := loadAdventureFromForm(resp, req, app)
+	if err != nil {
+		return err
+	}
+
+	publicLink := model.AdventurePublicLink{AthleteId: adventure.AthleteId, StartLocation: adventure.StartLocation, EndLocation: adventure.EndLocation}
+	if err = publicLink.Delete(app.SqlDb, nil); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if err = helper.RotateAdventureMapImage(adventure, app.MapImageDirectory, app.SqlDb, nil); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+	return nil
+}
+
+// loadAdventureFromForm loads the adventure of the logged in athlete identified by start and end form values.
+func loadAdventureFromForm(resp *handler.ResponseWithSession, req *http.Request, app *application.App) (*model.Adventure, error) {
+	startLocationId, err := strconv.Atoi(req.FormValue("start"))
+	if err != nil {
+		return nil, handler.NewHandlerError(http.StatusBadRequest, fmt.Errorf("start location is not populated: %w", err))
+	}
+
+	endLocationId, err := strconv.Atoi(req.FormValue("end"))
+	if err != nil {
+		return nil, handler.NewHandlerError(http.StatusBadRequest, fmt.Errorf("end location is not populated: %w", err))
+	}
+
+	var adventure model.Adventure
+	found, err := adventure.Load(resp.Session().UserId, startLocationId, endLocationId, app.SqlDb, nil)
+	if err != nil {
+		return nil, handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !found {
+		return nil, handler.NewHandlerError(http.StatusNotFound, errors.New("adventure not found"))
+	}
+
+	return &adventure, nil
+}
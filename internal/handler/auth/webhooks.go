@@ -0,0 +1,243 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+const maxWebhookEndpointsPerAthlete = 5
+
+// deliveries shown in the delivery log
+const maxWebhookDeliveriesShown = 50
+
+// WebhookDeliveryView is a webhook delivery as shown in the delivery log.
+type WebhookDeliveryView struct {
+	model.WebhookDelivery
+
+	Url                    string
+	CreatedAtFormatted     string
+	LastAttemptAtFormatted string
+	NextAttemptAtFormatted string
+}
+
+func formatWebhookTime(unixTime int) string {
+	if unixTime == 0 {
+		return ""
+	}
+
+	return time.Unix(int64(unixTime), 0).UTC().Format(time.DateTime)
+}
+
+// Webhooks lets the athlete manage webhook endpoints that receive events of their adventures.
+func Webhooks(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodGet != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	return renderWebhooks(resp, app, resp.Session().UserId)
+}
+
+// AdminWebhooks lets admins manage webhook endpoints that receive events of all athletes.
+func AdminWebhooks(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	isAdmin, err := helper.IsAthleteAdmin(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !isAdmin {
+		http.Redirect(resp, req, app.GetDefaultPageLoggedInUsers(), http.StatusFound)
+
+		return nil
+	}
+
+	if http.MethodGet != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	return renderWebhooks(resp, app, 0)
+}
+
+// renderWebhooks renders endpoints of the owner (0 for admin endpoints) and the log of their latest deliveries.
+func renderWebhooks(resp *handler.ResponseWithSession, app *application.App, ownerId int64) error {
+	endpoints, err := model.AllWebhookEndpoints(app.SqlDb, nil, map[string]any{"athlete_id": ownerId})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	var deliveries []WebhookDeliveryView
+	for _, endpoint := range endpoints {
+		endpointDeliveries, err := model.AllWebhookDeliveries(app.SqlDb, nil, map[string]any{"endpoint_id": endpoint.Id})
+		if err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+
+		for _, delivery := range endpointDeliveries {
+			deliveryView := WebhookDeliveryView{
+				WebhookDelivery:        delivery,
+				Url:                    endpoint.Url,
+				CreatedAtFormatted:     formatWebhookTime(delivery.CreatedAt),
+				LastAttemptAtFormatted: formatWebhookTime(delivery.LastAttemptAt),
+			}
+
+			if delivery.Status == model.WebhookDeliveryPending {
+				deliveryView.NextAttemptAtFormatted = formatWebhookTime(delivery.NextAttemptAt)
+			}
+
+			deliveries = append(deliveries, deliveryView)
+		}
+	}
+
+	// the latest first
+	slices.SortFunc(deliveries, func(a, b WebhookDeliveryView) int {
+		return b.Id - a.Id
+	})
+
+	if len(deliveries) > maxWebhookDeliveriesShown {
+		deliveries = deliveries[:maxWebhookDeliveriesShown]
+	}
+
+	backPage := app.ProxyPathPrefix + "/settings"
+	if ownerId == 0 {
+		backPage = app.GetAdminPanelPage()
+	}
+
+	err = app.Templates.ExecuteTemplate(resp, "webhooks.html", struct {
+		ProxyPathPrefix string
+		BackPage        string
+		Global          bool
+		Endpoints       []model.WebhookEndpoint
+		Deliveries      []WebhookDeliveryView
+		Events          []string
+	}{
+		ProxyPathPrefix: app.ProxyPathPrefix,
+		BackPage:        backPage,
+		Global:          ownerId == 0,
+		Endpoints:       endpoints,
+		Deliveries:      deliveries,
+		Events:          helper.WebhookEvents,
+	})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// webhookOwnerFromForm returns the athlete whose endpoints are managed, or 0 for admin endpoints (global form value),
+// together with the page to go back to.
+func webhookOwnerFromForm(resp *handler.ResponseWithSession, req *http.Request, app *application.App) (int64, string, error) {
+	if req.FormValue("global") == "" {
+		return resp.Session().UserId, app.ProxyPathPrefix + "/webhooks", nil
+	}
+
+	isAdmin, err := helper.IsAthleteAdmin(resp.Session().UserId, app.SqlDb, nil)
+	if err != nil {
+		return 0, "", handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !isAdmin {
+		return 0, "", handler.NewHandlerError(http.StatusForbidden, errors.New("only admins can manage global webhooks"))
+	}
+
+	return 0, app.ProxyPathPrefix + "/admin/webhooks", nil
+}
+
+// CreateWebhook registers a new webhook endpoint, with a random secret for signing payloads.
+func CreateWebhook(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodPost != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	if err := req.ParseForm(); err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	ownerId, redirectTo, err := webhookOwnerFromForm(resp, req, app)
+	if err != nil {
+		return err
+	}
+
+	if err = helper.ValidateWebhookUrl(req.FormValue("url")); err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	events, err := helper.ValidateWebhookEvents(req.Form["events"])
+	if err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	if ownerId != 0 {
+		endpoints, err := model.AllWebhookEndpoints(app.SqlDb, nil, map[string]any{"athlete_id": ownerId})
+		if err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+
+		if len(endpoints) >= maxWebhookEndpointsPerAthlete {
+			return handler.NewHandlerError(http.StatusBadRequest, fmt.Errorf("you can't have more than %d webhooks", maxWebhookEndpointsPerAthlete))
+		}
+	}
+
+	endpoint := model.WebhookEndpoint{
+		AthleteId: ownerId,
+		Url:       req.FormValue("url"),
+		Secret:    helper.NewWebhookSecret(),
+		Events:    events,
+		CreatedAt: int(time.Now().Unix()),
+	}
+
+	if err = endpoint.Save(app.SqlDb, nil); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	http.Redirect(resp, req, redirectTo, http.StatusFound)
+
+	return nil
+}
+
+// DeleteWebhook deletes a webhook endpoint, together with its delivery log and queued events.
+func DeleteWebhook(resp *handler.ResponseWithSession, req *http.Request, app *application.App) error {
+	if http.MethodPost != req.Method {
+		return handler.NewHandlerError(http.StatusMethodNotAllowed, nil)
+	}
+
+	if err := req.ParseForm(); err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	ownerId, redirectTo, err := webhookOwnerFromForm(resp, req, app)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(req.FormValue("id"))
+	if err != nil {
+		return handler.NewHandlerError(http.StatusBadRequest, err)
+	}
+
+	var endpoint model.WebhookEndpoint
+	found, err := endpoint.Load(id, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !found || endpoint.AthleteId != ownerId {
+		return handler.NewHandlerError(http.StatusNotFound, errors.New("webhook not found"))
+	}
+
+	if err = endpoint.Delete(app.SqlDb, nil); err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	http.Redirect(resp, req, redirectTo, http.StatusFound)
+
+	return nil
+}
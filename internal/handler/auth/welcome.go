@@ -3,6 +3,7 @@ package auth
 import (
 	"fmt"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/miki208/stravaadventuregame/internal/application"
@@ -28,11 +29,18 @@ func Welcome(resp *handler.ResponseWithSession, req *http.Request, app *applicat
 		return nil
 	}
 
-	availableLocations, err := model.AllLocations(app.SqlDb, nil, nil)
+	availableLocations, err := helper.LocationsVisibleTo(athlete.Id, app.SqlDb, nil)
 	if err != nil {
 		return handler.NewHandlerError(http.StatusInternalServerError, err)
 	}
 
+	var myLocations []model.Location
+	for _, location := range availableLocations {
+		if location.OwnerId == athlete.Id {
+			myLocations = append(myLocations, location)
+		}
+	}
+
 	type AdventureExtended struct {
 		Adventure          *model.Adventure
 		CompletedRoute     orb.LineString
@@ -41,6 +49,14 @@ func Welcome(resp *handler.ResponseWithSession, req *http.Request, app *applicat
 		EndLocation        *model.Location
 		StartDateFormatted string
 		EndDateFormatted   string
+		PassedPlaces       []string
+		NextPlace          string
+		NextPlaceDistance  string
+		ElevationProfile   [][2]float64
+		AscentSoFar        string
+		TotalAscent        string
+		MapImageUrl        string
+		PublicUrl          string // empty if the adventure isn't public
 	}
 
 	adventureToAdventureExtended := func(adv *model.Adventure) (AdventureExtended, error) {
@@ -56,44 +72,64 @@ func Welcome(resp *handler.ResponseWithSession, req *http.Request, app *applicat
 			return AdventureExtended{}, err
 		}
 
-		var completedRoute, notCompletedRoute orb.LineString
-		if adv.Completed == 0 { // we're going to populate routes for not completed adventures only
-			courseDbName := fmt.Sprintf("%d-%d", min(adv.StartLocation, adv.EndLocation),
-				max(adv.StartLocation, adv.EndLocation))
+		passedPlaces, err := model.AllAdventurePassedPlaces(app.SqlDb, nil, map[string]any{"athlete_id": adv.AthleteId, "start_location": adv.StartLocation, "end_location": adv.EndLocation})
+		if err != nil {
+			return AdventureExtended{}, err
+		}
 
-			var route *model.DirectionsRoute = model.NewDirectionsRoute()
-			err := app.FileDb.Read("course", courseDbName, route)
+		var passedPlaceNames []string
+		for _, passedPlace := range passedPlaces {
+			passedPlaceNames = append(passedPlaceNames, passedPlace.Name)
+		}
+
+		var nextPlace, nextPlaceDistance string
+		if adv.Completed == 0 {
+			coursePlaces, found, err := helper.LoadCoursePlaces(app.FileDb, adv)
 			if err != nil {
 				return AdventureExtended{}, err
 			}
 
-			routePolyline, err := helper.DecodePolyline(route.Geometry, adv.StartLocation > adv.EndLocation)
+			if found {
+				if place, distanceKm, ok := helper.NextCoursePlace(coursePlaces, float64(adv.CurrentDistance)); ok {
+					nextPlace, nextPlaceDistance = place.Name, fmt.Sprintf("%.1f", distanceKm)
+				}
+			}
+		}
+
+		var elevationProfile [][2]float64
+		var ascentSoFar, totalAscent string
+		var completedRoute, notCompletedRoute orb.LineString
+		if adv.Completed == 0 { // we're going to populate routes for not completed adventures only
+			route, err := helper.LoadAdventureRoute(app.FileDb, adv)
 			if err != nil {
 				return AdventureExtended{}, err
 			}
 
-			if adv.CurrentLocationIndexOnRoute == -1 {
-				completedRoute = routePolyline
-			} else {
-				for i := 0; i < len(routePolyline); i++ {
-					if i < adv.CurrentLocationIndexOnRoute {
-						completedRoute = append(completedRoute, orb.Point{routePolyline[i].Lon(), routePolyline[i].Lat()})
-					} else if i > adv.CurrentLocationIndexOnRoute {
-						notCompletedRoute = append(notCompletedRoute, orb.Point{routePolyline[i].Lon(), routePolyline[i].Lat()})
-					}
-
-					if i == adv.CurrentLocationIndexOnRoute {
-						completedRoute = append(completedRoute, orb.Point{routePolyline[i].Lon(), routePolyline[i].Lat()})
-
-						if routePolyline[i].Lat() != adv.CurrentLocationLat || routePolyline[i].Lon() != adv.CurrentLocationLon {
-							completedRoute = append(completedRoute, orb.Point{adv.CurrentLocationLon, adv.CurrentLocationLat})
-							notCompletedRoute = append(notCompletedRoute, orb.Point{adv.CurrentLocationLon, adv.CurrentLocationLat})
-						} else {
-							notCompletedRoute = append(notCompletedRoute, orb.Point{routePolyline[i].Lon(), routePolyline[i].Lat()})
-						}
-					}
-				}
+			if route.Elevations != nil {
+				ascent, _ := helper.ElevationGain(route.Elevations)
+
+				elevationProfile = helper.ElevationProfile(route.Course, route.Elevations, 200)
+				ascentSoFar = fmt.Sprintf("%.0f", helper.AscentAlongLine(route.Course, route.Elevations, float64(adv.CurrentDistance*1000)))
+				totalAscent = fmt.Sprintf("%.0f", ascent)
 			}
+
+			completedRoute, notCompletedRoute = route.Completed, route.NotCompleted
+		}
+
+		mapImage, err := helper.AdventureMapImageOf(adv, app.SqlDb, nil)
+		if err != nil {
+			return AdventureExtended{}, err
+		}
+
+		var publicUrl string
+		var publicLink model.AdventurePublicLink
+		found, err = publicLink.Load(adv.AthleteId, adv.StartLocation, adv.EndLocation, app.SqlDb, nil)
+		if err != nil {
+			return AdventureExtended{}, err
+		}
+
+		if found {
+			publicUrl = app.GetFullPublicAdventureUrl(publicLink.Slug)
 		}
 
 		return AdventureExtended{
@@ -104,6 +140,14 @@ func Welcome(resp *handler.ResponseWithSession, req *http.Request, app *applicat
 			EndLocation:        &endLocation,
 			StartDateFormatted: time.Unix(int64(adv.StartDate), 0).UTC().Format(time.DateTime),
 			EndDateFormatted:   time.Unix(int64(adv.EndDate), 0).UTC().Format(time.DateTime),
+			PassedPlaces:       passedPlaceNames,
+			NextPlace:          nextPlace,
+			NextPlaceDistance:  nextPlaceDistance,
+			ElevationProfile:   elevationProfile,
+			AscentSoFar:        ascentSoFar,
+			TotalAscent:        totalAscent,
+			MapImageUrl:        app.GetAdventureMapImageUrl(mapImage.ImageId),
+			PublicUrl:          publicUrl,
 		}, nil
 	}
 
@@ -139,18 +183,89 @@ func Welcome(resp *handler.ResponseWithSession, req *http.Request, app *applicat
 		completedAdventuresExtended = append(completedAdventuresExtended, adventureExtended)
 	}
 
+	type CatalogueEntry struct {
+		Curated       *model.CuratedAdventure
+		StartLocation *model.Location
+		EndLocation   *model.Location
+		Tags          []string
+		CompletedBy   int
+		CompletedByMe bool
+	}
+
+	curatedAdventures, err := model.AllCuratedAdventures(app.SqlDb, nil, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	var catalogue []CatalogueEntry
+	var catalogueCountries []string
+	for i := range curatedAdventures {
+		curated := &curatedAdventures[i]
+
+		var startLocation, endLocation model.Location
+		if found, err := startLocation.Load(curated.StartLocation, app.SqlDb, nil); err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		} else if !found {
+			continue
+		}
+
+		if found, err := endLocation.Load(curated.EndLocation, app.SqlDb, nil); err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		} else if !found {
+			continue
+		}
+
+		routeFilter := map[string]any{"start_location": curated.StartLocation, "end_location": curated.EndLocation, "completed": 1}
+
+		completedBy, err := model.CountAdventures(app.SqlDb, nil, routeFilter)
+		if err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+
+		routeFilter["athlete_id"] = athlete.Id
+		completedByMe, err := model.CountAdventures(app.SqlDb, nil, routeFilter)
+		if err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+
+		for _, country := range []string{startLocation.Country, endLocation.Country} {
+			if country != "" && !slices.Contains(catalogueCountries, country) {
+				catalogueCountries = append(catalogueCountries, country)
+			}
+		}
+
+		catalogue = append(catalogue, CatalogueEntry{
+			Curated:       curated,
+			StartLocation: &startLocation,
+			EndLocation:   &endLocation,
+			Tags:          helper.SplitTags(curated.Tags),
+			CompletedBy:   completedBy,
+			CompletedByMe: completedByMe > 0,
+		})
+	}
+
+	slices.Sort(catalogueCountries)
+
 	err = app.Templates.ExecuteTemplate(resp, "welcome.html", struct {
 		ProxyPathPrefix     string
 		Athl                *model.Athlete
 		StartedAdventures   []AdventureExtended
 		CompletedAdventures []AdventureExtended
 		AvailableLocations  []model.Location
+		MyLocations         []model.Location
+		Catalogue           []CatalogueEntry
+		CatalogueCountries  []string
+		CatalogueModes      []string
 	}{
 		ProxyPathPrefix:     app.ProxyPathPrefix,
 		Athl:                athlete,
 		StartedAdventures:   startedAdventuresExtended,
 		CompletedAdventures: completedAdventuresExtended,
 		AvailableLocations:  availableLocations,
+		MyLocations:         myLocations,
+		Catalogue:           catalogue,
+		CatalogueCountries:  catalogueCountries,
+		CatalogueModes:      helper.CuratedAdventureModes,
 	})
 	if err != nil {
 		return handler.NewHandlerError(http.StatusInternalServerError, err)
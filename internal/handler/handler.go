@@ -1,13 +1,17 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/miki208/stravaadventuregame/internal/application"
 	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
 )
 
 type HandlerError struct {
@@ -30,9 +34,48 @@ func (handlerError *HandlerError) Error() string {
 	return fmt.Sprintf("Handler error (%d): %v", handlerError.statusCode, handlerError.err)
 }
 
+func (handlerError *HandlerError) Unwrap() error {
+	return handlerError.err
+}
+
+// Message returns the message of the underlying error, or the status text if there is none.
+func (handlerError *HandlerError) Message() string {
+	if handlerError.err == nil {
+		return http.StatusText(handlerError.statusCode)
+	}
+
+	return handlerError.err.Error()
+}
+
+// ApiError is the body of every error response of the JSON API.
+type ApiError struct {
+	Error ApiErrorDetails `json:"error"`
+}
+
+type ApiErrorDetails struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// WriteApiError writes the error as a json body, with the status code of the handler error (500 for other errors).
+func WriteApiError(resp http.ResponseWriter, err error) {
+	handlerError, ok := err.(*HandlerError)
+	if !ok {
+		handlerError = NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(handlerError.StatusCode())
+
+	json.NewEncoder(resp).Encode(ApiError{Error: ApiErrorDetails{Status: handlerError.StatusCode(), Message: handlerError.Message()}})
+}
+
 type ResponseWithSession struct {
 	http.ResponseWriter
 	session *helper.Session
+
+	// set if the request is authenticated with an API token instead of a session cookie
+	apiToken *model.ApiToken
 }
 
 func NewResponseWithSession(resp http.ResponseWriter, session *helper.Session) *ResponseWithSession {
@@ -42,6 +85,16 @@ func NewResponseWithSession(resp http.ResponseWriter, session *helper.Session) *
 	}
 }
 
+// NewResponseWithApiToken is used for requests authenticated with an API token. The session only identifies the
+// athlete, there is no session cookie.
+func NewResponseWithApiToken(resp http.ResponseWriter, apiToken *model.ApiToken) *ResponseWithSession {
+	return &ResponseWithSession{
+		ResponseWriter: resp,
+		session:        &helper.Session{UserId: apiToken.AthleteId},
+		apiToken:       apiToken,
+	}
+}
+
 func (resp *ResponseWithSession) InvalidateSession() {
 	if resp.session != nil {
 		resp.session.SessionCookie.Expires = time.Now().Add(-time.Hour)
@@ -52,8 +105,17 @@ func (resp *ResponseWithSession) Session() *helper.Session {
 	return resp.session
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer (e.g. to flush streamed responses).
+func (resp *ResponseWithSession) Unwrap() http.ResponseWriter {
+	return resp.ResponseWriter
+}
+
+func (resp *ResponseWithSession) ApiToken() *model.ApiToken {
+	return resp.apiToken
+}
+
 func (resp *ResponseWithSession) WriteHeader(statusCode int) {
-	if resp.session != nil {
+	if resp.session != nil && resp.apiToken == nil {
 		http.SetCookie(resp.ResponseWriter, &resp.session.SessionCookie)
 	}
 
@@ -61,7 +123,7 @@ func (resp *ResponseWithSession) WriteHeader(statusCode int) {
 }
 
 func (resp *ResponseWithSession) Write(b []byte) (int, error) {
-	if resp.session != nil {
+	if resp.session != nil && resp.apiToken == nil {
 		http.SetCookie(resp.ResponseWriter, &resp.session.SessionCookie)
 	}
 
@@ -95,6 +157,75 @@ func MakeHandlerWSession(app *application.App, fn FuncHandlerWSession) func(http
 	}
 }
 
+// MakeApiHandler is like MakeHandlerWSession, but for the JSON API: instead of redirecting to the login page and
+// responding with plain text errors, it responds with json error bodies. Besides the session cookie, requests can be
+// authenticated with an API token (Authorization: Bearer <token>). Tokens with only the read scope can't make any
+// requests other than GET.
+func MakeApiHandler(app *application.App, fn FuncHandlerWSession) func(http.ResponseWriter, *http.Request) {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		var respWithSession *ResponseWithSession
+
+		if authorization := req.Header.Get("Authorization"); authorization != "" {
+			token, found := strings.CutPrefix(authorization, "Bearer ")
+			if !found {
+				WriteApiError(resp, NewHandlerError(http.StatusUnauthorized, errors.New("unsupported authorization scheme, expected Bearer")))
+
+				return
+			}
+
+			apiToken, err := helper.FindApiToken(strings.TrimSpace(token), app.SqlDb, nil)
+			if err != nil {
+				slog.Error("ApiHandler > Error occurred while looking up the API token.", "error", err, "route", req.URL.Path)
+
+				WriteApiError(resp, err)
+
+				return
+			}
+
+			if apiToken == nil {
+				WriteApiError(resp, NewHandlerError(http.StatusUnauthorized, errors.New("invalid or expired API token")))
+
+				return
+			}
+
+			requiredScope := helper.ApiTokenScopeWrite
+			if http.MethodGet == req.Method || http.MethodHead == req.Method {
+				requiredScope = helper.ApiTokenScopeRead
+			}
+
+			if !helper.ApiTokenHasScope(apiToken, requiredScope) {
+				WriteApiError(resp, NewHandlerError(http.StatusForbidden, fmt.Errorf("API token doesn't have the %s scope", requiredScope)))
+
+				return
+			}
+
+			respWithSession = NewResponseWithApiToken(resp, apiToken)
+		} else {
+			session := app.SessionMgr.GetSessionByRequest(req)
+			if session == nil {
+				WriteApiError(resp, NewHandlerError(http.StatusUnauthorized, errors.New("not logged in")))
+
+				return
+			}
+
+			respWithSession = NewResponseWithSession(resp, session)
+		}
+
+		err := fn(respWithSession, req, app)
+		if err != nil {
+			if apiToken := respWithSession.ApiToken(); apiToken != nil {
+				slog.Error("ApiHandler > Error occurred while handling request.", "error", err, "route", req.URL.Path, "api_token_id", apiToken.Id, "user_id", apiToken.AthleteId)
+			} else {
+				slog.Error("ApiHandler > Error occurred while handling request.", "error", err, "route", req.URL.Path, "session_id", respWithSession.Session().SessionCookie.Value, "user_id", respWithSession.Session().UserId)
+			}
+
+			WriteApiError(resp, err)
+
+			return
+		}
+	}
+}
+
 func MakeHandlerWoutSession(app *application.App, fn FuncHandler) func(http.ResponseWriter, *http.Request) {
 	return func(resp http.ResponseWriter, req *http.Request) {
 		session := app.SessionMgr.GetSessionByRequest(req)
@@ -77,7 +77,7 @@ func StravaAuthCallback(w http.ResponseWriter, req *http.Request, app *applicati
 		}
 	}
 
-	err = database.CommitOrRollbackSQLiteTransaction(tx)
+	err = database.CommitOrRollbackTransaction(tx)
 	if err != nil {
 		return handler.NewHandlerError(http.StatusInternalServerError, err)
 	}
@@ -7,7 +7,7 @@ import (
 )
 
 func StravaWebhookCallback(resp http.ResponseWriter, req *http.Request, app *application.App) error {
-	app.StravaSvc.StravaWebhookCallback(resp, req, app.SqlDb, app.SessionMgr)
+	app.StravaSvc.StravaWebhookCallback(resp, req, app.SqlDb, app.SessionMgr, app.EventBus)
 
 	return nil
 }
@@ -0,0 +1,122 @@
+package other
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+	"github.com/miki208/stravaadventuregame/internal/service/maprender"
+	"github.com/paulmach/orb"
+)
+
+// AdventureMap serves the map image of an adventure to anyone who knows its image id, so it can be shared or embedded.
+// The image is rendered again only if the progress of the adventure changed since it was rendered last time.
+func AdventureMap(resp http.ResponseWriter, req *http.Request, app *application.App) error {
+	imageId, ok := strings.CutSuffix(req.PathValue("image"), ".png")
+	if !ok || imageId == "" {
+		return handler.NewHandlerError(http.StatusNotFound, errors.New("map image not found"))
+	}
+
+	mapImages, err := model.AllAdventureMapImages(app.SqlDb, nil, map[string]any{"image_id": imageId})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if len(mapImages) == 0 {
+		return handler.NewHandlerError(http.StatusNotFound, errors.New("map image not found"))
+	}
+
+	mapImage := &mapImages[0]
+
+	var adventure model.Adventure
+	found, err := adventure.Load(mapImage.AthleteId, mapImage.StartLocation, mapImage.EndLocation, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !found {
+		return handler.NewHandlerError(http.StatusNotFound, errors.New("adventure not found"))
+	}
+
+	imagePath := app.MapImageDirectory + mapImage.ImageId + ".png"
+	progress := helper.AdventureProgress(&adventure)
+
+	var content []byte
+	if mapImage.Progress == progress {
+		content, err = os.ReadFile(imagePath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+	}
+
+	if content == nil {
+		content, err = renderAdventureMap(&adventure, app)
+		if err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+
+		if err = writeMapImage(app.MapImageDirectory, imagePath, content); err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+
+		mapImage.Progress = progress
+		mapImage.RenderedAt = int(time.Now().Unix())
+
+		saved, err := mapImage.SaveRendering(app.SqlDb, nil)
+		if err != nil {
+			return handler.NewHandlerError(http.StatusInternalServerError, err)
+		}
+
+		if !saved {
+			// the image id was rotated while the image was rendered, it's not served under the old one anymore
+			os.Remove(imagePath)
+
+			return handler.NewHandlerError(http.StatusNotFound, errors.New("map image not found"))
+		}
+
+		slog.Debug("Adventure map rendered.", "imageId", mapImage.ImageId, "progress", progress)
+	}
+
+	resp.Header().Set("Content-Type", "image/png")
+	resp.Header().Set("Cache-Control", "public, max-age=300")
+
+	http.ServeContent(resp, req, mapImage.ImageId+".png", time.Unix(int64(mapImage.RenderedAt), 0), bytes.NewReader(content))
+
+	return nil
+}
+
+func renderAdventureMap(adventure *model.Adventure, app *application.App) ([]byte, error) {
+	adventureExport, err := helper.LoadAdventureExport(adventure, app.SqlDb, nil, app.FileDb)
+	if err != nil {
+		return nil, err
+	}
+
+	return app.MapRenderer.RenderPng(&maprender.Map{
+		Completed:    adventureExport.Route.Completed,
+		NotCompleted: adventureExport.Route.NotCompleted,
+		Start:        orb.Point{adventureExport.Start.Lon, adventureExport.Start.Lat},
+		End:          orb.Point{adventureExport.End.Lon, adventureExport.End.Lat},
+		Current:      orb.Point{adventureExport.CurrentLocation.Lon, adventureExport.CurrentLocation.Lat},
+	})
+}
+
+// writeMapImage writes the image to a temporary file first, so that a concurrent request never reads a partial image.
+func writeMapImage(directory, imagePath string, content []byte) error {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(imagePath+".tmp", content, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(imagePath+".tmp", imagePath)
+}
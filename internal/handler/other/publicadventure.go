@@ -0,0 +1,159 @@
+package other
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+	"github.com/paulmach/orb"
+)
+
+// number of most recent activities shown on the public page
+const publicContributionsCount = 10
+
+// PublicAdventure shows an adventure that the athlete made public to anyone who knows its slug. Only the progress and
+// the date, type and distance of contributing activities are shown, nothing else about the activities.
+func PublicAdventure(resp http.ResponseWriter, req *http.Request, app *application.App) error {
+	publicLinks, err := model.AllAdventurePublicLinks(app.SqlDb, nil, map[string]any{"slug": req.PathValue("slug")})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if len(publicLinks) == 0 {
+		return handler.NewHandlerError(http.StatusNotFound, errors.New("adventure not found"))
+	}
+
+	publicLink := &publicLinks[0]
+
+	var adventure model.Adventure
+	found, err := adventure.Load(publicLink.AthleteId, publicLink.StartLocation, publicLink.EndLocation, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !found {
+		return handler.NewHandlerError(http.StatusNotFound, errors.New("adventure not found"))
+	}
+
+	athlete := model.NewAthlete()
+	found, err = athlete.Load(adventure.AthleteId, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	if !found {
+		return handler.NewHandlerError(http.StatusNotFound, errors.New("athlete not found"))
+	}
+
+	adventureExport, err := helper.LoadAdventureExport(&adventure, app.SqlDb, nil, app.FileDb)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	passedPlaces, err := model.AllAdventurePassedPlaces(app.SqlDb, nil, map[string]any{"athlete_id": adventure.AthleteId, "start_location": adventure.StartLocation, "end_location": adventure.EndLocation})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	var passedPlaceNames []string
+	for _, passedPlace := range passedPlaces {
+		passedPlaceNames = append(passedPlaceNames, passedPlace.Name)
+	}
+
+	activities, err := helper.AdventureContributions(&adventure, publicContributionsCount, 0, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	type Contribution struct {
+		Date      string
+		SportType string
+		Distance  string
+	}
+
+	var contributions []Contribution
+	for _, activity := range activities {
+		contributions = append(contributions, Contribution{
+			Date:      time.Unix(int64(activity.StartDate), 0).UTC().Format(time.DateOnly),
+			SportType: activity.SportType,
+			Distance:  fmt.Sprintf("%.2f", activity.Distance),
+		})
+	}
+
+	// the map image is created when the adventure is published, an anonymous read doesn't write
+	var mapImage model.AdventureMapImage
+	found, err = mapImage.Load(adventure.AthleteId, adventure.StartLocation, adventure.EndLocation, app.SqlDb, nil)
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	mapImageUrl := ""
+	if found {
+		mapImageUrl = app.GetFullAdventureMapImageUrl(mapImage.ImageId)
+	}
+
+	// last name is shortened to its initial, like Strava does for athletes you don't follow
+	athleteName := athlete.FirstName
+	if athlete.LastName != "" {
+		athleteName += " " + string([]rune(athlete.LastName)[0]) + "."
+	}
+
+	percentage := 100.0
+	if adventure.TotalDistance > 0 {
+		percentage = float64(adventure.CurrentDistance / adventure.TotalDistance * 100)
+	}
+
+	var description string
+	if adventure.Completed == 1 {
+		description = fmt.Sprintf("%s has completed the %.0f km adventure from %s to %s.", athleteName, adventure.TotalDistance, adventureExport.Start.Name, adventureExport.End.Name)
+	} else {
+		description = fmt.Sprintf("%s is at %s, %.1f of %.0f km from %s to %s.", athleteName, adventure.CurrentLocationName, adventure.CurrentDistance,
+			adventure.TotalDistance, adventureExport.Start.Name, adventureExport.End.Name)
+	}
+
+	err = app.Templates.ExecuteTemplate(resp, "publicadventure.html", struct {
+		ProxyPathPrefix    string
+		PageUrl            string
+		MapImageUrl        string
+		Title              string
+		Description        string
+		AthleteName        string
+		Adventure          *model.Adventure
+		Start              helper.Waypoint
+		End                helper.Waypoint
+		Percentage         string
+		StartDateFormatted string
+		EndDateFormatted   string
+		CompletedRoute     orb.LineString
+		NotCompletedRoute  orb.LineString
+		PassedPlaces       []string
+		Contributions      []Contribution
+	}{
+		ProxyPathPrefix:    app.ProxyPathPrefix,
+		PageUrl:            app.GetFullPublicAdventureUrl(publicLink.Slug),
+		MapImageUrl:        mapImageUrl,
+		Title:              athleteName + "'s adventure: " + adventureExport.Start.Name + " - " + adventureExport.End.Name,
+		Description:        description,
+		AthleteName:        athleteName,
+		Adventure:          &adventure,
+		Start:              adventureExport.Start,
+		End:                adventureExport.End,
+		Percentage:         fmt.Sprintf("%.0f", percentage),
+		StartDateFormatted: time.Unix(int64(adventure.StartDate), 0).UTC().Format(time.DateOnly),
+		EndDateFormatted:   time.Unix(int64(adventure.EndDate), 0).UTC().Format(time.DateOnly),
+		CompletedRoute:     adventureExport.Route.Completed,
+		NotCompletedRoute:  adventureExport.Route.NotCompleted,
+		PassedPlaces:       passedPlaceNames,
+		Contributions:      contributions,
+	})
+	if err != nil {
+		return handler.NewHandlerError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
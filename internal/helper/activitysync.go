@@ -0,0 +1,53 @@
+package helper
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+// RecordActivitySyncReceived logs a received activity webhook event. processAt is when the queued event gets processed.
+func RecordActivitySyncReceived(athleteId int64, activityId int64, aspectType string, processAt int64, db *sql.DB, tx *sql.Tx) error {
+	activitySync := model.ActivitySync{
+		AthleteId:  athleteId,
+		ActivityId: activityId,
+		AspectType: aspectType,
+		Status:     model.ActivitySyncPending,
+		ReceivedAt: int(time.Now().Unix()),
+		ProcessAt:  int(processAt),
+	}
+
+	return activitySync.Save(db, tx)
+}
+
+// ResolveActivitySync records the outcome for all received events of the activity which are still waiting (or have failed
+// before), since they are processed together as one queued event. sportType and reason are optional.
+func ResolveActivitySync(athleteId int64, activityId int64, status string, sportType string, reason string, db *sql.DB, tx *sql.Tx) error {
+	activitySyncs, err := model.AllActivitySyncs(db, tx, map[string]any{"athlete_id": athleteId, "activity_id": activityId})
+	if err != nil {
+		return err
+	}
+
+	now := int(time.Now().Unix())
+
+	for _, activitySync := range activitySyncs {
+		if activitySync.Status != model.ActivitySyncPending && activitySync.Status != model.ActivitySyncFailed {
+			continue
+		}
+
+		activitySync.Status = status
+		activitySync.Reason = reason
+		activitySync.ProcessedAt = now
+
+		if sportType != "" {
+			activitySync.SportType = sportType
+		}
+
+		if err = activitySync.Save(db, tx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package helper
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/miki208/stravaadventuregame/internal/database/databasetest"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+func TestResolveActivitySync(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		entries := []struct {
+			athleteId  int64
+			activityId int64
+			status     string
+			wantStatus string
+		}{
+			{1, 10, model.ActivitySyncPending, model.ActivitySyncUpdated},
+			{1, 10, model.ActivitySyncFailed, model.ActivitySyncUpdated},  // retried together with the pending one
+			{1, 10, model.ActivitySyncCreated, model.ActivitySyncCreated}, // already resolved
+			{1, 11, model.ActivitySyncPending, model.ActivitySyncPending}, // another activity
+			{2, 10, model.ActivitySyncPending, model.ActivitySyncPending}, // another athlete
+		}
+
+		ids := make([]int, len(entries))
+		for i, entry := range entries {
+			if err := RecordActivitySyncReceived(entry.athleteId, entry.activityId, "update", 1700000000, db, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			syncs, err := model.AllActivitySyncs(db, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the latest first
+			syncs[0].Status = entry.status
+			if err = syncs[0].Save(db, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			ids[i] = syncs[0].Id
+		}
+
+		if err := ResolveActivitySync(1, 10, model.ActivitySyncUpdated, "Run", "", db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		for i, entry := range entries {
+			var sync model.ActivitySync
+			if _, err := sync.Load(ids[i], db, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if sync.Status != entry.wantStatus || sync.ProcessAt != 1700000000 {
+				t.Errorf("entry %d = %+v, want status %q", i, sync, entry.wantStatus)
+			}
+
+			if resolved := sync.ProcessedAt != 0 && sync.SportType == "Run"; resolved != (entry.status != entry.wantStatus) {
+				t.Errorf("entry %d = %+v, want resolved %v", i, sync, entry.status != entry.wantStatus)
+			}
+		}
+	})
+}
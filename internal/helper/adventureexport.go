@@ -0,0 +1,277 @@
+package helper
+
+import (
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/miki208/stravaadventuregame/internal/database"
+	"github.com/miki208/stravaadventuregame/internal/model"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// AdventureExportContentTypes maps supported export formats to their content types.
+var AdventureExportContentTypes = map[string]string{
+	"gpx":     "application/gpx+xml",
+	"kml":     "application/vnd.google-earth.kml+xml",
+	"geojson": "application/geo+json",
+}
+
+// AdventureExport is everything that gets exported for an adventure: the course split at the current location, and
+// the start, the end and the current location.
+type AdventureExport struct {
+	Name            string
+	Start           Waypoint
+	End             Waypoint
+	CurrentLocation Waypoint
+	Route           *AdventureRoute
+}
+
+// LoadAdventureExport loads locations and the course of the adventure.
+func LoadAdventureExport(adventure *model.Adventure, db *sql.DB, tx *sql.Tx, fileDb *database.FileDatabase) (*AdventureExport, error) {
+	var startLocation, endLocation model.Location
+
+	found, err := startLocation.Load(adventure.StartLocation, db, tx)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("start location %d not found", adventure.StartLocation)
+	}
+
+	found, err = endLocation.Load(adventure.EndLocation, db, tx)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("end location %d not found", adventure.EndLocation)
+	}
+
+	route, err := LoadAdventureRoute(fileDb, adventure)
+	if err != nil {
+		return nil, err
+	}
+
+	waypoints := LocationsToWaypoints([]model.Location{startLocation, endLocation})
+
+	return &AdventureExport{
+		Name:  startLocation.Name + " - " + endLocation.Name,
+		Start: waypoints[0],
+		End:   waypoints[1],
+		CurrentLocation: Waypoint{
+			Name: adventure.CurrentLocationName,
+			Lat:  adventure.CurrentLocationLat,
+			Lon:  adventure.CurrentLocationLon,
+		},
+		Route: route,
+	}, nil
+}
+
+// WriteAdventureFile writes the adventure as a GPX (track and waypoints), KML (styled completed and remaining parts)
+// or GeoJSON (FeatureCollection) file. The format is determined by the file extension.
+func WriteAdventureFile(fileName string, adventureExport *AdventureExport) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".gpx":
+		return writeGpxAdventure(adventureExport)
+	case ".kml":
+		return writeKmlAdventure(adventureExport)
+	case ".geojson", ".json":
+		return writeGeoJsonAdventure(adventureExport)
+	default:
+		return nil, errors.New("unsupported adventure file format, expected .gpx, .kml or .geojson")
+	}
+}
+
+func writeGpxAdventure(adventureExport *AdventureExport) ([]byte, error) {
+	doc := gpxDocument{
+		Version: "1.1",
+		Creator: "stravaadventuregame",
+		Xmlns:   "http://www.topografix.com/GPX/1/1",
+	}
+
+	for _, waypoint := range []Waypoint{adventureExport.Start, adventureExport.End, adventureExport.CurrentLocation} {
+		doc.Waypoints = append(doc.Waypoints, gpxPoint{Lat: waypoint.Lat, Lon: waypoint.Lon, Name: waypoint.Name})
+	}
+
+	route := adventureExport.Route
+	parts := []struct {
+		name       string
+		line       orb.LineString
+		elevations []*float64
+	}{
+		{"Completed", route.Completed, partElevations(route.Completed, route.Course, route.Elevations, false)},
+		{"Remaining", route.NotCompleted, partElevations(route.NotCompleted, route.Course, route.Elevations, true)},
+	}
+
+	for _, part := range parts {
+		if len(part.line) < 2 {
+			continue
+		}
+
+		var segment gpxTrackSegment
+		for i, point := range part.line {
+			segment.Points = append(segment.Points, gpxPoint{Lat: point.Lat(), Lon: point.Lon(), Ele: part.elevations[i]})
+		}
+
+		doc.Tracks = append(doc.Tracks, gpxTrack{Name: adventureExport.Name + " (" + part.name + ")", Segments: []gpxTrackSegment{segment}})
+	}
+
+	content, err := xml.MarshalIndent(&doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), content...), nil
+}
+
+// partElevations finds elevations of points of a part of the course. The completed part is a prefix of the course and
+// the remaining part is a suffix (fromEnd), except for the current location, which has no elevation.
+func partElevations(part orb.LineString, course orb.LineString, elevations []float64, fromEnd bool) []*float64 {
+	result := make([]*float64, len(part))
+	if elevations == nil {
+		return result
+	}
+
+	offset := 0
+	if fromEnd {
+		offset = len(course) - len(part)
+	}
+
+	for i, point := range part {
+		if courseIndex := offset + i; courseIndex >= 0 && courseIndex < len(course) && course[courseIndex] == point {
+			result[i] = &elevations[courseIndex]
+		}
+	}
+
+	return result
+}
+
+type kmlDocument struct {
+	XMLName  xml.Name     `xml:"kml"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Document kmlContainer `xml:"Document"`
+}
+
+type kmlContainer struct {
+	Name       string         `xml:"name"`
+	Styles     []kmlStyle     `xml:"Style"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlStyle struct {
+	Id        string       `xml:"id,attr"`
+	LineStyle kmlLineStyle `xml:"LineStyle"`
+}
+
+type kmlLineStyle struct {
+	Color string `xml:"color"` // aabbggrr
+	Width int    `xml:"width"`
+}
+
+type kmlPlacemark struct {
+	Name       string         `xml:"name"`
+	StyleUrl   string         `xml:"styleUrl,omitempty"`
+	Point      *kmlPoint      `xml:"Point,omitempty"`
+	LineString *kmlLineString `xml:"LineString,omitempty"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlLineString struct {
+	Tessellate  int    `xml:"tessellate"`
+	Coordinates string `xml:"coordinates"`
+}
+
+func kmlCoordinates(points ...orb.Point) string {
+	coordinates := make([]string, 0, len(points))
+	for _, point := range points {
+		coordinates = append(coordinates, strconv.FormatFloat(point.Lon(), 'f', -1, 64)+","+strconv.FormatFloat(point.Lat(), 'f', -1, 64))
+	}
+
+	return strings.Join(coordinates, " ")
+}
+
+func writeKmlAdventure(adventureExport *AdventureExport) ([]byte, error) {
+	doc := kmlDocument{
+		Xmlns: "http://www.opengis.net/kml/2.2",
+		Document: kmlContainer{
+			Name: adventureExport.Name,
+			Styles: []kmlStyle{
+				{Id: "completed", LineStyle: kmlLineStyle{Color: "ff008000", Width: 4}},
+				{Id: "remaining", LineStyle: kmlLineStyle{Color: "ff0000ff", Width: 4}},
+			},
+		},
+	}
+
+	route := adventureExport.Route
+	if len(route.Completed) > 1 {
+		doc.Document.Placemarks = append(doc.Document.Placemarks, kmlPlacemark{
+			Name:       "Completed",
+			StyleUrl:   "#completed",
+			LineString: &kmlLineString{Tessellate: 1, Coordinates: kmlCoordinates(route.Completed...)},
+		})
+	}
+
+	if len(route.NotCompleted) > 1 {
+		doc.Document.Placemarks = append(doc.Document.Placemarks, kmlPlacemark{
+			Name:       "Remaining",
+			StyleUrl:   "#remaining",
+			LineString: &kmlLineString{Tessellate: 1, Coordinates: kmlCoordinates(route.NotCompleted...)},
+		})
+	}
+
+	for _, waypoint := range []Waypoint{adventureExport.Start, adventureExport.End, adventureExport.CurrentLocation} {
+		doc.Document.Placemarks = append(doc.Document.Placemarks, kmlPlacemark{
+			Name:  waypoint.Name,
+			Point: &kmlPoint{Coordinates: kmlCoordinates(orb.Point{waypoint.Lon, waypoint.Lat})},
+		})
+	}
+
+	content, err := xml.MarshalIndent(&doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), content...), nil
+}
+
+func writeGeoJsonAdventure(adventureExport *AdventureExport) ([]byte, error) {
+	fc := geojson.NewFeatureCollection()
+
+	route := adventureExport.Route
+	for _, part := range []struct {
+		kind string
+		line orb.LineString
+	}{{"completed", route.Completed}, {"remaining", route.NotCompleted}} {
+		if len(part.line) < 2 {
+			continue
+		}
+
+		feature := geojson.NewFeature(part.line)
+		feature.Properties["name"] = adventureExport.Name
+		feature.Properties["kind"] = part.kind
+
+		fc.Append(feature)
+	}
+
+	for _, point := range []struct {
+		kind     string
+		waypoint Waypoint
+	}{{"start", adventureExport.Start}, {"end", adventureExport.End}, {"current", adventureExport.CurrentLocation}} {
+		feature := geojson.NewFeature(orb.Point{point.waypoint.Lon, point.waypoint.Lat})
+		feature.Properties["name"] = point.waypoint.Name
+		feature.Properties["kind"] = point.kind
+
+		fc.Append(feature)
+	}
+
+	return json.MarshalIndent(fc, "", "  ")
+}
@@ -0,0 +1,193 @@
+package helper
+
+import (
+	"encoding/xml"
+	"slices"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+func testAdventureExport(completedPoints int, withElevation bool) *AdventureExport {
+	course := orb.LineString{{20.46, 44.82}, {20.2, 45.0}, {20.0, 45.1}, {19.84, 45.25}}
+	current := orb.Point{20.1, 45.05}
+
+	route := &AdventureRoute{Course: course}
+	if withElevation {
+		route.Elevations = []float64{117, 80, 75.5, 80}
+	}
+
+	switch completedPoints {
+	case 0: // not started yet
+		route.Completed = orb.LineString{course[0]}
+		route.NotCompleted = course
+		current = course[0]
+	case len(course): // completed
+		route.Completed = course
+		route.NotCompleted = orb.LineString{course[len(course)-1]}
+		current = course[len(course)-1]
+	default:
+		route.Completed = append(slices.Clone(course[:completedPoints]), current)
+		route.NotCompleted = append(orb.LineString{current}, course[completedPoints:]...)
+	}
+
+	return &AdventureExport{
+		Name:            "Belgrade - Novi Sad",
+		Start:           Waypoint{Name: "Belgrade", Lat: 44.82, Lon: 20.46},
+		End:             Waypoint{Name: "Novi Sad", Lat: 45.25, Lon: 19.84},
+		CurrentLocation: Waypoint{Name: "Stara Pazova", Lat: current.Lat(), Lon: current.Lon()},
+		Route:           route,
+	}
+}
+
+func TestWriteAdventureFileGpx(t *testing.T) {
+	tests := []struct {
+		completedPoints int
+		withElevation   bool
+		wantTracks      []string
+		wantElevations  [][]bool // whether each point of each track has an elevation
+	}{
+		{2, true, []string{"Completed", "Remaining"}, [][]bool{{true, true, false}, {false, true, true}}},
+		{2, false, []string{"Completed", "Remaining"}, [][]bool{{false, false, false}, {false, false, false}}},
+		{0, true, []string{"Remaining"}, [][]bool{{true, true, true, true}}},
+		{4, true, []string{"Completed"}, [][]bool{{true, true, true, true}}},
+	}
+
+	for _, test := range tests {
+		content, err := WriteAdventureFile("adventure.gpx", testAdventureExport(test.completedPoints, test.withElevation))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var doc gpxDocument
+		if err = xml.Unmarshal(content, &doc); err != nil {
+			t.Fatalf("the written GPX can't be parsed: %v", err)
+		}
+
+		var names []string
+		for _, waypoint := range doc.Waypoints {
+			names = append(names, waypoint.Name)
+		}
+
+		if want := []string{"Belgrade", "Novi Sad", "Stara Pazova"}; !slices.Equal(names, want) {
+			t.Errorf("%d completed points: waypoints %v, want %v", test.completedPoints, names, want)
+		}
+
+		if len(doc.Tracks) != len(test.wantTracks) {
+			t.Errorf("%d completed points: %d tracks, want %v", test.completedPoints, len(doc.Tracks), test.wantTracks)
+
+			continue
+		}
+
+		for i, track := range doc.Tracks {
+			if track.Name != "Belgrade - Novi Sad ("+test.wantTracks[i]+")" {
+				t.Errorf("%d completed points: track %q, want the %s part", test.completedPoints, track.Name, test.wantTracks[i])
+			}
+
+			var elevations []bool
+			for _, point := range track.Segments[0].Points {
+				elevations = append(elevations, point.Ele != nil)
+			}
+
+			if !slices.Equal(elevations, test.wantElevations[i]) {
+				t.Errorf("%d completed points, elevation %v: points of %s with elevation %v, want %v", test.completedPoints,
+					test.withElevation, test.wantTracks[i], elevations, test.wantElevations[i])
+			}
+		}
+	}
+
+	// the exported course is read back as the whole course
+	content, err := WriteAdventureFile("adventure.gpx", testAdventureExport(2, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	route, _, err := ParseRouteFile("adventure.gpx", content)
+	if err != nil || len(route) != 6 {
+		t.Errorf("ParseRouteFile() of the export = %v, %v, want 6 points", route, err)
+	}
+}
+
+func TestWriteAdventureFileKml(t *testing.T) {
+	tests := []struct {
+		completedPoints int
+		wantPlacemarks  []string
+	}{
+		{2, []string{"Completed", "Remaining", "Belgrade", "Novi Sad", "Stara Pazova"}},
+		{0, []string{"Remaining", "Belgrade", "Novi Sad", "Stara Pazova"}},
+		{4, []string{"Completed", "Belgrade", "Novi Sad", "Stara Pazova"}},
+	}
+
+	for _, test := range tests {
+		content, err := WriteAdventureFile("adventure.KML", testAdventureExport(test.completedPoints, true))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var doc kmlDocument
+		if err = xml.Unmarshal(content, &doc); err != nil {
+			t.Fatalf("the written KML can't be parsed: %v", err)
+		}
+
+		var names []string
+		for _, placemark := range doc.Document.Placemarks {
+			names = append(names, placemark.Name)
+		}
+
+		if !slices.Equal(names, test.wantPlacemarks) {
+			t.Errorf("%d completed points: placemarks %v, want %v", test.completedPoints, names, test.wantPlacemarks)
+		}
+	}
+
+	content, err := WriteAdventureFile("adventure.kml", testAdventureExport(2, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc kmlDocument
+	if err = xml.Unmarshal(content, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	// lon,lat pairs
+	if got, want := doc.Document.Placemarks[0].LineString.Coordinates, "20.46,44.82 20.2,45 20.1,45.05"; got != want {
+		t.Errorf("coordinates of the completed part = %q, want %q", got, want)
+	}
+}
+
+func TestWriteAdventureFileGeoJson(t *testing.T) {
+	tests := []struct {
+		completedPoints int
+		wantKinds       []string
+	}{
+		{2, []string{"completed", "remaining", "start", "end", "current"}},
+		{0, []string{"remaining", "start", "end", "current"}},
+		{4, []string{"completed", "start", "end", "current"}},
+	}
+
+	for _, test := range tests {
+		content, err := WriteAdventureFile("adventure.geojson", testAdventureExport(test.completedPoints, true))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fc, err := geojson.UnmarshalFeatureCollection(content)
+		if err != nil {
+			t.Fatalf("the written GeoJSON can't be parsed: %v", err)
+		}
+
+		var kinds []string
+		for _, feature := range fc.Features {
+			kinds = append(kinds, feature.Properties.MustString("kind", ""))
+		}
+
+		if !slices.Equal(kinds, test.wantKinds) {
+			t.Errorf("%d completed points: features %v, want %v", test.completedPoints, kinds, test.wantKinds)
+		}
+	}
+
+	if _, err := WriteAdventureFile("adventure.kmz", testAdventureExport(2, true)); err == nil {
+		t.Error("WriteAdventureFile() of an unsupported format succeeded, want an error")
+	}
+}
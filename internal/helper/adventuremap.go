@@ -0,0 +1,74 @@
+package helper
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+// AdventureMapImageOf returns the map image record of the adventure, creating it (with a new random image id) if the
+// adventure doesn't have one yet. The image itself is rendered lazily, when it's requested.
+func AdventureMapImageOf(adventure *model.Adventure, db *sql.DB, tx *sql.Tx) (*model.AdventureMapImage, error) {
+	var mapImage model.AdventureMapImage
+
+	found, err := mapImage.Load(adventure.AthleteId, adventure.StartLocation, adventure.EndLocation, db, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if found {
+		return &mapImage, nil
+	}
+
+	mapImage = model.AdventureMapImage{
+		AthleteId:     adventure.AthleteId,
+		StartLocation: adventure.StartLocation,
+		EndLocation:   adventure.EndLocation,
+		ImageId:       uuid.New().String(),
+	}
+
+	if err = mapImage.Save(db, tx); err != nil {
+		return nil, err
+	}
+
+	return &mapImage, nil
+}
+
+// RotateAdventureMapImage gives the map image of the adventure a new random image id, so that the image isn't served
+// under the old one anymore (e.g. when the public link of the adventure is revoked), and removes the image rendered under
+// the old id from the directory. It does nothing if the adventure has no map image yet.
+func RotateAdventureMapImage(adventure *model.Adventure, directory string, db *sql.DB, tx *sql.Tx) error {
+	var mapImage model.AdventureMapImage
+
+	found, err := mapImage.Load(adventure.AthleteId, adventure.StartLocation, adventure.EndLocation, db, tx)
+	if err != nil || !found {
+		return err
+	}
+
+	oldImageId := mapImage.ImageId
+
+	mapImage.ImageId = uuid.New().String()
+	mapImage.Progress = ""
+	mapImage.RenderedAt = 0
+
+	if err = mapImage.Save(db, tx); err != nil {
+		return err
+	}
+
+	// images rendered under the old id by concurrent requests are left behind, but they aren't served anymore
+	if err = os.Remove(directory + oldImageId + ".png"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// AdventureProgress describes everything that's visible on the map of the adventure, the map has to be rendered again
+// when it changes.
+func AdventureProgress(adventure *model.Adventure) string {
+	return fmt.Sprintf("%d|%d|%.6f|%.6f", adventure.Completed, adventure.CurrentLocationIndexOnRoute, adventure.CurrentLocationLat, adventure.CurrentLocationLon)
+}
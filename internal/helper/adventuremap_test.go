@@ -0,0 +1,81 @@
+package helper
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/miki208/stravaadventuregame/internal/database/databasetest"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+func TestRotateAdventureMapImage(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		directory := t.TempDir() + "/"
+
+		athlete := model.Athlete{Id: 1, FirstName: "Jane"}
+		if err := athlete.Save(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		start, end := model.Location{Name: "Belgrade"}, model.Location{Name: "Novi Sad"}
+		for _, location := range []*model.Location{&start, &end} {
+			if err := location.Save(db, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		adventure := model.Adventure{AthleteId: 1, StartLocation: start.Id, EndLocation: end.Id, TotalDistance: 94}
+		if err := adventure.Insert(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		// nothing to rotate yet
+		if err := RotateAdventureMapImage(&adventure, directory, db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		oldImage, err := AdventureMapImageOf(&adventure, db, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		oldImagePath := filepath.Join(directory, oldImage.ImageId+".png")
+		if err = os.WriteFile(oldImagePath, []byte("png"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = RotateAdventureMapImage(&adventure, directory, db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = os.Stat(oldImagePath); !os.IsNotExist(err) {
+			t.Errorf("the image rendered under the old id still exists (%v)", err)
+		}
+
+		newImage, err := AdventureMapImageOf(&adventure, db, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if newImage.ImageId == oldImage.ImageId {
+			t.Error("the image id wasn't rotated")
+		}
+
+		if images, err := model.AllAdventureMapImages(db, nil, map[string]any{"image_id": oldImage.ImageId}); err != nil || len(images) != 0 {
+			t.Errorf("images with the old id = %v, %v, want none", images, err)
+		}
+
+		// a rendering of the old image finished after the rotation
+		oldImage.Progress, oldImage.RenderedAt = "0|5|44.8|20.4", 100
+		if saved, err := oldImage.SaveRendering(db, nil); err != nil || saved {
+			t.Errorf("SaveRendering() of the old image = %v, %v, want false", saved, err)
+		}
+
+		newImage.Progress, newImage.RenderedAt = "0|5|44.8|20.4", 100
+		if saved, err := newImage.SaveRendering(db, nil); err != nil || !saved {
+			t.Errorf("SaveRendering() of the new image = %v, %v, want true", saved, err)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package helper
+
+import (
+	"github.com/miki208/stravaadventuregame/internal/database"
+	"github.com/miki208/stravaadventuregame/internal/model"
+	"github.com/paulmach/orb"
+)
+
+// AdventureRoute is the course of an adventure, split at the current location of the athlete.
+type AdventureRoute struct {
+	Course       orb.LineString
+	Elevations   []float64 // nil if the course has no elevation
+	Completed    orb.LineString
+	NotCompleted orb.LineString
+}
+
+// LoadAdventureRoute reads the course of the adventure from the file database (in the direction of the adventure)
+// and splits it into the completed and the not completed part.
+func LoadAdventureRoute(fileDb *database.FileDatabase, adventure *model.Adventure) (*AdventureRoute, error) {
+	route := model.NewDirectionsRoute()
+	if err := fileDb.Read("course", model.CourseDbName(adventure.StartLocation, adventure.EndLocation), route); err != nil {
+		return nil, err
+	}
+
+	course, elevations, err := DecodePolyline(route.Geometry, route.Elevation, adventure.StartLocation > adventure.EndLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	completed, notCompleted := SplitRouteAtCurrentLocation(course, adventure)
+
+	return &AdventureRoute{
+		Course:       course,
+		Elevations:   elevations,
+		Completed:    completed,
+		NotCompleted: notCompleted,
+	}, nil
+}
+
+// SplitRouteAtCurrentLocation splits the course into the completed and the not completed part. Both parts contain
+// the current location of the athlete, unless the adventure is completed, in which case the whole course is completed.
+func SplitRouteAtCurrentLocation(course orb.LineString, adventure *model.Adventure) (orb.LineString, orb.LineString) {
+	var completed, notCompleted orb.LineString
+
+	if adventure.CurrentLocationIndexOnRoute == -1 {
+		return course, nil
+	}
+
+	for i := 0; i < len(course); i++ {
+		if i < adventure.CurrentLocationIndexOnRoute {
+			completed = append(completed, orb.Point{course[i].Lon(), course[i].Lat()})
+		} else if i > adventure.CurrentLocationIndexOnRoute {
+			notCompleted = append(notCompleted, orb.Point{course[i].Lon(), course[i].Lat()})
+		}
+
+		if i == adventure.CurrentLocationIndexOnRoute {
+			completed = append(completed, orb.Point{course[i].Lon(), course[i].Lat()})
+
+			if course[i].Lat() != adventure.CurrentLocationLat || course[i].Lon() != adventure.CurrentLocationLon {
+				completed = append(completed, orb.Point{adventure.CurrentLocationLon, adventure.CurrentLocationLat})
+				notCompleted = append(notCompleted, orb.Point{adventure.CurrentLocationLon, adventure.CurrentLocationLat})
+			} else {
+				notCompleted = append(notCompleted, orb.Point{course[i].Lon(), course[i].Lat()})
+			}
+		}
+	}
+
+	return completed, notCompleted
+}
@@ -0,0 +1,125 @@
+package helper
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+const (
+	ApiTokenScopeRead  = "read"  // GET requests
+	ApiTokenScopeWrite = "write" // all other requests
+)
+
+var ApiTokenScopes = []string{ApiTokenScopeRead, ApiTokenScopeWrite}
+
+// prefix of every token, so they are easy to recognize (e.g. by secret scanners)
+const apiTokenPrefix = "sag_"
+
+const maxApiTokenNameLength = 100
+
+// lastUsedAt is updated at most once per this interval, to avoid a write on every request
+const apiTokenLastUsedResolution = time.Minute
+
+func HashApiToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// CreateApiToken issues a new token for the athlete. The token itself is returned only here, just its hash is saved.
+// validFor of 0 means that the token never expires.
+func CreateApiToken(athleteId int64, name string, scopes []string, validFor time.Duration, db *sql.DB, tx *sql.Tx) (string, *model.ApiToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, errors.New("token name is required")
+	}
+
+	if len(name) > maxApiTokenNameLength {
+		return "", nil, fmt.Errorf("token name can't be longer than %d characters", maxApiTokenNameLength)
+	}
+
+	if len(scopes) == 0 {
+		return "", nil, errors.New("at least one scope is required")
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(ApiTokenScopes, scope) {
+			return "", nil, fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+
+	now := time.Now()
+
+	token := apiTokenPrefix + rand.Text()
+	apiToken := model.ApiToken{
+		AthleteId: athleteId,
+		Name:      name,
+		TokenHash: HashApiToken(token),
+		Scopes:    strings.Join(slices.Compact(slices.Sorted(slices.Values(scopes))), ","),
+		CreatedAt: int(now.Unix()),
+	}
+
+	if validFor > 0 {
+		apiToken.ExpiresAt = int(now.Add(validFor).Unix())
+	}
+
+	if err := apiToken.Save(db, tx); err != nil {
+		return "", nil, err
+	}
+
+	return token, &apiToken, nil
+}
+
+// FindApiToken returns the stored token matching the given one, or nil if there is no such token or it has expired.
+// Its last used timestamp is updated.
+func FindApiToken(token string, db *sql.DB, tx *sql.Tx) (*model.ApiToken, error) {
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return nil, nil
+	}
+
+	apiTokens, err := model.AllApiTokens(db, tx, map[string]any{"token_hash": HashApiToken(token)})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(apiTokens) == 0 {
+		return nil, nil
+	}
+
+	apiToken := &apiTokens[0]
+
+	now := time.Now()
+	if IsApiTokenExpired(apiToken, now) {
+		return nil, nil
+	}
+
+	if now.Sub(time.Unix(int64(apiToken.LastUsedAt), 0)) >= apiTokenLastUsedResolution {
+		apiToken.LastUsedAt = int(now.Unix())
+
+		// a token revoked meanwhile is not found
+		if err = apiToken.Save(db, tx); errors.Is(err, model.ErrRowNotFound) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	return apiToken, nil
+}
+
+func IsApiTokenExpired(apiToken *model.ApiToken, now time.Time) bool {
+	return apiToken.ExpiresAt != 0 && now.Unix() >= int64(apiToken.ExpiresAt)
+}
+
+func ApiTokenHasScope(apiToken *model.ApiToken, scope string) bool {
+	return slices.Contains(strings.Split(apiToken.Scopes, ","), scope)
+}
@@ -0,0 +1,181 @@
+package helper
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/database/databasetest"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+func TestHashApiToken(t *testing.T) {
+	// sha256 in hex
+	if got, want := HashApiToken("sag_abc"), "d417cbc7de07d3836f5a7e9b50c29f340472a0a142f70c79f7866bc0d969daf1"; got != want {
+		t.Errorf("HashApiToken() = %q, want %q", got, want)
+	}
+
+	if HashApiToken("sag_abc") == HashApiToken("sag_abd") {
+		t.Error("different tokens have the same hash")
+	}
+}
+
+func TestApiTokenHasScope(t *testing.T) {
+	tests := []struct {
+		scopes string
+		scope  string
+		want   bool
+	}{
+		{"read", ApiTokenScopeRead, true},
+		{"read", ApiTokenScopeWrite, false},
+		{"read,write", ApiTokenScopeWrite, true},
+		{"write", ApiTokenScopeRead, false}, // write doesn't imply read
+		{"readwrite", ApiTokenScopeRead, false},
+		{"", ApiTokenScopeRead, false},
+	}
+
+	for _, test := range tests {
+		if got := ApiTokenHasScope(&model.ApiToken{Scopes: test.scopes}, test.scope); got != test.want {
+			t.Errorf("ApiTokenHasScope(%q, %q) = %v, want %v", test.scopes, test.scope, got, test.want)
+		}
+	}
+}
+
+func TestCreateApiTokenValidation(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		athlete := model.Athlete{Id: 1, FirstName: "Jane"}
+		if err := athlete.Save(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name       string
+			scopes     []string
+			wantScopes string // empty if the token isn't created
+		}{
+			{"script", []string{ApiTokenScopeRead}, "read"},
+			{" widget ", []string{ApiTokenScopeWrite, ApiTokenScopeRead, ApiTokenScopeWrite}, "read,write"},
+			{"  ", []string{ApiTokenScopeRead}, ""},
+			{strings.Repeat("a", maxApiTokenNameLength+1), []string{ApiTokenScopeRead}, ""},
+			{"script", nil, ""},
+			{"script", []string{"admin"}, ""},
+		}
+
+		for _, test := range tests {
+			token, apiToken, err := CreateApiToken(1, test.name, test.scopes, 0, db, nil)
+			if test.wantScopes == "" {
+				if err == nil {
+					t.Errorf("CreateApiToken(%q, %v) succeeded, want an error", test.name, test.scopes)
+				}
+
+				continue
+			}
+
+			if err != nil {
+				t.Errorf("CreateApiToken(%q, %v) failed: %v", test.name, test.scopes, err)
+
+				continue
+			}
+
+			if apiToken.Scopes != test.wantScopes || apiToken.Name != strings.TrimSpace(test.name) {
+				t.Errorf("CreateApiToken(%q, %v) = %+v, want scopes %q", test.name, test.scopes, apiToken, test.wantScopes)
+			}
+
+			// only the hash is stored
+			if !strings.HasPrefix(token, apiTokenPrefix) || apiToken.TokenHash != HashApiToken(token) || strings.Contains(apiToken.TokenHash, token) {
+				t.Errorf("token %q stored as %q", token, apiToken.TokenHash)
+			}
+		}
+	})
+}
+
+func TestFindApiToken(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		athlete := model.Athlete{Id: 1, FirstName: "Jane"}
+		if err := athlete.Save(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		token, created, err := CreateApiToken(1, "script", []string{ApiTokenScopeRead}, time.Hour, db, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expiredToken, expired, err := CreateApiToken(1, "old script", []string{ApiTokenScopeRead}, time.Hour, db, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expired.ExpiresAt = int(time.Now().Add(-time.Second).Unix())
+		if err = expired.Save(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		revokedToken, revoked, err := CreateApiToken(1, "revoked", []string{ApiTokenScopeRead}, 0, db, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = revoked.Delete(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			token  string
+			wantId int // 0 if not found
+		}{
+			{token, created.Id},
+			{expiredToken, 0},
+			{revokedToken, 0},
+			{token + "x", 0},
+			{strings.TrimPrefix(token, apiTokenPrefix), 0},
+			{"", 0},
+		}
+
+		for _, test := range tests {
+			apiToken, err := FindApiToken(test.token, db, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotId := 0
+			if apiToken != nil {
+				gotId = apiToken.Id
+			}
+
+			if gotId != test.wantId {
+				t.Errorf("FindApiToken(%q) found token %d, want %d", test.token, gotId, test.wantId)
+			}
+		}
+
+		var used model.ApiToken
+		if _, err = used.Load(created.Id, db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if used.LastUsedAt == 0 {
+			t.Error("the last use of a found token wasn't recorded")
+		}
+	})
+}
+
+func TestIsApiTokenExpired(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	tests := []struct {
+		expiresAt int
+		want      bool
+	}{
+		{0, false},
+		{999, true},
+		{1000, true},
+		{1001, false},
+	}
+
+	for _, test := range tests {
+		if got := IsApiTokenExpired(&model.ApiToken{ExpiresAt: test.expiresAt}, now); got != test.want {
+			t.Errorf("IsApiTokenExpired(%d at 1000) = %v, want %v", test.expiresAt, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package helper
+
+import (
+	"slices"
+
+	"github.com/miki208/stravaadventuregame/internal/database"
+	"github.com/miki208/stravaadventuregame/internal/model"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+)
+
+// LoadCoursePlaces returns places along the adventure course, ordered from the start of the adventure.
+// Returns false if places for this course are not known (yet).
+func LoadCoursePlaces(fileDb *database.FileDatabase, adventure *model.Adventure) ([]model.CoursePlace, bool, error) {
+	courseDbName := model.CourseDbName(adventure.StartLocation, adventure.EndLocation)
+
+	exists, err := fileDb.Exists("courseplaces", courseDbName)
+	if err != nil || !exists {
+		return nil, false, err
+	}
+
+	var coursePlaces model.CoursePlaces
+	if err = fileDb.Read("courseplaces", courseDbName, &coursePlaces); err != nil {
+		return nil, false, err
+	}
+
+	return coursePlaces.InDirection(adventure.StartLocation > adventure.EndLocation), true, nil
+}
+
+// NextCoursePlace returns the first place after the given distance (in km), and how far it is.
+func NextCoursePlace(places []model.CoursePlace, currentKm float64) (model.CoursePlace, float64, bool) {
+	for _, place := range places {
+		if place.Km > currentKm {
+			return place, place.Km - currentKm, true
+		}
+	}
+
+	return model.CoursePlace{}, 0, false
+}
+
+// SnapWaypointsToCourse turns waypoints which are at most maxDistanceKm away from the course into course places.
+// Every waypoint gets the kilometre mark of the nearest point of the course.
+func SnapWaypointsToCourse(course orb.LineString, waypoints []Waypoint, maxDistanceKm float64) model.CoursePlaces {
+	cumulativeKm := make([]float64, len(course))
+	for i := 1; i < len(course); i++ {
+		cumulativeKm[i] = cumulativeKm[i-1] + geo.DistanceHaversine(course[i-1], course[i])/1000
+	}
+
+	coursePlaces := model.CoursePlaces{
+		Source:         "import",
+		CourseLengthKm: cumulativeKm[len(cumulativeKm)-1],
+	}
+
+	for _, waypoint := range waypoints {
+		point := orb.Point{waypoint.Lon, waypoint.Lat}
+
+		nearestIndex, nearestDistance := 0, geo.DistanceHaversine(point, course[0])
+		for i := 1; i < len(course); i++ {
+			if distance := geo.DistanceHaversine(point, course[i]); distance < nearestDistance {
+				nearestIndex, nearestDistance = i, distance
+			}
+		}
+
+		if nearestDistance > maxDistanceKm*1000 {
+			continue
+		}
+
+		coursePlaces.Places = append(coursePlaces.Places, model.CoursePlace{
+			Name: waypoint.Name,
+			Lat:  waypoint.Lat,
+			Lon:  waypoint.Lon,
+			Km:   cumulativeKm[nearestIndex],
+		})
+	}
+
+	slices.SortStableFunc(coursePlaces.Places, func(a, b model.CoursePlace) int {
+		if a.Km < b.Km {
+			return -1
+		} else if a.Km > b.Km {
+			return 1
+		}
+
+		return 0
+	})
+
+	return coursePlaces
+}
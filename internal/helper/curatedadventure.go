@@ -0,0 +1,76 @@
+package helper
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+var CuratedAdventureDifficulties = []string{"easy", "moderate", "hard", "epic"}
+
+var CuratedAdventureModes = []string{"run", "ride", "hike", "any"}
+
+// ValidateCuratedAdventure trims and normalizes fields of the curated adventure (tags become a lowercase, comma separated
+// list without duplicates), and checks that it's complete and that it goes between two different public locations.
+func ValidateCuratedAdventure(curated *model.CuratedAdventure, db *sql.DB, tx *sql.Tx) error {
+	curated.Title = strings.TrimSpace(curated.Title)
+	curated.Description = strings.TrimSpace(curated.Description)
+	curated.CoverImageUrl = strings.TrimSpace(curated.CoverImageUrl)
+	curated.Tags = strings.Join(SplitTags(curated.Tags), ",")
+
+	if curated.Title == "" {
+		return errors.New("title is required")
+	}
+
+	if !slices.Contains(CuratedAdventureDifficulties, curated.Difficulty) {
+		return fmt.Errorf("difficulty has to be one of: %s", strings.Join(CuratedAdventureDifficulties, ", "))
+	}
+
+	if !slices.Contains(CuratedAdventureModes, curated.Mode) {
+		return fmt.Errorf("mode has to be one of: %s", strings.Join(CuratedAdventureModes, ", "))
+	}
+
+	if curated.Distance <= 0 {
+		return errors.New("distance has to be positive")
+	}
+
+	if curated.CoverImageUrl != "" && !isHttpUrl(curated.CoverImageUrl) {
+		return errors.New("cover image url has to be an absolute http or https url")
+	}
+
+	if curated.StartLocation == curated.EndLocation {
+		return errors.New("start and end location can't be the same")
+	}
+
+	for _, locationId := range []int{curated.StartLocation, curated.EndLocation} {
+		var location model.Location
+
+		found, err := location.Load(locationId, db, tx)
+		if err != nil {
+			return err
+		}
+
+		if !found || location.OwnerId != 0 {
+			return errors.New("start and end have to be public locations")
+		}
+	}
+
+	return nil
+}
+
+// SplitTags splits comma separated tags, dropping empty ones and duplicates.
+func SplitTags(tags string) []string {
+	var result []string
+	for _, tag := range strings.Split(tags, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,166 @@
+package helper
+
+import (
+	"sync"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+const (
+	LiveEventActivity  = "activity"  // a state change of an activity on its way into the adventure
+	LiveEventAdventure = "adventure" // the new position of the ongoing adventure
+)
+
+const (
+	ActivityStatePending    = "pending"    // received from Strava, waiting to be processed
+	ActivityStateProcessing = "processing" // being fetched from Strava and applied
+	ActivityStateApplied    = "applied"    // processed, see the result
+	ActivityStateDropped    = "dropped"    // removed from the queue before it was processed (e.g. deleted on Strava)
+)
+
+// ActivityStateUpdate tells the athlete where their activity is between Strava and the adventure.
+type ActivityStateUpdate struct {
+	ActivityId int64  `json:"activity_id"`
+	AspectType string `json:"aspect_type"`
+	State      string `json:"state"`
+	Result     string `json:"result,omitempty"`     // for the applied state: created, updated, deleted or ignored
+	ProcessAt  string `json:"process_at,omitempty"` // for the pending state: when the activity gets processed at the earliest
+	Error      string `json:"error,omitempty"`      // for the applied state, if the activity couldn't be processed
+}
+
+// AdventurePositionUpdate is the new position of the ongoing adventure after an activity is applied.
+type AdventurePositionUpdate struct {
+	StartLocation       int     `json:"start_location"`
+	EndLocation         int     `json:"end_location"`
+	CurrentLocationName string  `json:"current_location_name"`
+	CurrentLocationLat  float64 `json:"current_location_lat"`
+	CurrentLocationLon  float64 `json:"current_location_lon"`
+	CurrentDistanceKm   float32 `json:"current_distance_km"`
+	TotalDistanceKm     float32 `json:"total_distance_km"`
+	Completed           bool    `json:"completed"`
+}
+
+func NewAdventurePositionUpdate(adventure *model.Adventure) AdventurePositionUpdate {
+	return AdventurePositionUpdate{
+		StartLocation:       adventure.StartLocation,
+		EndLocation:         adventure.EndLocation,
+		CurrentLocationName: adventure.CurrentLocationName,
+		CurrentLocationLat:  adventure.CurrentLocationLat,
+		CurrentLocationLon:  adventure.CurrentLocationLon,
+		CurrentDistanceKm:   adventure.CurrentDistance,
+		TotalDistanceKm:     adventure.TotalDistance,
+		Completed:           adventure.Completed == 1,
+	}
+}
+
+// LiveEvent is published to the athlete's subscribers, e.g. to update the welcome page without a reload.
+type LiveEvent struct {
+	Type      string                   `json:"type"`
+	Activity  *ActivityStateUpdate     `json:"activity,omitempty"`
+	Adventure *AdventurePositionUpdate `json:"adventure,omitempty"`
+}
+
+// events are dropped for subscribers that are this much behind
+const liveEventBufferSize = 16
+
+// EventBus delivers live events of an athlete to all their subscribers (e.g. open browser tabs) in this process.
+// Publishing never blocks, slow subscribers miss events instead. Events are not shared between processes: with several
+// instances of the application, subscribers of one instance don't get the events published by another one (e.g. by the
+// instance running the job which applies activities).
+type EventBus struct {
+	mutex       sync.Mutex
+	subscribers map[int64]map[chan LiveEvent]struct{}
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[int64]map[chan LiveEvent]struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Close tells the subscribers to stop listening, e.g. when the server is shutting down.
+func (bus *EventBus) Close() {
+	bus.closeOnce.Do(func() {
+		close(bus.done)
+	})
+}
+
+// Done is closed when the bus is closed.
+func (bus *EventBus) Done() <-chan struct{} {
+	return bus.done
+}
+
+// Subscribe returns a channel with live events of the athlete, and a function that has to be called to unsubscribe.
+func (bus *EventBus) Subscribe(athleteId int64) (<-chan LiveEvent, func()) {
+	events := make(chan LiveEvent, liveEventBufferSize)
+
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	if bus.subscribers[athleteId] == nil {
+		bus.subscribers[athleteId] = make(map[chan LiveEvent]struct{})
+	}
+
+	bus.subscribers[athleteId][events] = struct{}{}
+
+	return events, func() {
+		bus.mutex.Lock()
+		defer bus.mutex.Unlock()
+
+		delete(bus.subscribers[athleteId], events)
+		if len(bus.subscribers[athleteId]) == 0 {
+			delete(bus.subscribers, athleteId)
+		}
+	}
+}
+
+func (bus *EventBus) Publish(athleteId int64, event LiveEvent) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	for events := range bus.subscribers[athleteId] {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+func (bus *EventBus) PublishActivityState(athleteId int64, update ActivityStateUpdate) {
+	bus.Publish(athleteId, LiveEvent{Type: LiveEventActivity, Activity: &update})
+}
+
+func (bus *EventBus) PublishAdventurePosition(athleteId int64, update AdventurePositionUpdate) {
+	bus.Publish(athleteId, LiveEvent{Type: LiveEventAdventure, Adventure: &update})
+}
+
+func FormatProcessAt(eventTime int64, processAfterSec int) string {
+	return time.Unix(eventTime+int64(processAfterSec), 0).UTC().Format(time.RFC3339)
+}
+
+// QueuedActivityState is the state of the activity of a webhook event left in the queue. Failed events are
+// processed at their next attempt at the earliest, events which are given up on aren't processed until requeued.
+func QueuedActivityState(ev *model.StravaWebhookEvent, processAfterSec int) ActivityStateUpdate {
+	stateUpdate := ActivityStateUpdate{
+		ActivityId: ev.ObjectId,
+		AspectType: ev.AspectType,
+		State:      ActivityStatePending,
+	}
+
+	switch {
+	case ev.Dead == 1:
+		stateUpdate.Error = "processing failed too many times"
+	case ev.Attempts > 0:
+		stateUpdate.Error = "processing failed, it will be retried"
+		stateUpdate.ProcessAt = FormatProcessAt(max(ev.EventTime, int64(ev.NextAttemptAt)-int64(processAfterSec)), processAfterSec)
+	default:
+		stateUpdate.ProcessAt = FormatProcessAt(ev.EventTime, processAfterSec)
+	}
+
+	return stateUpdate
+}
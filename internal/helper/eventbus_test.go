@@ -0,0 +1,86 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus()
+
+	events, unsubscribe := bus.Subscribe(1)
+	otherEvents, unsubscribeOther := bus.Subscribe(2)
+	defer unsubscribeOther()
+
+	bus.PublishActivityState(1, ActivityStateUpdate{ActivityId: 10, State: ActivityStatePending})
+
+	select {
+	case event := <-events:
+		if event.Type != LiveEventActivity || event.Activity.ActivityId != 10 {
+			t.Errorf("received %+v, want the state of activity 10", event)
+		}
+	default:
+		t.Error("the subscriber didn't receive the event")
+	}
+
+	select {
+	case event := <-otherEvents:
+		t.Errorf("a subscriber of another athlete received %+v", event)
+	default:
+	}
+
+	// slow subscribers miss events, publishing doesn't block
+	for range liveEventBufferSize + 1 {
+		bus.PublishActivityState(1, ActivityStateUpdate{ActivityId: 11})
+	}
+
+	unsubscribe()
+	bus.PublishActivityState(1, ActivityStateUpdate{ActivityId: 12})
+
+	if len(events) != liveEventBufferSize {
+		t.Errorf("%d events buffered, want %d", len(events), liveEventBufferSize)
+	}
+
+	select {
+	case <-bus.Done():
+		t.Fatal("Done() is closed before Close()")
+	default:
+	}
+
+	bus.Close()
+	bus.Close()
+
+	select {
+	case <-bus.Done():
+	default:
+		t.Error("Done() is not closed after Close()")
+	}
+}
+
+func TestQueuedActivityState(t *testing.T) {
+	tests := []struct {
+		name      string
+		ev        model.StravaWebhookEvent
+		processAt string
+		failed    bool
+	}{
+		{"waiting", model.StravaWebhookEvent{EventTime: 1700000000}, "2023-11-14T22:18:20Z", false},
+		{"retried", model.StravaWebhookEvent{EventTime: 1700000000, Attempts: 2, NextAttemptAt: 1700003600}, "2023-11-14T23:13:20Z", true},
+		{"retried before the delay", model.StravaWebhookEvent{EventTime: 1700000000, Attempts: 1, NextAttemptAt: 1700000060}, "2023-11-14T22:18:20Z", true},
+		{"given up", model.StravaWebhookEvent{EventTime: 1700000000, Attempts: 6, Dead: 1}, "", true},
+	}
+
+	for _, test := range tests {
+		test.ev.ObjectId = 10
+
+		stateUpdate := QueuedActivityState(&test.ev, 5*60)
+		if stateUpdate.ActivityId != 10 || stateUpdate.State != ActivityStatePending || stateUpdate.ProcessAt != test.processAt {
+			t.Errorf("%s: QueuedActivityState() = %+v, want pending, processed at %q", test.name, stateUpdate, test.processAt)
+		}
+
+		if failed := stateUpdate.Error != ""; failed != test.failed {
+			t.Errorf("%s: QueuedActivityState() = %+v, want failed %v", test.name, stateUpdate, test.failed)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package helper
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"slices"
 
 	"github.com/miki208/stravaadventuregame/internal/model"
 	"github.com/paulmach/orb"
@@ -39,26 +42,158 @@ func PointAndIndexAtDistanceAlongLine(ls orb.LineString, distance float64) (orb.
 	return to, len(ls) - 1
 }
 
-func DecodePolyline(coursePolylineEncoded string, reverse bool) (orb.LineString, error) {
-	coords, notDecodedBytes, err := polyline.DecodeCoords([]byte(coursePolylineEncoded)) // returns lat, lon pairs
-	if err != nil {
-		return nil, err
-	}
+// DecodePolyline decodes a course encoded as a polyline. Polylines with elevation (like the ones returned by ORS
+// with the elevation option) have elevation in meters encoded as the third dimension, with a precision of 1e2.
+// Elevations are returned only if withElevation is set, in the same order as the points.
+func DecodePolyline(coursePolylineEncoded string, withElevation bool, reverse bool) (orb.LineString, []float64, error) {
+	if !withElevation {
+		coords, notDecodedBytes, err := polyline.DecodeCoords([]byte(coursePolylineEncoded)) // returns lat, lon pairs
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(notDecodedBytes) > 0 {
+			return nil, nil, errors.New("not all bytes were decoded from polyline")
+		}
+
+		course := make(orb.LineString, 0, len(coords))
+		for _, coord := range coords {
+			course = append(course, orb.Point{coord[1], coord[0]})
+		}
 
-	if len(notDecodedBytes) > 0 {
-		return nil, errors.New("not all bytes were decoded from polyline")
+		if reverse {
+			course.Reverse()
+		}
+
+		return course, nil, nil
 	}
 
-	course := make(orb.LineString, 0, len(coords))
-	for _, coord := range coords {
-		course = append(course, orb.Point{coord[1], coord[0]})
+	var course orb.LineString
+	var elevations []float64
+
+	buf := []byte(coursePolylineEncoded)
+	var lat, lon, ele int
+	for len(buf) > 0 {
+		var deltas [3]int
+		for i := range deltas {
+			var err error
+			if deltas[i], buf, err = polyline.DecodeInt(buf); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		lat, lon, ele = lat+deltas[0], lon+deltas[1], ele+deltas[2]
+
+		course = append(course, orb.Point{float64(lon) / 1e5, float64(lat) / 1e5})
+		elevations = append(elevations, float64(ele)/1e2)
 	}
 
 	if reverse {
 		course.Reverse()
+		slices.Reverse(elevations)
+	}
+
+	return course, elevations, nil
+}
+
+// EncodePolyline encodes a course as a polyline, with elevations as the third dimension if they are provided.
+func EncodePolyline(course orb.LineString, elevations []float64) string {
+	if elevations == nil {
+		coords := make([][]float64, 0, len(course))
+		for _, point := range course {
+			coords = append(coords, []float64{point.Lat(), point.Lon()})
+		}
+
+		return string(polyline.EncodeCoords(coords))
+	}
+
+	var buf []byte
+	var lat, lon, ele int
+	for i, point := range course {
+		newLat, newLon, newEle := int(math.Round(point.Lat()*1e5)), int(math.Round(point.Lon()*1e5)), int(math.Round(elevations[i]*1e2))
+
+		buf = polyline.EncodeInt(buf, newLat-lat)
+		buf = polyline.EncodeInt(buf, newLon-lon)
+		buf = polyline.EncodeInt(buf, newEle-ele)
+
+		lat, lon, ele = newLat, newLon, newEle
+	}
+
+	return string(buf)
+}
+
+// ElevationGain returns total ascent and descent (both positive, in meters) along the given elevations.
+func ElevationGain(elevations []float64) (float64, float64) {
+	var ascent, descent float64
+	for i := 1; i < len(elevations); i++ {
+		if diff := elevations[i] - elevations[i-1]; diff > 0 {
+			ascent += diff
+		} else {
+			descent -= diff
+		}
+	}
+
+	return ascent, descent
+}
+
+// AscentAlongLine returns total ascent (in meters) from the start of the line to the given distance (in meters).
+// Elevation of the last, partially travelled segment is interpolated.
+func AscentAlongLine(ls orb.LineString, elevations []float64, distance float64) float64 {
+	var ascent, travelled float64
+	for i := 1; i < len(ls) && travelled < distance; i++ {
+		segmentDistance := geo.DistanceHaversine(ls[i-1], ls[i])
+		diff := elevations[i] - elevations[i-1]
+
+		if travelled+segmentDistance > distance {
+			diff *= (distance - travelled) / segmentDistance
+		}
+
+		if diff > 0 {
+			ascent += diff
+		}
+
+		travelled += segmentDistance
+	}
+
+	return ascent
+}
+
+// ElevationProfile samples the line into roughly maxPoints (km from the start, elevation in meters) pairs.
+// The first and the last point of the line are always included.
+func ElevationProfile(ls orb.LineString, elevations []float64, maxPoints int) [][2]float64 {
+	if len(ls) == 0 || maxPoints < 2 {
+		return nil
+	}
+
+	step := max(1, (len(ls)+maxPoints-3)/(maxPoints-1))
+
+	var profile [][2]float64
+	var travelled float64
+	for i := range ls {
+		if i > 0 {
+			travelled += geo.DistanceHaversine(ls[i-1], ls[i])
+		}
+
+		if i%step == 0 || i == len(ls)-1 {
+			profile = append(profile, [2]float64{math.Round(travelled/10) / 100, math.Round(elevations[i])})
+		}
 	}
 
-	return course, nil
+	return profile
+}
+
+// MetersToUnits converts distance in meters to the units used in directions requests ("m", "km" or "mi").
+func MetersToUnits(meters float64, units string) (float64, error) {
+	switch units {
+	case "m":
+		return meters, nil
+	case "km":
+		return meters / 1000, nil
+	case "mi":
+		return meters / 1609.344, nil
+	default:
+		return 0, fmt.Errorf("unsupported units: %s", units)
+	}
 }
 
 func GetPreferedLocationName(features []model.ReverseGeocodeFeature) string {
@@ -0,0 +1,139 @@
+package helper
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/twpayne/go-polyline"
+)
+
+func TestDecodePolyline(t *testing.T) {
+	// the example of the polyline algorithm
+	course, elevations, err := DecodePolyline("_p~iF~ps|U_ulLnnqC_mqNvxq`@", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := orb.LineString{{-120.2, 38.5}, {-120.95, 40.7}, {-126.453, 43.252}}
+	if !lineStringsEqual(course, want) || elevations != nil {
+		t.Errorf("DecodePolyline() = %v, %v, want %v, nil", course, elevations, want)
+	}
+
+	course, _, err = DecodePolyline("_p~iF~ps|U_ulLnnqC_mqNvxq`@", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !lineStringsEqual(course, orb.LineString{{-126.453, 43.252}, {-120.95, 40.7}, {-120.2, 38.5}}) {
+		t.Errorf("reversed DecodePolyline() = %v", course)
+	}
+
+	invalid := []struct {
+		encoded       string
+		withElevation bool
+	}{
+		{"_p~iF~ps|U_", false},
+		{string(polyline.EncodeInt(polyline.EncodeInt(nil, 4480000), 2040000)), true}, // no elevation of the point
+	}
+
+	for _, test := range invalid {
+		if _, _, err = DecodePolyline(test.encoded, test.withElevation, false); err == nil {
+			t.Errorf("DecodePolyline(%q, %v) succeeded, want an error", test.encoded, test.withElevation)
+		}
+	}
+}
+
+func TestEncodePolylineRoundTrip(t *testing.T) {
+	course := orb.LineString{{20.45, 44.8}, {20.41, 44.84}, {19.83, 45.25}}
+
+	tests := []struct {
+		elevations []float64
+		reverse    bool
+	}{
+		{nil, false},
+		{nil, true},
+		{[]float64{117.5, 76.03, 80}, false},
+		{[]float64{117.5, 76.03, 80}, true},
+		{[]float64{-3.5, 0, 1210.99}, false}, // below the sea level
+	}
+
+	for _, test := range tests {
+		encoded := EncodePolyline(course, test.elevations)
+
+		decoded, elevations, err := DecodePolyline(encoded, test.elevations != nil, test.reverse)
+		if err != nil {
+			t.Errorf("DecodePolyline(EncodePolyline(%v)) failed: %v", test.elevations, err)
+
+			continue
+		}
+
+		wantCourse, wantElevations := slices.Clone(course), slices.Clone(test.elevations)
+		if test.reverse {
+			wantCourse.Reverse()
+			slices.Reverse(wantElevations)
+		}
+
+		if !lineStringsEqual(decoded, wantCourse) || !slices.Equal(elevations, wantElevations) {
+			t.Errorf("round trip of %v (reverse %v) = %v, %v, want %v, %v", test.elevations, test.reverse, decoded, elevations, wantCourse, wantElevations)
+		}
+	}
+}
+
+func TestElevationGain(t *testing.T) {
+	tests := []struct {
+		elevations      []float64
+		ascent, descent float64
+		ascentAt500m    float64 // along a line with points 250 m apart
+		ascentAtTheEnd  float64
+	}{
+		{nil, 0, 0, 0, 0},
+		{[]float64{100, 120, 110, 150}, 60, 10, 20, 60},
+		{[]float64{100, 90, 80, 70}, 0, 30, 0, 0},
+		{[]float64{100, 100, 140, 140}, 40, 0, 40, 40},
+		{[]float64{100, 140, 100, 140}, 80, 40, 40, 80},
+	}
+
+	// 250 m apart along the equator
+	step := 250 / (2 * math.Pi * orb.EarthRadius / 360)
+	line := orb.LineString{{0, 0}, {step, 0}, {2 * step, 0}, {3 * step, 0}}
+
+	for _, test := range tests {
+		ascent, descent := ElevationGain(test.elevations)
+		if math.Abs(ascent-test.ascent) > 1e-9 || math.Abs(descent-test.descent) > 1e-9 {
+			t.Errorf("ElevationGain(%v) = %v, %v, want %v, %v", test.elevations, ascent, descent, test.ascent, test.descent)
+		}
+
+		if test.elevations == nil {
+			continue
+		}
+
+		if got := AscentAlongLine(line, test.elevations, 500); math.Abs(got-test.ascentAt500m) > 1e-6 {
+			t.Errorf("AscentAlongLine(%v, 500) = %v, want %v", test.elevations, got, test.ascentAt500m)
+		}
+
+		if got := AscentAlongLine(line, test.elevations, 10000); math.Abs(got-test.ascentAtTheEnd) > 1e-6 {
+			t.Errorf("AscentAlongLine(%v, 10000) = %v, want %v", test.elevations, got, test.ascentAtTheEnd)
+		}
+	}
+
+	// the partially travelled segment is interpolated
+	if got := AscentAlongLine(line, []float64{100, 140, 100, 140}, 125); math.Abs(got-20) > 1e-6 {
+		t.Errorf("AscentAlongLine() in the middle of a segment = %v, want 20", got)
+	}
+}
+
+func lineStringsEqual(a, b orb.LineString) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if math.Abs(a[i][0]-b[i][0]) > 1e-9 || math.Abs(a[i][1]-b[i][1]) > 1e-9 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,401 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// Waypoint is a named point read from (or written to) a GPX, GeoJSON or csv file.
+// Fields other than name and coordinates are optional.
+type Waypoint struct {
+	Name        string
+	Lat         float64
+	Lon         float64
+	Country     string
+	Category    string
+	Description string
+	ImageUrl    string
+}
+
+// csv columns of waypoints, in the order used when the file has no header row
+var waypointCsvColumns = []string{"name", "lat", "lon", "country", "category", "description", "image_url"}
+
+type gpxLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type gpxPoint struct {
+	Lat         float64  `xml:"lat,attr"`
+	Lon         float64  `xml:"lon,attr"`
+	Ele         *float64 `xml:"ele,omitempty"`
+	Name        string   `xml:"name,omitempty"`
+	Description string   `xml:"desc,omitempty"`
+	Link        *gpxLink `xml:"link,omitempty"`
+	Type        string   `xml:"type,omitempty"`
+}
+
+type gpxTrackSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxTrack struct {
+	Name     string            `xml:"name,omitempty"`
+	Segments []gpxTrackSegment `xml:"trkseg"`
+}
+
+type gpxRoute struct {
+	Points []gpxPoint `xml:"rtept"`
+}
+
+type gpxDocument struct {
+	XMLName   xml.Name   `xml:"gpx"`
+	Version   string     `xml:"version,attr,omitempty"`
+	Creator   string     `xml:"creator,attr,omitempty"`
+	Xmlns     string     `xml:"xmlns,attr,omitempty"`
+	Waypoints []gpxPoint `xml:"wpt"`
+	Tracks    []gpxTrack `xml:"trk"`
+	Routes    []gpxRoute `xml:"rte"`
+}
+
+// ParseRouteFile parses a GPX (tracks or routes) or a GeoJSON (LineString or MultiLineString) file into a single line.
+// The format is determined by the file extension, all segments found in the file are joined in order of appearance.
+// Elevations are returned only for GPX files in which every point has one, otherwise they are nil.
+func ParseRouteFile(fileName string, content []byte) (orb.LineString, []float64, error) {
+	var route orb.LineString
+	var elevations []float64
+	var err error
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".gpx":
+		route, elevations, err = parseGpxRoute(content)
+	case ".geojson", ".json":
+		route, err = parseGeoJsonRoute(content)
+	default:
+		return nil, nil, errors.New("unsupported route file format, expected .gpx or .geojson")
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(route) < 2 {
+		return nil, nil, errors.New("route must contain at least two points")
+	}
+
+	return route, elevations, nil
+}
+
+func parseGpxRoute(content []byte) (orb.LineString, []float64, error) {
+	var doc gpxDocument
+	if err := xml.Unmarshal(content, &doc); err != nil {
+		return nil, nil, err
+	}
+
+	var points []gpxPoint
+	for _, track := range doc.Tracks {
+		for _, segment := range track.Segments {
+			points = append(points, segment.Points...)
+		}
+	}
+
+	for _, rte := range doc.Routes {
+		points = append(points, rte.Points...)
+	}
+
+	route := make(orb.LineString, 0, len(points))
+	elevations := make([]float64, 0, len(points))
+	for _, point := range points {
+		route = append(route, orb.Point{point.Lon, point.Lat})
+
+		if elevations != nil && point.Ele != nil {
+			elevations = append(elevations, *point.Ele)
+		} else {
+			elevations = nil
+		}
+	}
+
+	return route, elevations, nil
+}
+
+func parseGeoJsonRoute(content []byte) (orb.LineString, error) {
+	var probe struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(content, &probe); err != nil {
+		return nil, err
+	}
+
+	var geometries []orb.Geometry
+	switch probe.Type {
+	case "FeatureCollection":
+		fc, err := geojson.UnmarshalFeatureCollection(content)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, feature := range fc.Features {
+			geometries = append(geometries, feature.Geometry)
+		}
+	case "Feature":
+		feature, err := geojson.UnmarshalFeature(content)
+		if err != nil {
+			return nil, err
+		}
+
+		geometries = append(geometries, feature.Geometry)
+	default:
+		geometry, err := geojson.UnmarshalGeometry(content)
+		if err != nil {
+			return nil, err
+		}
+
+		geometries = append(geometries, geometry.Geometry())
+	}
+
+	var route orb.LineString
+	for _, geometry := range geometries {
+		switch g := geometry.(type) {
+		case orb.LineString:
+			route = append(route, g...)
+		case orb.MultiLineString:
+			for _, ls := range g {
+				route = append(route, ls...)
+			}
+		}
+	}
+
+	return route, nil
+}
+
+// ParseWaypointsFile parses named points from GPX (wpt elements), GeoJSON (Point features with a name property)
+// or csv (name, lat, lon columns, header row is optional) file. The format is determined by the file extension.
+// Optional fields are read from GPX desc, type and link elements, from GeoJSON properties and from additional
+// csv columns (country, category, description, image_url), named the same way as in the files written by WriteWaypointsFile.
+func ParseWaypointsFile(fileName string, content []byte) ([]Waypoint, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".gpx":
+		return parseGpxWaypoints(content)
+	case ".geojson", ".json":
+		return parseGeoJsonWaypoints(content)
+	case ".csv":
+		return parseCsvWaypoints(content)
+	default:
+		return nil, errors.New("unsupported waypoints file format, expected .gpx, .geojson or .csv")
+	}
+}
+
+func parseGpxWaypoints(content []byte) ([]Waypoint, error) {
+	var doc gpxDocument
+	if err := xml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+
+	var waypoints []Waypoint
+	for _, point := range doc.Waypoints {
+		waypoint := Waypoint{
+			Name:        strings.TrimSpace(point.Name),
+			Lat:         point.Lat,
+			Lon:         point.Lon,
+			Category:    strings.TrimSpace(point.Type),
+			Description: strings.TrimSpace(point.Description),
+		}
+
+		if point.Link != nil {
+			waypoint.ImageUrl = strings.TrimSpace(point.Link.Href)
+		}
+
+		waypoints = append(waypoints, waypoint)
+	}
+
+	return waypoints, nil
+}
+
+func parseGeoJsonWaypoints(content []byte) ([]Waypoint, error) {
+	fc, err := geojson.UnmarshalFeatureCollection(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var waypoints []Waypoint
+	for _, feature := range fc.Features {
+		point, ok := feature.Geometry.(orb.Point)
+		if !ok {
+			continue
+		}
+
+		waypoints = append(waypoints, Waypoint{
+			Name:        strings.TrimSpace(feature.Properties.MustString("name", "")),
+			Lat:         point.Lat(),
+			Lon:         point.Lon(),
+			Country:     strings.TrimSpace(feature.Properties.MustString("country", "")),
+			Category:    strings.TrimSpace(feature.Properties.MustString("category", "")),
+			Description: strings.TrimSpace(feature.Properties.MustString("description", "")),
+			ImageUrl:    strings.TrimSpace(feature.Properties.MustString("image_url", "")),
+		})
+	}
+
+	return waypoints, nil
+}
+
+func parseCsvWaypoints(content []byte) ([]Waypoint, error) {
+	csvReader := csv.NewReader(bytes.NewReader(content))
+	csvReader.FieldsPerRecord = -1
+
+	columns := waypointCsvColumns
+
+	var waypoints []Waypoint
+	for line := 1; ; line++ {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		values := make(map[string]string)
+		for i, value := range record {
+			if i < len(columns) {
+				values[columns[i]] = strings.TrimSpace(value)
+			}
+		}
+
+		if len(record) < 3 {
+			return nil, fmt.Errorf("line %d: expected name, lat and lon columns", line)
+		}
+
+		lat, errLat := strconv.ParseFloat(values["lat"], 64)
+		lon, errLon := strconv.ParseFloat(values["lon"], 64)
+		if errLat != nil || errLon != nil {
+			if line == 1 {
+				// header, columns can be given in any order
+				columns = make([]string, 0, len(record))
+				for _, column := range record {
+					columns = append(columns, strings.ToLower(strings.TrimSpace(column)))
+				}
+
+				if !slices.Contains(columns, "lat") || !slices.Contains(columns, "lon") {
+					return nil, errors.New("line 1: header has to contain lat and lon columns")
+				}
+
+				continue
+			}
+
+			return nil, fmt.Errorf("line %d: %w", line, errors.Join(errLat, errLon))
+		}
+
+		waypoints = append(waypoints, Waypoint{
+			Name:        values["name"],
+			Lat:         lat,
+			Lon:         lon,
+			Country:     values["country"],
+			Category:    values["category"],
+			Description: values["description"],
+			ImageUrl:    values["image_url"],
+		})
+	}
+
+	return waypoints, nil
+}
+
+// WriteWaypointsFile writes waypoints in the format given by the file extension (.gpx, .geojson or .csv).
+func WriteWaypointsFile(fileName string, waypoints []Waypoint) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".gpx":
+		return writeGpxWaypoints(waypoints)
+	case ".geojson", ".json":
+		return writeGeoJsonWaypoints(waypoints)
+	case ".csv":
+		return writeCsvWaypoints(waypoints)
+	default:
+		return nil, errors.New("unsupported waypoints file format, expected .gpx, .geojson or .csv")
+	}
+}
+
+func writeGpxWaypoints(waypoints []Waypoint) ([]byte, error) {
+	doc := gpxDocument{
+		Version: "1.1",
+		Creator: "stravaadventuregame",
+		Xmlns:   "http://www.topografix.com/GPX/1/1",
+	}
+
+	for _, waypoint := range waypoints {
+		point := gpxPoint{
+			Lat:         waypoint.Lat,
+			Lon:         waypoint.Lon,
+			Name:        waypoint.Name,
+			Description: waypoint.Description,
+			Type:        waypoint.Category,
+		}
+
+		if waypoint.ImageUrl != "" {
+			point.Link = &gpxLink{Href: waypoint.ImageUrl}
+		}
+
+		doc.Waypoints = append(doc.Waypoints, point)
+	}
+
+	content, err := xml.MarshalIndent(&doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), content...), nil
+}
+
+func writeGeoJsonWaypoints(waypoints []Waypoint) ([]byte, error) {
+	fc := geojson.NewFeatureCollection()
+	for _, waypoint := range waypoints {
+		feature := geojson.NewFeature(orb.Point{waypoint.Lon, waypoint.Lat})
+		feature.Properties["name"] = waypoint.Name
+		feature.Properties["country"] = waypoint.Country
+		feature.Properties["category"] = waypoint.Category
+		feature.Properties["description"] = waypoint.Description
+		feature.Properties["image_url"] = waypoint.ImageUrl
+
+		fc.Append(feature)
+	}
+
+	return json.MarshalIndent(fc, "", "  ")
+}
+
+func writeCsvWaypoints(waypoints []Waypoint) ([]byte, error) {
+	var buf bytes.Buffer
+
+	csvWriter := csv.NewWriter(&buf)
+	if err := csvWriter.Write(waypointCsvColumns); err != nil {
+		return nil, err
+	}
+
+	for _, waypoint := range waypoints {
+		err := csvWriter.Write([]string{
+			waypoint.Name,
+			strconv.FormatFloat(waypoint.Lat, 'f', -1, 64),
+			strconv.FormatFloat(waypoint.Lon, 'f', -1, 64),
+			waypoint.Country,
+			waypoint.Category,
+			waypoint.Description,
+			waypoint.ImageUrl,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	csvWriter.Flush()
+
+	return buf.Bytes(), csvWriter.Error()
+}
@@ -0,0 +1,167 @@
+package helper
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/paulmach/orb"
+)
+
+func TestParseWaypointsFile(t *testing.T) {
+	tests := []struct {
+		fileName string
+		content  string
+		want     []Waypoint
+	}{
+		{"cities.csv", "Belgrade,44.82,20.46\nNovi Sad, 45.25 , 19.84,Serbia\n", []Waypoint{
+			{Name: "Belgrade", Lat: 44.82, Lon: 20.46},
+			{Name: "Novi Sad", Lat: 45.25, Lon: 19.84, Country: "Serbia"},
+		}},
+		// columns in any order after a header
+		{"cities.CSV", "lon,Lat,name,category\n20.46,44.82,Belgrade,city\n", []Waypoint{
+			{Name: "Belgrade", Lat: 44.82, Lon: 20.46, Category: "city"},
+		}},
+		{"cities.gpx", `<?xml version="1.0"?>
+<gpx version="1.1" xmlns="http://www.topografix.com/GPX/1/1">
+  <wpt lat="44.82" lon="20.46"><name> Belgrade </name><desc>Capital</desc><type>city</type><link href="https://example.com/bg.jpg"/></wpt>
+  <wpt lat="45.25" lon="19.84"><name>Novi Sad</name></wpt>
+  <trk><trkseg><trkpt lat="1" lon="1"/></trkseg></trk>
+</gpx>`, []Waypoint{
+			{Name: "Belgrade", Lat: 44.82, Lon: 20.46, Category: "city", Description: "Capital", ImageUrl: "https://example.com/bg.jpg"},
+			{Name: "Novi Sad", Lat: 45.25, Lon: 19.84},
+		}},
+		// only points are waypoints
+		{"cities.geojson", `{"type": "FeatureCollection", "features": [
+  {"type": "Feature", "geometry": {"type": "Point", "coordinates": [20.46, 44.82]}, "properties": {"name": "Belgrade", "country": "Serbia"}},
+  {"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[20.46, 44.82], [19.84, 45.25]]}, "properties": {"name": "Road"}},
+  {"type": "Feature", "geometry": {"type": "Point", "coordinates": [19.84, 45.25]}, "properties": {}}
+]}`, []Waypoint{
+			{Name: "Belgrade", Lat: 44.82, Lon: 20.46, Country: "Serbia"},
+			{Lat: 45.25, Lon: 19.84},
+		}},
+	}
+
+	for _, test := range tests {
+		waypoints, err := ParseWaypointsFile(test.fileName, []byte(test.content))
+		if err != nil {
+			t.Errorf("ParseWaypointsFile(%q) failed: %v", test.fileName, err)
+
+			continue
+		}
+
+		if !slices.Equal(waypoints, test.want) {
+			t.Errorf("ParseWaypointsFile(%q) = %+v, want %+v", test.fileName, waypoints, test.want)
+		}
+	}
+}
+
+func TestParseWaypointsFileRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		fileName string
+		content  string
+	}{
+		{"cities.kml", "<kml/>"},
+		{"cities.csv", "Belgrade,44.82\n"},
+		{"cities.csv", "Belgrade,44.82,20.46\nNovi Sad,north,19.84\n"},
+		{"cities.csv", "name,latitude,longitude\nBelgrade,44.82,20.46\n"},
+		{"cities.gpx", "<gpx><wpt lat=\"x\""},
+		{"cities.geojson", `{"type": "Point"`},
+	}
+
+	for _, test := range tests {
+		if waypoints, err := ParseWaypointsFile(test.fileName, []byte(test.content)); err == nil {
+			t.Errorf("ParseWaypointsFile(%q, %q) = %+v, want an error", test.fileName, test.content, waypoints)
+		}
+	}
+}
+
+func TestWriteWaypointsFileRoundTrip(t *testing.T) {
+	waypoints := []Waypoint{
+		{Name: "Belgrade", Lat: 44.82, Lon: 20.46, Country: "Serbia", Category: "city", Description: "Capital, \"White city\"", ImageUrl: "https://example.com/bg.jpg"},
+		{Name: "Novi Sad", Lat: 45.25, Lon: 19.84},
+	}
+
+	for _, test := range []struct {
+		fileName    string
+		withCountry bool // GPX waypoints have no country
+	}{
+		{"cities.csv", true},
+		{"cities.geojson", true},
+		{"cities.gpx", false},
+	} {
+		content, err := WriteWaypointsFile(test.fileName, waypoints)
+		if err != nil {
+			t.Errorf("WriteWaypointsFile(%q) failed: %v", test.fileName, err)
+
+			continue
+		}
+
+		parsed, err := ParseWaypointsFile(test.fileName, content)
+		if err != nil {
+			t.Errorf("ParseWaypointsFile() of the written %q failed: %v", test.fileName, err)
+
+			continue
+		}
+
+		want := slices.Clone(waypoints)
+		if !test.withCountry {
+			want[0].Country = ""
+		}
+
+		if !slices.Equal(parsed, want) {
+			t.Errorf("round trip of %q = %+v, want %+v", test.fileName, parsed, want)
+		}
+	}
+}
+
+func TestParseRouteFile(t *testing.T) {
+	tests := []struct {
+		fileName       string
+		content        string
+		wantPoints     int
+		wantElevations []float64
+	}{
+		// segments of all tracks and routes, in order
+		{"route.gpx", `<gpx><trk><trkseg><trkpt lat="44.82" lon="20.46"><ele>117</ele></trkpt><trkpt lat="45.0" lon="20.2"><ele>90.5</ele></trkpt></trkseg>
+<trkseg><trkpt lat="45.25" lon="19.84"><ele>80</ele></trkpt></trkseg></trk></gpx>`, 3, []float64{117, 90.5, 80}},
+		{"route.GPX", `<gpx><rte><rtept lat="44.82" lon="20.46"><ele>117</ele></rtept><rtept lat="45.25" lon="19.84"/></rte></gpx>`, 2, nil}, // not every point has elevation
+		{"route.geojson", `{"type": "LineString", "coordinates": [[20.46, 44.82], [20.2, 45.0], [19.84, 45.25]]}`, 3, nil},
+		{"route.json", `{"type": "Feature", "geometry": {"type": "MultiLineString", "coordinates": [[[20.46, 44.82], [20.2, 45.0]], [[20.1, 45.1], [19.84, 45.25]]]}, "properties": {}}`, 4, nil},
+		{"route.geojson", `{"type": "FeatureCollection", "features": [
+  {"type": "Feature", "geometry": {"type": "Point", "coordinates": [20.46, 44.82]}, "properties": {}},
+  {"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[20.46, 44.82], [19.84, 45.25]]}, "properties": {}}
+]}`, 2, nil},
+	}
+
+	for _, test := range tests {
+		route, elevations, err := ParseRouteFile(test.fileName, []byte(test.content))
+		if err != nil {
+			t.Errorf("ParseRouteFile(%q) failed: %v", test.fileName, err)
+
+			continue
+		}
+
+		if len(route) != test.wantPoints || !slices.Equal(elevations, test.wantElevations) {
+			t.Errorf("ParseRouteFile(%q) = %v, %v, want %d points and elevations %v", test.fileName, route, elevations, test.wantPoints, test.wantElevations)
+		}
+
+		if route[0] != (orb.Point{20.46, 44.82}) || route[len(route)-1] != (orb.Point{19.84, 45.25}) {
+			t.Errorf("ParseRouteFile(%q) = %v, want a route from Belgrade to Novi Sad", test.fileName, route)
+		}
+	}
+
+	for _, test := range []struct {
+		fileName string
+		content  string
+	}{
+		{"route.kml", "<kml/>"},
+		{"route.gpx", `<gpx><trk><trkseg><trkpt lat="44.82" lon="20.46"/></trkseg></trk></gpx>`}, // a single point
+		{"route.gpx", "<gpx><trk>"},
+		{"route.geojson", `{"type": "Point", "coordinates": [20.46, 44.82]}`},
+		{"route.geojson", "{"},
+	} {
+		if route, _, err := ParseRouteFile(test.fileName, []byte(test.content)); err == nil {
+			t.Errorf("ParseRouteFile(%q, %q) = %v, want an error", test.fileName, test.content, route)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package helper
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+
+	"github.com/miki208/stravaadventuregame/internal/model"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+)
+
+// ValidateLocation trims text fields of the location and checks that it has a name and valid coordinates.
+// Image url is optional, but it has to be an absolute http(s) url if it's set.
+func ValidateLocation(location *model.Location) error {
+	location.Name = strings.TrimSpace(location.Name)
+	location.Country = strings.TrimSpace(location.Country)
+	location.Description = strings.TrimSpace(location.Description)
+	location.ImageUrl = strings.TrimSpace(location.ImageUrl)
+	location.Category = strings.TrimSpace(location.Category)
+
+	if location.Name == "" {
+		return errors.New("location name is required")
+	}
+
+	if math.IsNaN(location.Lat) || location.Lat < -90 || location.Lat > 90 {
+		return errors.New("latitude has to be between -90 and 90")
+	}
+
+	if math.IsNaN(location.Lon) || location.Lon < -180 || location.Lon > 180 {
+		return errors.New("longitude has to be between -180 and 180")
+	}
+
+	if location.ImageUrl != "" && !isHttpUrl(location.ImageUrl) {
+		return errors.New("image url has to be an absolute http or https url")
+	}
+
+	return nil
+}
+
+func isHttpUrl(rawUrl string) bool {
+	parsedUrl, err := url.Parse(rawUrl)
+
+	return err == nil && (parsedUrl.Scheme == "http" || parsedUrl.Scheme == "https") && parsedUrl.Host != ""
+}
+
+// IsLocationVisibleTo checks if the athlete can see (and start adventures from or to) the location.
+func IsLocationVisibleTo(location *model.Location, athleteId int64) bool {
+	return location.OwnerId == 0 || location.OwnerId == athleteId
+}
+
+// LocationsVisibleTo returns locations visible to everyone, followed by private locations of the athlete.
+func LocationsVisibleTo(athleteId int64, db *sql.DB, tx *sql.Tx) ([]model.Location, error) {
+	return model.AllLocationsVisibleTo(athleteId, 0, 0, db, tx)
+}
+
+// locations closer than this are considered the same place, whatever their names are
+const sameLocationDistanceMeters = 100
+
+type SkippedWaypoint struct {
+	Waypoint Waypoint
+	Reason   string
+}
+
+type LocationImportResult struct {
+	Created []model.Location
+	Skipped []SkippedWaypoint
+}
+
+// ImportLocations creates a public location for every valid waypoint which isn't a duplicate of an existing (or already imported)
+// location. Waypoint is a duplicate if it's closer than 100 m to a location, or if it has the same name (case insensitive)
+// as a location closer than dedupeDistanceKm.
+func ImportLocations(waypoints []Waypoint, dedupeDistanceKm float64, db *sql.DB, tx *sql.Tx) (*LocationImportResult, error) {
+	// private locations of athletes don't stop the same place from being added to the public catalogue
+	knownLocations, err := model.AllLocations(db, tx, map[string]any{"owner_id": 0})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &LocationImportResult{}
+	for _, waypoint := range waypoints {
+		location := model.Location{
+			Lat:         waypoint.Lat,
+			Lon:         waypoint.Lon,
+			Name:        waypoint.Name,
+			Country:     waypoint.Country,
+			Description: waypoint.Description,
+			ImageUrl:    waypoint.ImageUrl,
+			Category:    waypoint.Category,
+		}
+
+		if err = ValidateLocation(&location); err != nil {
+			result.Skipped = append(result.Skipped, SkippedWaypoint{Waypoint: waypoint, Reason: err.Error()})
+
+			continue
+		}
+
+		if reason, duplicate := findDuplicateLocation(&location, knownLocations, dedupeDistanceKm); duplicate {
+			result.Skipped = append(result.Skipped, SkippedWaypoint{Waypoint: waypoint, Reason: reason})
+
+			continue
+		}
+
+		if err = location.Save(db, tx); err != nil {
+			return nil, err
+		}
+
+		knownLocations = append(knownLocations, location)
+		result.Created = append(result.Created, location)
+	}
+
+	return result, nil
+}
+
+func findDuplicateLocation(location *model.Location, knownLocations []model.Location, dedupeDistanceKm float64) (string, bool) {
+	point := orb.Point{location.Lon, location.Lat}
+
+	for _, known := range knownLocations {
+		distance := geo.DistanceHaversine(point, orb.Point{known.Lon, known.Lat})
+
+		if distance < sameLocationDistanceMeters {
+			return fmt.Sprintf("too close to %s (%.0f m)", known.Name, distance), true
+		}
+
+		if strings.EqualFold(location.Name, known.Name) && distance < dedupeDistanceKm*1000 {
+			return fmt.Sprintf("%s already exists %.1f km away", known.Name, distance/1000), true
+		}
+	}
+
+	return "", false
+}
+
+// LocationsToWaypoints converts locations to waypoints, to be written by WriteWaypointsFile.
+func LocationsToWaypoints(locations []model.Location) []Waypoint {
+	waypoints := make([]Waypoint, 0, len(locations))
+	for _, location := range locations {
+		waypoints = append(waypoints, Waypoint{
+			Name:        location.Name,
+			Lat:         location.Lat,
+			Lon:         location.Lon,
+			Country:     location.Country,
+			Category:    location.Category,
+			Description: location.Description,
+			ImageUrl:    location.ImageUrl,
+		})
+	}
+
+	return waypoints
+}
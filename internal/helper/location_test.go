@@ -0,0 +1,93 @@
+package helper
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+
+	"github.com/miki208/stravaadventuregame/internal/database/databasetest"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+func TestImportLocations(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		existing := []model.Location{
+			{Name: "Belgrade", Lat: 44.8176, Lon: 20.4569},
+			{Name: "Zemun", Lat: 44.8433, Lon: 20.4011, OwnerId: 1}, // private, doesn't count
+		}
+
+		athlete := model.Athlete{Id: 1, FirstName: "Jane"}
+		if err := athlete.Save(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		for i := range existing {
+			if err := existing[i].Save(db, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		tests := []struct {
+			waypoint Waypoint
+			created  bool
+		}{
+			{Waypoint{Name: "Beograd", Lat: 44.8180, Lon: 20.4570}, false},              // within 100 m
+			{Waypoint{Name: "BELGRADE", Lat: 44.90, Lon: 20.50}, false},                 // same name within 20 km
+			{Waypoint{Name: "Belgrade", Lat: 33.79, Lon: -84.28}, true},                 // same name far away
+			{Waypoint{Name: "Zemun", Lat: 44.8433, Lon: 20.4011}, true},                 // only a private location there
+			{Waypoint{Name: " Novi Sad ", Lat: 45.2671, Lon: 19.8335}, true},            // trimmed
+			{Waypoint{Name: "novi sad", Lat: 45.25, Lon: 19.85}, false},                 // duplicate of the one imported before
+			{Waypoint{Name: "", Lat: 43.32, Lon: 21.89}, false},                         // no name
+			{Waypoint{Name: "Nowhere", Lat: 91, Lon: 0}, false},                         // invalid latitude
+			{Waypoint{Name: "Nis", Lat: 43.32, Lon: 21.89, ImageUrl: "ftp://x"}, false}, // invalid image url
+		}
+
+		var waypoints []Waypoint
+		wantSkipped := 0
+		for _, test := range tests {
+			waypoints = append(waypoints, test.waypoint)
+
+			if !test.created {
+				wantSkipped++
+			}
+		}
+
+		result, err := ImportLocations(waypoints, 20, db, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var created []string
+		for _, location := range result.Created {
+			created = append(created, location.Name)
+
+			if location.Id == 0 || location.OwnerId != 0 {
+				t.Errorf("created location %+v, want a saved public location", location)
+			}
+		}
+
+		// names are trimmed
+		if want := []string{"Belgrade", "Zemun", "Novi Sad"}; !slices.Equal(created, want) {
+			t.Errorf("created %v, want %v", created, want)
+		}
+
+		if len(result.Skipped) != wantSkipped {
+			t.Errorf("skipped %+v, want %d waypoints", result.Skipped, wantSkipped)
+		}
+
+		for _, skipped := range result.Skipped {
+			if skipped.Reason == "" {
+				t.Errorf("waypoint %+v skipped without a reason", skipped.Waypoint)
+			}
+		}
+
+		locations, err := model.AllLocations(db, nil, map[string]any{"owner_id": 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(locations) != 4 {
+			t.Errorf("%d public locations after the import, want 4", len(locations))
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package helper
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+const (
+	OutboxActivityDescription = "activity.description" // ActivityDescriptionUpdate
+)
+
+// ActivityDescriptionUpdate is the payload of an outbox message which writes the description of a Strava activity.
+type ActivityDescriptionUpdate struct {
+	AthleteId   int64  `json:"athlete_id"`
+	ActivityId  int64  `json:"activity_id"`
+	Description string `json:"description"`
+}
+
+// EnqueueOutboxMessage queues a side effect of the given kind, to be performed once the transaction is committed. It
+// should be called in the transaction which causes the side effect, so that both are committed (or lost) together.
+func EnqueueOutboxMessage(kind string, payload any, db *sql.DB, tx *sql.Tx) error {
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	now := int(time.Now().Unix())
+
+	message := model.OutboxMessage{
+		Kind:          kind,
+		Payload:       string(content),
+		Status:        model.OutboxMessagePending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+
+	return message.Save(db, tx)
+}
@@ -0,0 +1,58 @@
+package helper
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+// PublishAdventure makes the adventure public under a new random slug. If the adventure is already public, its slug is
+// rotated, so the old link stops working. The map image record is created too, if the adventure doesn't have one yet,
+// since the public page links to it.
+func PublishAdventure(adventure *model.Adventure, db *sql.DB, tx *sql.Tx) (*model.AdventurePublicLink, error) {
+	publicLink := model.AdventurePublicLink{
+		AthleteId:     adventure.AthleteId,
+		StartLocation: adventure.StartLocation,
+		EndLocation:   adventure.EndLocation,
+		Slug:          strings.ToLower(rand.Text()),
+		CreatedAt:     int(time.Now().Unix()),
+	}
+
+	if err := publicLink.Save(db, tx); err != nil {
+		return nil, err
+	}
+
+	if _, err := AdventureMapImageOf(adventure, db, tx); err != nil {
+		return nil, err
+	}
+
+	return &publicLink, nil
+}
+
+// AdventureContributions returns a page of activities that counted towards the adventure (all of them if limit is 0),
+// the most recent first.
+func AdventureContributions(adventure *model.Adventure, limit int, offset int, db *sql.DB, tx *sql.Tx) ([]model.Activity, error) {
+	from, to := adventureContributionPeriod(adventure)
+
+	return model.AllActivitiesBetween(adventure.AthleteId, from, to, limit, offset, db, tx)
+}
+
+func CountAdventureContributions(adventure *model.Adventure, db *sql.DB, tx *sql.Tx) (int, error) {
+	from, to := adventureContributionPeriod(adventure)
+
+	return model.CountActivitiesBetween(adventure.AthleteId, from, to, db, tx)
+}
+
+// adventureContributionPeriod returns start dates of activities which count towards the adventure: activities after the
+// one which completed the adventure didn't count.
+func adventureContributionPeriod(adventure *model.Adventure) (int, int) {
+	if adventure.Completed == 1 {
+		return adventure.StartDate, adventure.EndDate
+	}
+
+	return adventure.StartDate, math.MaxInt
+}
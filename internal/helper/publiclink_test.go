@@ -0,0 +1,54 @@
+package helper
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/miki208/stravaadventuregame/internal/database/databasetest"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+func TestPublishAdventureCreatesTheMapImage(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		athlete := model.Athlete{Id: 1, FirstName: "Jane"}
+		if err := athlete.Save(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		start, end := model.Location{Name: "Belgrade"}, model.Location{Name: "Novi Sad"}
+		for _, location := range []*model.Location{&start, &end} {
+			if err := location.Save(db, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		adventure := model.Adventure{AthleteId: 1, StartLocation: start.Id, EndLocation: end.Id, TotalDistance: 94}
+		if err := adventure.Insert(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		var imageIds []string
+		for range 2 {
+			publicLink, err := PublishAdventure(&adventure, db, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if found, err := publicLink.Load(1, start.Id, end.Id, db, nil); err != nil || !found {
+				t.Fatalf("the published link wasn't saved (%v)", err)
+			}
+
+			var mapImage model.AdventureMapImage
+			if found, err := mapImage.Load(1, start.Id, end.Id, db, nil); err != nil || !found {
+				t.Fatalf("the map image of the published adventure wasn't created (%v)", err)
+			}
+
+			imageIds = append(imageIds, mapImage.ImageId)
+		}
+
+		// publishing again rotates the link, the map image is rotated by the caller
+		if imageIds[0] != imageIds[1] {
+			t.Errorf("image ids %v, want the existing map image kept", imageIds)
+		}
+	})
+}
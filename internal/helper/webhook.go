@@ -0,0 +1,193 @@
+package helper
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+const (
+	WebhookEventAdventureStarted   = "adventure.started"
+	WebhookEventProgress           = "progress"
+	WebhookEventMilestoneReached   = "milestone.reached" // a place along the course is passed
+	WebhookEventAdventureCompleted = "adventure.completed"
+)
+
+var WebhookEvents = []string{WebhookEventAdventureStarted, WebhookEventProgress, WebhookEventMilestoneReached, WebhookEventAdventureCompleted}
+
+const maxWebhookUrlLength = 500
+
+// WebhookPayload is the json body posted to webhook endpoints.
+type WebhookPayload struct {
+	Event      string            `json:"event"`
+	CreatedAt  string            `json:"created_at"`
+	AthleteId  int64             `json:"athlete_id"`
+	Adventure  WebhookAdventure  `json:"adventure"`
+	ActivityId int64             `json:"activity_id,omitempty"` // the activity that made the progress
+	Milestone  *WebhookMilestone `json:"milestone,omitempty"`
+}
+
+type WebhookAdventure struct {
+	StartLocationId     int     `json:"start_location_id"`
+	StartLocationName   string  `json:"start_location_name"`
+	EndLocationId       int     `json:"end_location_id"`
+	EndLocationName     string  `json:"end_location_name"`
+	CurrentLocationName string  `json:"current_location_name"`
+	CurrentLocationLat  float64 `json:"current_location_lat"`
+	CurrentLocationLon  float64 `json:"current_location_lon"`
+	CurrentDistanceKm   float32 `json:"current_distance_km"`
+	TotalDistanceKm     float32 `json:"total_distance_km"`
+	Completed           bool    `json:"completed"`
+}
+
+type WebhookMilestone struct {
+	Name string  `json:"name"`
+	Km   float64 `json:"km"`
+}
+
+// ValidateWebhookUrl makes sure that events are posted only over https.
+func ValidateWebhookUrl(webhookUrl string) error {
+	if len(webhookUrl) > maxWebhookUrlLength {
+		return fmt.Errorf("url can't be longer than %d characters", maxWebhookUrlLength)
+	}
+
+	parsedUrl, err := url.Parse(webhookUrl)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+
+	if parsedUrl.Scheme != "https" || parsedUrl.Hostname() == "" {
+		return errors.New("url has to be an absolute https url")
+	}
+
+	if parsedUrl.User != nil {
+		return errors.New("url can't contain credentials")
+	}
+
+	return nil
+}
+
+// ValidateWebhookEvents returns the events joined as they are stored in the endpoint.
+func ValidateWebhookEvents(events []string) (string, error) {
+	if len(events) == 0 {
+		return "", errors.New("at least one event is required")
+	}
+
+	for _, event := range events {
+		if !slices.Contains(WebhookEvents, event) {
+			return "", fmt.Errorf("unknown event %q", event)
+		}
+	}
+
+	return strings.Join(slices.Compact(slices.Sorted(slices.Values(events))), ","), nil
+}
+
+func NewWebhookSecret() string {
+	return "whsec_" + rand.Text()
+}
+
+// SignWebhookPayload returns the value of the signature header: the timestamp and the hex encoded HMAC-SHA256 of
+// "<timestamp>.<body>", keyed with the endpoint secret. Receivers should recompute it and reject old timestamps.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func webhookEndpointSubscribedTo(endpoint *model.WebhookEndpoint, event string) bool {
+	return slices.Contains(strings.Split(endpoint.Events, ","), event)
+}
+
+// EnqueueWebhookEvent queues the event for every endpoint of the athlete and every admin endpoint subscribed to it.
+// It should be called in the transaction which changes the adventure, so that the event is queued only if the change is
+// committed. activity and milestone are optional.
+func EnqueueWebhookEvent(event string, adventure *model.Adventure, activity *model.Activity, milestone *WebhookMilestone, db *sql.DB, tx *sql.Tx) error {
+	athleteEndpoints, err := model.AllWebhookEndpoints(db, tx, map[string]any{"athlete_id": adventure.AthleteId})
+	if err != nil {
+		return err
+	}
+
+	globalEndpoints, err := model.AllWebhookEndpoints(db, tx, map[string]any{"athlete_id": 0})
+	if err != nil {
+		return err
+	}
+
+	endpoints := slices.DeleteFunc(append(athleteEndpoints, globalEndpoints...), func(endpoint model.WebhookEndpoint) bool {
+		return !webhookEndpointSubscribedTo(&endpoint, event)
+	})
+
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	var startLocation, endLocation model.Location
+	if _, err = startLocation.Load(adventure.StartLocation, db, tx); err != nil {
+		return err
+	}
+
+	if _, err = endLocation.Load(adventure.EndLocation, db, tx); err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	payload := WebhookPayload{
+		Event:     event,
+		CreatedAt: now.UTC().Format(time.RFC3339),
+		AthleteId: adventure.AthleteId,
+		Adventure: WebhookAdventure{
+			StartLocationId:     adventure.StartLocation,
+			StartLocationName:   startLocation.Name,
+			EndLocationId:       adventure.EndLocation,
+			EndLocationName:     endLocation.Name,
+			CurrentLocationName: adventure.CurrentLocationName,
+			CurrentLocationLat:  adventure.CurrentLocationLat,
+			CurrentLocationLon:  adventure.CurrentLocationLon,
+			CurrentDistanceKm:   adventure.CurrentDistance,
+			TotalDistanceKm:     adventure.TotalDistance,
+			Completed:           adventure.Completed == 1,
+		},
+		Milestone: milestone,
+	}
+
+	if activity != nil {
+		payload.ActivityId = activity.Id
+	}
+
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	for _, endpoint := range endpoints {
+		delivery := model.WebhookDelivery{
+			EndpointId:    endpoint.Id,
+			Event:         event,
+			Payload:       string(content),
+			Status:        model.WebhookDeliveryPending,
+			NextAttemptAt: int(now.Unix()),
+			CreatedAt:     int(now.Unix()),
+		}
+
+		if err = delivery.Save(db, tx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
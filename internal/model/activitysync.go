@@ -0,0 +1,56 @@
+package model
+
+import (
+	"database/sql"
+)
+
+const (
+	ActivitySyncPending = "pending" // queued, waiting to be processed
+	ActivitySyncCreated = "created"
+	ActivitySyncUpdated = "updated"
+	ActivitySyncDeleted = "deleted"
+	ActivitySyncIgnored = "ignored" // processed without changes, see the reason (e.g. unsupported activity type)
+	ActivitySyncDropped = "dropped" // removed from the queue before it was processed
+	ActivitySyncFailed  = "failed"  // the last processing attempt failed, it is retried while the event is queued
+)
+
+var ActivitySyncStatuses = []string{ActivitySyncPending, ActivitySyncCreated, ActivitySyncUpdated, ActivitySyncDeleted, ActivitySyncIgnored,
+	ActivitySyncDropped, ActivitySyncFailed}
+
+// ActivitySync is one activity webhook event received from Strava, together with the outcome of its processing.
+type ActivitySync struct {
+	Id          int    `db:"id,pk,auto"`
+	AthleteId   int64  `db:"athlete_id"`
+	ActivityId  int64  `db:"activity_id"`
+	AspectType  string `db:"aspect_type"`
+	SportType   string `db:"sport_type"` // known only once the activity is fetched from Strava
+	Status      string `db:"status"`
+	Reason      string `db:"reason"` // why the activity was ignored, dropped or failed
+	ReceivedAt  int    `db:"received_at"`
+	ProcessAt   int    `db:"process_at"` // the earliest time the event gets processed
+	ProcessedAt int    `db:"processed_at"`
+}
+
+var activitySyncRepo = newRepository[ActivitySync]("ActivitySync")
+
+func (sync *ActivitySync) Load(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return activitySyncRepo.load(dbtx(db, tx), sync, id)
+}
+
+// Save inserts the entry if its id is 0 (populating the id), otherwise it updates the one with the given id.
+func (sync *ActivitySync) Save(db *sql.DB, tx *sql.Tx) error {
+	return activitySyncRepo.save(dbtx(db, tx), sync)
+}
+
+func (sync *ActivitySync) Delete(db *sql.DB, tx *sql.Tx) error {
+	return activitySyncRepo.delete(dbtx(db, tx), sync)
+}
+
+func ActivitySyncExists(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return activitySyncRepo.exists(dbtx(db, tx), id)
+}
+
+// AllActivitySyncs returns entries matching the filter, the latest received first.
+func AllActivitySyncs(db *sql.DB, tx *sql.Tx, filter map[string]any) ([]ActivitySync, error) {
+	return activitySyncRepo.all(dbtx(db, tx), filter, QueryOptions{OrderBy: "id DESC"})
+}
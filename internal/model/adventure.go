@@ -2,118 +2,64 @@ package model
 
 import (
 	"database/sql"
-	"errors"
+	"fmt"
 )
 
 type Adventure struct {
-	AthleteId                   int64
-	StartLocation               int
-	EndLocation                 int
-	CurrentLocationLat          float64
-	CurrentLocationLon          float64
-	CurrentLocationIndexOnRoute int
-	CurrentLocationName         string
-	CurrentDistance             float32
-	TotalDistance               float32
-	Completed                   int
-	StartDate                   int
-	EndDate                     int
+	AthleteId                   int64   `db:"athlete_id,pk"`
+	StartLocation               int     `db:"start_location,pk"`
+	EndLocation                 int     `db:"end_location,pk"`
+	CurrentLocationLat          float64 `db:"current_location_lat"`
+	CurrentLocationLon          float64 `db:"current_location_lon"`
+	CurrentLocationIndexOnRoute int     `db:"current_location_index_on_route"`
+	CurrentLocationName         string  `db:"current_location_name"`
+	CurrentDistance             float32 `db:"current_distance"`
+	TotalDistance               float32 `db:"total_distance"`
+	Completed                   int     `db:"completed"`
+	StartDate                   int     `db:"start_date"`
+	EndDate                     int     `db:"end_date"`
 }
 
-func (adventure *Adventure) Load(athlId int64, startLocation int, endLocation int, db *sql.DB, tx *sql.Tx) (bool, error) {
-	query, params := PrepareQuery("SELECT * FROM Adventure", map[string]any{
-		"athlete_id":     athlId,
-		"start_location": startLocation,
-		"end_location":   endLocation,
-	})
-
-	var row *sql.Row
-	if tx != nil {
-		row = tx.QueryRow(query, params...)
-	} else {
-		row = db.QueryRow(query, params...)
-	}
+var adventureRepo = newRepository[Adventure]("Adventure")
 
-	err := row.Scan(&adventure.AthleteId, &adventure.StartLocation, &adventure.EndLocation, &adventure.CurrentLocationLat, &adventure.CurrentLocationLon, &adventure.CurrentLocationIndexOnRoute, &adventure.CurrentLocationName, &adventure.CurrentDistance, &adventure.TotalDistance, &adventure.Completed, &adventure.StartDate, &adventure.EndDate)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			err = nil
-		}
+// CourseDbName returns the name under which the course between two locations is kept in the file database.
+// Course is stored only once for both directions, going from the location with the lower id to the one with the higher id.
+func CourseDbName(startLocation, endLocation int) string {
+	return fmt.Sprintf("%d-%d", min(startLocation, endLocation), max(startLocation, endLocation))
+}
 
-		return false, err
-	}
+func (adventure *Adventure) Load(athlId int64, startLocation int, endLocation int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return adventureRepo.load(dbtx(db, tx), adventure, athlId, startLocation, endLocation)
+}
 
-	return true, nil
+// Insert inserts a new adventure, it fails if the athlete already has one between the same locations.
+func (adv *Adventure) Insert(db *sql.DB, tx *sql.Tx) error {
+	return adventureRepo.insert(dbtx(db, tx), adv)
 }
 
+// Save updates the adventure. It returns ErrRowNotFound if the adventure doesn't exist, e.g. it was abandoned after it
+// was loaded, the adventure isn't inserted again then.
 func (adv *Adventure) Save(db *sql.DB, tx *sql.Tx) error {
-	var err error
-
-	var found bool
-	found, err = AdventureExists(adv.AthleteId, adv.StartLocation, adv.EndLocation, db, tx)
-	if err != nil {
-		return err
-	}
-
-	if found {
-		query := "UPDATE Adventure SET current_location_lat=?, current_location_lon=?, current_location_index_on_route=?, current_location_name=?, current_distance=?, total_distance=?, completed=?, start_date=?, end_date=? WHERE athlete_id=? AND start_location=? AND end_location=?"
-
-		if tx != nil {
-			_, err = tx.Exec(query, adv.CurrentLocationLat, adv.CurrentLocationLon, adv.CurrentLocationIndexOnRoute, adv.CurrentLocationName, adv.CurrentDistance, adv.TotalDistance, adv.Completed, adv.StartDate, adv.EndDate, adv.AthleteId, adv.StartLocation, adv.EndLocation)
-		} else {
-			_, err = db.Exec(query, adv.CurrentLocationLat, adv.CurrentLocationLon, adv.CurrentLocationIndexOnRoute, adv.CurrentLocationName, adv.CurrentDistance, adv.TotalDistance, adv.Completed, adv.StartDate, adv.EndDate, adv.AthleteId, adv.StartLocation, adv.EndLocation)
-		}
-	} else {
-		query := "INSERT INTO Adventure VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
-
-		if tx != nil {
-			_, err = tx.Exec(query, adv.AthleteId, adv.StartLocation, adv.EndLocation, adv.CurrentLocationLat, adv.CurrentLocationLon, adv.CurrentLocationIndexOnRoute, adv.CurrentLocationName, adv.CurrentDistance, adv.TotalDistance, adv.Completed, adv.StartDate, adv.EndDate)
-		} else {
-			_, err = db.Exec(query, adv.AthleteId, adv.StartLocation, adv.EndLocation, adv.CurrentLocationLat, adv.CurrentLocationLon, adv.CurrentLocationIndexOnRoute, adv.CurrentLocationName, adv.CurrentDistance, adv.TotalDistance, adv.Completed, adv.StartDate, adv.EndDate)
-		}
-	}
+	return adventureRepo.update(dbtx(db, tx), adv)
+}
 
-	return err
+func (adv *Adventure) Delete(db *sql.DB, tx *sql.Tx) error {
+	return adventureRepo.delete(dbtx(db, tx), adv)
 }
 
 func AdventureExists(athlId int64, startLocation int, endLocation int, db *sql.DB, tx *sql.Tx) (bool, error) {
-	var temp Adventure
-
-	return temp.Load(athlId, startLocation, endLocation, db, tx)
+	return adventureRepo.exists(dbtx(db, tx), athlId, startLocation, endLocation)
 }
 
 func AllAdventures(db *sql.DB, tx *sql.Tx, filter map[string]any) ([]Adventure, error) {
-	var err error
-
-	var rows *sql.Rows
-	query, params := PrepareQuery("SELECT * FROM Adventure", filter)
-	if tx != nil {
-		rows, err = tx.Query(query, params...)
-	} else {
-		rows, err = db.Query(query, params...)
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	defer rows.Close()
-
-	var adventures []Adventure
-	for rows.Next() {
-		adventures = append(adventures, Adventure{})
-
-		adventureToEdit := &adventures[len(adventures)-1]
-		if err = rows.Scan(&adventureToEdit.AthleteId, &adventureToEdit.StartLocation, &adventureToEdit.EndLocation,
-			&adventureToEdit.CurrentLocationLat, &adventureToEdit.CurrentLocationLon, &adventureToEdit.CurrentLocationIndexOnRoute,
-			&adventureToEdit.CurrentLocationName, &adventureToEdit.CurrentDistance, &adventureToEdit.TotalDistance,
-			&adventureToEdit.Completed, &adventureToEdit.StartDate, &adventureToEdit.EndDate); err != nil {
-			return nil, err
-		}
-	}
+	return adventureRepo.all(dbtx(db, tx), filter, QueryOptions{})
+}
 
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
+// AllAdventuresByStartDate returns a page of adventures matching the filter, the most recently started first.
+func AllAdventuresByStartDate(filter map[string]any, limit int, offset int, db *sql.DB, tx *sql.Tx) ([]Adventure, error) {
+	return adventureRepo.all(dbtx(db, tx), filter, QueryOptions{OrderBy: "start_date DESC", Limit: limit, Offset: offset})
+}
 
-	return adventures, nil
+func CountAdventures(db *sql.DB, tx *sql.Tx, filter map[string]any) (int, error) {
+	return adventureRepo.count(dbtx(db, tx), filter)
 }
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"database/sql"
+)
+
+// AdventureMapImage is a rendered map of an adventure, served under an unguessable image id. The id is rotated when the
+// public link of the adventure is rotated or revoked.
+// Progress describes the state of the adventure at the time the image was rendered, so that the image is rendered again
+// only when the progress changes.
+type AdventureMapImage struct {
+	AthleteId     int64  `db:"athlete_id,pk"`
+	StartLocation int    `db:"start_location,pk"`
+	EndLocation   int    `db:"end_location,pk"`
+	ImageId       string `db:"image_id"`
+	Progress      string `db:"progress"`
+	RenderedAt    int    `db:"rendered_at"`
+}
+
+var adventureMapImageRepo = newRepository[AdventureMapImage]("AdventureMapImage")
+
+func (mapImage *AdventureMapImage) Load(athlId int64, startLocation int, endLocation int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return adventureMapImageRepo.load(dbtx(db, tx), mapImage, athlId, startLocation, endLocation)
+}
+
+func (mapImage *AdventureMapImage) Save(db *sql.DB, tx *sql.Tx) error {
+	return adventureMapImageRepo.save(dbtx(db, tx), mapImage)
+}
+
+// SaveRendering saves the progress and the time of the rendering, only if the image id wasn't rotated meanwhile. It
+// returns false if it was.
+func (mapImage *AdventureMapImage) SaveRendering(db *sql.DB, tx *sql.Tx) (bool, error) {
+	result, err := dbtx(db, tx).Exec("UPDATE AdventureMapImage SET progress=?, rendered_at=? WHERE athlete_id=? AND start_location=? AND end_location=? AND image_id=?",
+		mapImage.Progress, mapImage.RenderedAt, mapImage.AthleteId, mapImage.StartLocation, mapImage.EndLocation, mapImage.ImageId)
+	if err != nil {
+		return false, err
+	}
+
+	updated, err := result.RowsAffected()
+
+	return updated > 0, err
+}
+
+func (mapImage *AdventureMapImage) Delete(db *sql.DB, tx *sql.Tx) error {
+	return adventureMapImageRepo.delete(dbtx(db, tx), mapImage)
+}
+
+func AdventureMapImageExists(athlId int64, startLocation int, endLocation int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return adventureMapImageRepo.exists(dbtx(db, tx), athlId, startLocation, endLocation)
+}
+
+func AllAdventureMapImages(db *sql.DB, tx *sql.Tx, filter map[string]any) ([]AdventureMapImage, error) {
+	return adventureMapImageRepo.all(dbtx(db, tx), filter, QueryOptions{})
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"database/sql"
+)
+
+// AdventurePublicLink makes an adventure visible to anyone who knows its unguessable slug.
+type AdventurePublicLink struct {
+	AthleteId     int64  `db:"athlete_id,pk"`
+	StartLocation int    `db:"start_location,pk"`
+	EndLocation   int    `db:"end_location,pk"`
+	Slug          string `db:"slug"`
+	CreatedAt     int    `db:"created_at"`
+}
+
+var adventurePublicLinkRepo = newRepository[AdventurePublicLink]("AdventurePublicLink")
+
+func (publicLink *AdventurePublicLink) Load(athlId int64, startLocation int, endLocation int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return adventurePublicLinkRepo.load(dbtx(db, tx), publicLink, athlId, startLocation, endLocation)
+}
+
+func (publicLink *AdventurePublicLink) Save(db *sql.DB, tx *sql.Tx) error {
+	return adventurePublicLinkRepo.save(dbtx(db, tx), publicLink)
+}
+
+func (publicLink *AdventurePublicLink) Delete(db *sql.DB, tx *sql.Tx) error {
+	return adventurePublicLinkRepo.delete(dbtx(db, tx), publicLink)
+}
+
+func AdventurePublicLinkExists(athlId int64, startLocation int, endLocation int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return adventurePublicLinkRepo.exists(dbtx(db, tx), athlId, startLocation, endLocation)
+}
+
+func AllAdventurePublicLinks(db *sql.DB, tx *sql.Tx, filter map[string]any) ([]AdventurePublicLink, error) {
+	return adventurePublicLinkRepo.all(dbtx(db, tx), filter, QueryOptions{})
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"database/sql"
+)
+
+// ApiToken lets scripts and integrations use the JSON API on behalf of an athlete. Only the hash of the token is stored.
+type ApiToken struct {
+	Id         int    `db:"id,pk,auto"`
+	AthleteId  int64  `db:"athlete_id"`
+	Name       string `db:"name"`
+	TokenHash  string `db:"token_hash"`
+	Scopes     string `db:"scopes"` // comma separated
+	CreatedAt  int    `db:"created_at"`
+	LastUsedAt int    `db:"last_used_at"` // 0 if never used
+	ExpiresAt  int    `db:"expires_at"`   // 0 if it never expires
+}
+
+var apiTokenRepo = newRepository[ApiToken]("ApiToken")
+
+func (token *ApiToken) Load(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return apiTokenRepo.load(dbtx(db, tx), token, id)
+}
+
+// Save inserts the token if its id is 0 (populating the id), otherwise it updates the one with the given id.
+func (token *ApiToken) Save(db *sql.DB, tx *sql.Tx) error {
+	return apiTokenRepo.save(dbtx(db, tx), token)
+}
+
+func (token *ApiToken) Delete(db *sql.DB, tx *sql.Tx) error {
+	return apiTokenRepo.delete(dbtx(db, tx), token)
+}
+
+func ApiTokenExists(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return apiTokenRepo.exists(dbtx(db, tx), id)
+}
+
+func AllApiTokens(db *sql.DB, tx *sql.Tx, filter map[string]any) ([]ApiToken, error) {
+	return apiTokenRepo.all(dbtx(db, tx), filter, QueryOptions{OrderBy: "created_at DESC"})
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"database/sql"
+	"slices"
+)
+
+// CoursePlace is a town or a point of interest along a course. Km is measured from the start of the course,
+// in the direction the course is stored in (from the location with the lower id to the one with the higher id).
+type CoursePlace struct {
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+	Km   float64 `json:"km"`
+}
+
+// CoursePlaces is an ordered list of places along a course, kept in the file database under "courseplaces/<course>".
+type CoursePlaces struct {
+	Source         string        `json:"source"` // "geocoder" or "import"
+	CourseLengthKm float64       `json:"course_length_km"`
+	Places         []CoursePlace `json:"places"`
+}
+
+// InDirection returns places ordered by their kilometre marks, measured from the start of the adventure.
+func (coursePlaces *CoursePlaces) InDirection(reverse bool) []CoursePlace {
+	places := slices.Clone(coursePlaces.Places)
+
+	if reverse {
+		for i := range places {
+			places[i].Km = max(coursePlaces.CourseLengthKm-places[i].Km, 0)
+		}
+	}
+
+	slices.SortStableFunc(places, func(a, b CoursePlace) int {
+		if a.Km < b.Km {
+			return -1
+		} else if a.Km > b.Km {
+			return 1
+		}
+
+		return 0
+	})
+
+	return places
+}
+
+// AdventurePassedPlace records when an adventure passed a place along its course, and which activity took it there.
+// Km is measured from the start of the adventure.
+type AdventurePassedPlace struct {
+	AthleteId     int64   `db:"athlete_id,pk"`
+	StartLocation int     `db:"start_location,pk"`
+	EndLocation   int     `db:"end_location,pk"`
+	Km            float64 `db:"km,pk"`
+	Name          string  `db:"name"`
+	ActivityId    int64   `db:"activity_id"`
+	PassedDate    int     `db:"passed_date"`
+}
+
+var adventurePassedPlaceRepo = newRepository[AdventurePassedPlace]("AdventurePassedPlace")
+
+func (passedPlace *AdventurePassedPlace) Load(athlId int64, startLocation int, endLocation int, km float64, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return adventurePassedPlaceRepo.load(dbtx(db, tx), passedPlace, athlId, startLocation, endLocation, km)
+}
+
+func (passedPlace *AdventurePassedPlace) Save(db *sql.DB, tx *sql.Tx) error {
+	return adventurePassedPlaceRepo.save(dbtx(db, tx), passedPlace)
+}
+
+func (passedPlace *AdventurePassedPlace) Delete(db *sql.DB, tx *sql.Tx) error {
+	return adventurePassedPlaceRepo.delete(dbtx(db, tx), passedPlace)
+}
+
+func AdventurePassedPlaceExists(athlId int64, startLocation int, endLocation int, km float64, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return adventurePassedPlaceRepo.exists(dbtx(db, tx), athlId, startLocation, endLocation, km)
+}
+
+// AllAdventurePassedPlaces returns passed places ordered by their kilometre marks.
+func AllAdventurePassedPlaces(db *sql.DB, tx *sql.Tx, filter map[string]any) ([]AdventurePassedPlace, error) {
+	return adventurePassedPlaceRepo.all(dbtx(db, tx), filter, QueryOptions{OrderBy: "km"})
+}
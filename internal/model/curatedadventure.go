@@ -0,0 +1,43 @@
+package model
+
+import (
+	"database/sql"
+)
+
+// CuratedAdventure is a named route between two public locations, prepared by an admin for the adventure catalogue.
+type CuratedAdventure struct {
+	Id            int     `db:"id,pk,auto"`
+	Title         string  `db:"title"`
+	Description   string  `db:"description"`
+	Difficulty    string  `db:"difficulty"`
+	StartLocation int     `db:"start_location"`
+	EndLocation   int     `db:"end_location"`
+	Distance      float32 `db:"distance"` // in km
+	CoverImageUrl string  `db:"cover_image_url"`
+	Tags          string  `db:"tags"` // comma separated
+	Mode          string  `db:"mode"`
+	CreatedAt     int     `db:"created_at"`
+}
+
+var curatedAdventureRepo = newRepository[CuratedAdventure]("CuratedAdventure")
+
+func (curated *CuratedAdventure) Load(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return curatedAdventureRepo.load(dbtx(db, tx), curated, id)
+}
+
+// Save inserts the curated adventure if its id is 0 (populating the id), otherwise it updates the one with the given id.
+func (curated *CuratedAdventure) Save(db *sql.DB, tx *sql.Tx) error {
+	return curatedAdventureRepo.save(dbtx(db, tx), curated)
+}
+
+func (curated *CuratedAdventure) Delete(db *sql.DB, tx *sql.Tx) error {
+	return curatedAdventureRepo.delete(dbtx(db, tx), curated)
+}
+
+func CuratedAdventureExists(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return curatedAdventureRepo.exists(dbtx(db, tx), id)
+}
+
+func AllCuratedAdventures(db *sql.DB, tx *sql.Tx, filter map[string]any) ([]CuratedAdventure, error) {
+	return curatedAdventureRepo.all(dbtx(db, tx), filter, QueryOptions{OrderBy: "title"})
+}
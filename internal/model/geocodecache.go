@@ -0,0 +1,34 @@
+package model
+
+import (
+	"database/sql"
+)
+
+// GeocodeCacheEntry is a location name found by reverse geocoding, keyed by rounded coordinates.
+type GeocodeCacheEntry struct {
+	Key       string `db:"key,pk"`
+	Name      string `db:"name"`
+	CreatedAt int    `db:"created_at"`
+}
+
+var geocodeCacheRepo = newRepository[GeocodeCacheEntry]("GeocodeCache")
+
+func (entry *GeocodeCacheEntry) Load(key string, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return geocodeCacheRepo.load(dbtx(db, tx), entry, key)
+}
+
+func (entry *GeocodeCacheEntry) Save(db *sql.DB, tx *sql.Tx) error {
+	return geocodeCacheRepo.save(dbtx(db, tx), entry)
+}
+
+func (entry *GeocodeCacheEntry) Delete(db *sql.DB, tx *sql.Tx) error {
+	return geocodeCacheRepo.delete(dbtx(db, tx), entry)
+}
+
+func GeocodeCacheEntryExists(key string, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return geocodeCacheRepo.exists(dbtx(db, tx), key)
+}
+
+func AllGeocodeCacheEntries(db *sql.DB, tx *sql.Tx, filter map[string]any) ([]GeocodeCacheEntry, error) {
+	return geocodeCacheRepo.all(dbtx(db, tx), filter, QueryOptions{})
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"database/sql"
+)
+
+// ImportedRoute is a course uploaded by an admin (e.g. a famous trail from a GPX file).
+// Its geometry is kept in the file database, under "importedroute/<id>".
+type ImportedRoute struct {
+	Id            int     `db:"id,pk,auto"`
+	Name          string  `db:"name"`
+	StartLocation int     `db:"start_location"`
+	EndLocation   int     `db:"end_location"`
+	Distance      float32 `db:"distance"`
+	CreatedAt     int     `db:"created_at"`
+}
+
+var importedRouteRepo = newRepository[ImportedRoute]("ImportedRoute")
+
+func (route *ImportedRoute) Load(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return importedRouteRepo.load(dbtx(db, tx), route, id)
+}
+
+// Save inserts the route if its id is 0 (populating the id), otherwise it updates the one with the given id.
+func (route *ImportedRoute) Save(db *sql.DB, tx *sql.Tx) error {
+	return importedRouteRepo.save(dbtx(db, tx), route)
+}
+
+func (route *ImportedRoute) Delete(db *sql.DB, tx *sql.Tx) error {
+	return importedRouteRepo.delete(dbtx(db, tx), route)
+}
+
+func ImportedRouteExists(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return importedRouteRepo.exists(dbtx(db, tx), id)
+}
+
+func AllImportedRoutes(db *sql.DB, tx *sql.Tx, filter map[string]any) ([]ImportedRoute, error) {
+	return importedRouteRepo.all(dbtx(db, tx), filter, QueryOptions{})
+}
+
+// AllImportedRoutesBetween returns routes from a location at the start coordinates to a location at the end coordinates,
+// in the order they were imported. Coordinates of the locations are matched within the tolerance (in degrees).
+func AllImportedRoutesBetween(latStart, lonStart, latEnd, lonEnd, tolerance float64, db *sql.DB, tx *sql.Tx) ([]ImportedRoute, error) {
+	locationNear := "SELECT id FROM Location WHERE lat BETWEEN ? AND ? AND lon BETWEEN ? AND ?"
+
+	return importedRouteRepo.where(dbtx(db, tx), "start_location IN ("+locationNear+") AND end_location IN ("+locationNear+")", []any{
+		latStart - tolerance, latStart + tolerance, lonStart - tolerance, lonStart + tolerance,
+		latEnd - tolerance, latEnd + tolerance, lonEnd - tolerance, lonEnd + tolerance,
+	}, QueryOptions{OrderBy: "id"})
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"database/sql"
+)
+
+// InstanceHeartbeat shows that an instance of the application is alive, it's updated periodically while it runs.
+type InstanceHeartbeat struct {
+	Instance    string `db:"instance,pk"`
+	Hostname    string `db:"hostname"`
+	StartedAt   int    `db:"started_at"`
+	HeartbeatAt int    `db:"heartbeat_at"`
+}
+
+var instanceHeartbeatRepo = newRepository[InstanceHeartbeat]("InstanceHeartbeat")
+
+func (heartbeat *InstanceHeartbeat) Load(instance string, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return instanceHeartbeatRepo.load(dbtx(db, tx), heartbeat, instance)
+}
+
+func (heartbeat *InstanceHeartbeat) Save(db *sql.DB, tx *sql.Tx) error {
+	return instanceHeartbeatRepo.save(dbtx(db, tx), heartbeat)
+}
+
+func (heartbeat *InstanceHeartbeat) Delete(db *sql.DB, tx *sql.Tx) error {
+	return instanceHeartbeatRepo.delete(dbtx(db, tx), heartbeat)
+}
+
+func InstanceHeartbeatExists(instance string, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return instanceHeartbeatRepo.exists(dbtx(db, tx), instance)
+}
+
+func AllInstanceHeartbeats(db *sql.DB, tx *sql.Tx, filter map[string]any) ([]InstanceHeartbeat, error) {
+	return instanceHeartbeatRepo.all(dbtx(db, tx), filter, QueryOptions{})
+}
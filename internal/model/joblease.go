@@ -0,0 +1,81 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// JobLease gives one instance of the application the right to run a scheduled job, when several instances share the
+// database. The holder renews the lease while it's alive, otherwise it expires and another instance takes it over.
+type JobLease struct {
+	Job        string `db:"job,pk"`
+	Holder     string `db:"holder"` // the instance holding the lease
+	AcquiredAt int    `db:"acquired_at"`
+	ExpiresAt  int    `db:"expires_at"`
+	Generation int    `db:"generation"` // incremented on every takeover, it fences off runs started by previous holders
+}
+
+var jobLeaseRepo = newRepository[JobLease]("JobLease")
+
+func (lease *JobLease) Load(job string, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return jobLeaseRepo.load(dbtx(db, tx), lease, job)
+}
+
+// Delete releases the lease, only if it's still held by its holder.
+func (lease *JobLease) Delete(db *sql.DB, tx *sql.Tx) error {
+	_, err := jobLeaseRepo.deleteAll(dbtx(db, tx), map[string]any{"job": lease.Job, "holder": lease.Holder})
+
+	return err
+}
+
+func JobLeaseExists(job string, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return jobLeaseRepo.exists(dbtx(db, tx), job)
+}
+
+// AcquireJobLease takes the lease of the job for the holder until expiresAt, if it's free, expired or already held by the
+// holder (then it's just renewed). It returns the generation of the lease, which is incremented if the lease is taken over
+// from another holder, or 0 if another holder has it. Both statements are conditional, so two instances can't take the
+// same lease, even without a transaction.
+func AcquireJobLease(job, holder string, now, expiresAt int, db *sql.DB, tx *sql.Tx) (int, error) {
+	conn := dbtx(db, tx)
+
+	var generation int
+	err := conn.QueryRow("UPDATE JobLease SET acquired_at=CASE WHEN holder=? THEN acquired_at ELSE ? END, generation=CASE WHEN holder=? THEN generation ELSE generation+1 END, holder=?, expires_at=? WHERE job=? AND (holder=? OR expires_at<=?) RETURNING generation",
+		holder, now, holder, holder, expiresAt, job, holder, now).Scan(&generation)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return generation, err
+	}
+
+	// a released lease starts over, runs of its previous holders are still fenced off by the holder
+	err = conn.QueryRow("INSERT INTO JobLease(job, holder, acquired_at, expires_at, generation) VALUES(?, ?, ?, ?, 1) ON CONFLICT(job) DO NOTHING RETURNING generation",
+		job, holder, now, expiresAt).Scan(&generation)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+
+	return generation, err
+}
+
+// RenewJobLease extends the lease until expiresAt, only if the holder still holds it with the given generation. It returns
+// false if the lease was taken over (or released) meanwhile.
+func RenewJobLease(job, holder string, generation, expiresAt int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	result, err := dbtx(db, tx).Exec("UPDATE JobLease SET expires_at=? WHERE job=? AND holder=? AND generation=?", expiresAt, job, holder, generation)
+	if err != nil {
+		return false, err
+	}
+
+	updated, err := result.RowsAffected()
+
+	return updated > 0, err
+}
+
+// RenewJobLeases extends all leases held by the holder until expiresAt.
+func RenewJobLeases(holder string, expiresAt int, db *sql.DB, tx *sql.Tx) error {
+	_, err := dbtx(db, tx).Exec("UPDATE JobLease SET expires_at=? WHERE holder=?", expiresAt, holder)
+
+	return err
+}
+
+func AllJobLeases(db *sql.DB, tx *sql.Tx, filter map[string]any) ([]JobLease, error) {
+	return jobLeaseRepo.all(dbtx(db, tx), filter, QueryOptions{})
+}
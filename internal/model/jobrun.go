@@ -0,0 +1,55 @@
+package model
+
+import (
+	"database/sql"
+)
+
+const (
+	JobRunRunning   = "running"
+	JobRunSucceeded = "succeeded"
+	JobRunFailed    = "failed"
+	JobRunSkipped   = "skipped"   // the previous run of the job was still in progress
+	JobRunCancelled = "cancelled" // the application was shutting down, or the lease of the job was lost
+)
+
+const (
+	JobRunTriggerSchedule = "schedule"
+	JobRunTriggerManual   = "manual" // run now, by an admin
+)
+
+// JobRun is one run of a scheduled job.
+type JobRun struct {
+	Id          int    `db:"id,pk,auto"`
+	Job         string `db:"job"`
+	TriggeredBy string `db:"triggered_by"`
+	Status      string `db:"status"`
+	StartedAt   int    `db:"started_at"`
+	FinishedAt  int    `db:"finished_at"`
+	DurationMs  int    `db:"duration_ms"`
+	Error       string `db:"error"`
+	Instance    string `db:"instance"` // the instance of the application that ran the job
+}
+
+var jobRunRepo = newRepository[JobRun]("JobRun")
+
+func (run *JobRun) Load(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return jobRunRepo.load(dbtx(db, tx), run, id)
+}
+
+// Save inserts the run if its id is 0 (populating the id), otherwise it updates the one with the given id.
+func (run *JobRun) Save(db *sql.DB, tx *sql.Tx) error {
+	return jobRunRepo.save(dbtx(db, tx), run)
+}
+
+func (run *JobRun) Delete(db *sql.DB, tx *sql.Tx) error {
+	return jobRunRepo.delete(dbtx(db, tx), run)
+}
+
+func JobRunExists(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return jobRunRepo.exists(dbtx(db, tx), id)
+}
+
+// AllJobRuns returns runs matching the filter, the latest first.
+func AllJobRuns(db *sql.DB, tx *sql.Tx, filter map[string]any) ([]JobRun, error) {
+	return jobRunRepo.all(dbtx(db, tx), filter, QueryOptions{OrderBy: "id DESC"})
+}
@@ -2,75 +2,63 @@ package model
 
 import (
 	"database/sql"
-	"errors"
 )
 
 type Location struct {
-	Id   int
-	Lat  float64
-	Lon  float64
-	Name string
+	Id          int     `db:"id,pk,auto"`
+	Lat         float64 `db:"lat"`
+	Lon         float64 `db:"lon"`
+	Name        string  `db:"name"`
+	Country     string  `db:"country"`
+	Description string  `db:"description"`
+	ImageUrl    string  `db:"image_url"`
+	Category    string  `db:"category"`
+	OwnerId     int64   `db:"owner_id"` // 0 for locations visible to everyone, otherwise id of the athlete who created the private location
 }
 
-func (location *Location) Load(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
-	var err error
-
-	query, params := PrepareQuery("SELECT * FROM Location", map[string]any{"id": id})
+var locationRepo = newRepository[Location]("Location")
 
-	var row *sql.Row
-	if tx != nil {
-		row = tx.QueryRow(query, params...)
-	} else {
-		row = db.QueryRow(query, params...)
-	}
-
-	err = row.Scan(&location.Id, &location.Lat, &location.Lon, &location.Name)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			err = nil
-		}
+func (location *Location) Load(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return locationRepo.load(dbtx(db, tx), location, id)
+}
 
-		return false, err
-	}
+// Save inserts the location if its id is 0 (populating the id), otherwise it updates the one with the given id.
+func (location *Location) Save(db *sql.DB, tx *sql.Tx) error {
+	return locationRepo.save(dbtx(db, tx), location)
+}
 
-	return true, nil
+func (location *Location) Delete(db *sql.DB, tx *sql.Tx) error {
+	return locationRepo.delete(dbtx(db, tx), location)
 }
 
 func LocationExists(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
-	var temp Location
-
-	return temp.Load(id, db, tx)
+	return locationRepo.exists(dbtx(db, tx), id)
 }
 
-func AllLocations(db *sql.DB, tx *sql.Tx, filter map[string]any) ([]Location, error) {
-	var err error
+// LocationInUse checks if the location is the start or the end of any adventure (ongoing or completed), imported route
+// or curated adventure.
+func LocationInUse(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM Adventure WHERE start_location=? OR end_location=?)
+		OR EXISTS(SELECT 1 FROM ImportedRoute WHERE start_location=? OR end_location=?)
+		OR EXISTS(SELECT 1 FROM CuratedAdventure WHERE start_location=? OR end_location=?)`
 
-	var rows *sql.Rows
-	query, params := PrepareQuery("SELECT * FROM Location", filter)
-	if tx != nil {
-		rows, err = tx.Query(query, params...)
-	} else {
-		rows, err = db.Query(query, params...)
-	}
-	if err != nil {
-		return nil, err
+	var inUse bool
+	if err := dbtx(db, tx).QueryRow(query, id, id, id, id, id, id).Scan(&inUse); err != nil {
+		return false, err
 	}
 
-	defer rows.Close()
-
-	var locations []Location
-	for rows.Next() {
-		locations = append(locations, Location{})
+	return inUse, nil
+}
 
-		locationToEdit := &locations[len(locations)-1]
-		if err = rows.Scan(&locationToEdit.Id, &locationToEdit.Lat, &locationToEdit.Lon, &locationToEdit.Name); err != nil {
-			return nil, err
-		}
-	}
+func AllLocations(db *sql.DB, tx *sql.Tx, filter map[string]any) ([]Location, error) {
+	return locationRepo.all(dbtx(db, tx), filter, QueryOptions{})
+}
 
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
+// AllLocationsVisibleTo returns a page of locations visible to everyone, followed by private locations of the athlete.
+func AllLocationsVisibleTo(athleteId int64, limit int, offset int, db *sql.DB, tx *sql.Tx) ([]Location, error) {
+	return locationRepo.where(dbtx(db, tx), "owner_id IN (0, ?)", []any{athleteId}, QueryOptions{OrderBy: "owner_id, id", Limit: limit, Offset: offset})
+}
 
-	return locations, nil
+func CountLocationsVisibleTo(athleteId int64, db *sql.DB, tx *sql.Tx) (int, error) {
+	return locationRepo.countWhere(dbtx(db, tx), "owner_id IN (0, ?)", []any{athleteId})
 }
@@ -6,8 +6,11 @@ package model
 // 3. All functions should take db and tx as parameters
 // 4. Don't rollback in functions, let the caller handle it
 // 5. All returns list of objects, not pointers to objects
-// 6. Save is not atomic unless tx is provided. If row is updates, pk should not be changed
+// 6. Save inserts rows with a zero auto id, and updates other rows, without inserting them again if they were deleted
+//    meanwhile (ErrRowNotFound). Rows with natural keys are upserted, unless they are created by Insert (e.g. Adventure).
+//    If row is updated, pk should not be changed
 // 7. Load loads by primary key, returns true if found, false if not found
+// 8. Columns are mapped by db struct tags, queries are built by the table's repository (see repository.go)
 
 type ComparationOperation struct {
 	FieldValue any
@@ -27,3 +27,15 @@ func (feature *ReverseGeocodeFeature) FromExternalModel(externalFeature *externa
 func NewReverseGeocodeFeature() *ReverseGeocodeFeature {
 	return &ReverseGeocodeFeature{ReverseGeocodeFeature: &externalmodel.ReverseGeocodeFeature{}}
 }
+
+type GeocodeFeature struct {
+	*externalmodel.GeocodeFeature
+}
+
+func (feature *GeocodeFeature) FromExternalModel(externalFeature *externalmodel.GeocodeFeature) {
+	feature.GeocodeFeature = externalFeature
+}
+
+func NewGeocodeFeature() *GeocodeFeature {
+	return &GeocodeFeature{GeocodeFeature: &externalmodel.GeocodeFeature{}}
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"database/sql"
+)
+
+const (
+	OutboxMessagePending = "pending"
+	OutboxMessageDone    = "done"
+	OutboxMessageFailed  = "failed" // given up after too many attempts
+)
+
+// OutboxMessage is a side effect (e.g. a Strava activity description update) queued in the transaction which causes it,
+// and performed by a scheduled job after the transaction is committed.
+type OutboxMessage struct {
+	Id            int    `db:"id,pk,auto"`
+	Kind          string `db:"kind"`
+	Payload       string `db:"payload"` // json, depends on the kind
+	Status        string `db:"status"`
+	Attempts      int    `db:"attempts"`
+	NextAttemptAt int    `db:"next_attempt_at"`
+	LastAttemptAt int    `db:"last_attempt_at"`
+	LastError     string `db:"last_error"` // of the last attempt
+	CreatedAt     int    `db:"created_at"`
+}
+
+var outboxMessageRepo = newRepository[OutboxMessage]("OutboxMessage")
+
+func (message *OutboxMessage) Load(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return outboxMessageRepo.load(dbtx(db, tx), message, id)
+}
+
+// Save inserts the message if its id is 0 (populating the id), otherwise it updates the one with the given id.
+func (message *OutboxMessage) Save(db *sql.DB, tx *sql.Tx) error {
+	return outboxMessageRepo.save(dbtx(db, tx), message)
+}
+
+func (message *OutboxMessage) Delete(db *sql.DB, tx *sql.Tx) error {
+	return outboxMessageRepo.delete(dbtx(db, tx), message)
+}
+
+func OutboxMessageExists(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return outboxMessageRepo.exists(dbtx(db, tx), id)
+}
+
+// AllOutboxMessages returns messages matching the filter, in the order they were queued.
+func AllOutboxMessages(db *sql.DB, tx *sql.Tx, filter map[string]any) ([]OutboxMessage, error) {
+	return outboxMessageRepo.all(dbtx(db, tx), filter, QueryOptions{OrderBy: "id"})
+}
+
+// DueOutboxMessages returns at most limit pending messages whose next attempt is due at now, in the order they were queued.
+func DueOutboxMessages(now int, limit int, db *sql.DB, tx *sql.Tx) ([]OutboxMessage, error) {
+	return outboxMessageRepo.all(dbtx(db, tx), map[string]any{
+		"status":          OutboxMessagePending,
+		"next_attempt_at": ComparationOperation{FieldValue: now, Operation: "<="},
+	}, QueryOptions{OrderBy: "id", Limit: limit})
+}
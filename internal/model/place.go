@@ -0,0 +1,44 @@
+package model
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// Place is an entry of the local gazetteer (imported from a GeoNames or an OSM place dump).
+type Place struct {
+	Id         int64   `db:"id,pk,auto"`
+	Name       string  `db:"name"`
+	Country    string  `db:"country"`
+	Lat        float64 `db:"lat"`
+	Lon        float64 `db:"lon"`
+	Population int64   `db:"population"`
+}
+
+var placeRepo = newRepository[Place]("Place")
+
+func (place *Place) Load(id int64, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return placeRepo.load(dbtx(db, tx), place, id)
+}
+
+// Save inserts the place if its id is 0 (populating the id), otherwise it updates the one with the given id.
+func (place *Place) Save(db *sql.DB, tx *sql.Tx) error {
+	return placeRepo.save(dbtx(db, tx), place)
+}
+
+func PlaceExists(id int64, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return placeRepo.exists(dbtx(db, tx), id)
+}
+
+// AllPlacesInBoundingBox returns all places with coordinates inside of the given box (inclusive).
+func AllPlacesInBoundingBox(minLat, maxLat, minLon, maxLon float64, db *sql.DB, tx *sql.Tx) ([]Place, error) {
+	return placeRepo.where(dbtx(db, tx), "lat BETWEEN ? AND ? AND lon BETWEEN ? AND ?", []any{minLat, maxLat, minLon, maxLon}, QueryOptions{})
+}
+
+// AllPlacesByNamePrefix returns at most limit places whose name starts with the given prefix (case insensitive),
+// the most populated ones first.
+func AllPlacesByNamePrefix(prefix string, limit int, db *sql.DB, tx *sql.Tx) ([]Place, error) {
+	pattern := strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(prefix) + "%"
+
+	return placeRepo.where(dbtx(db, tx), "LOWER(name) LIKE LOWER(?) ESCAPE '\\'", []any{pattern}, QueryOptions{OrderBy: "population DESC", Limit: limit})
+}
@@ -0,0 +1,365 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// ErrRowNotFound is returned when a row is updated, but it doesn't exist (anymore), e.g. it was deleted after it was
+// loaded. Such rows are not inserted again.
+var ErrRowNotFound = errors.New("row not found")
+
+// DBTX is implemented by both *sql.DB and *sql.Tx, so that the same queries run in and out of transactions.
+type DBTX interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// dbtx returns the transaction if there is one, the database otherwise.
+func dbtx(db *sql.DB, tx *sql.Tx) DBTX {
+	if tx != nil {
+		return tx
+	}
+
+	return db
+}
+
+// QueryOptions orders and pages rows returned by All* functions.
+type QueryOptions struct {
+	OrderBy string // e.g. "id DESC", rows come in no particular order if empty
+	Limit   int    // 0 for all rows
+	Offset  int
+}
+
+func (options QueryOptions) apply(query string, params []any) (string, []any) {
+	if options.OrderBy != "" {
+		query += " ORDER BY " + options.OrderBy
+	}
+
+	// SQLite doesn't allow OFFSET without LIMIT
+	if options.Limit > 0 || options.Offset > 0 {
+		limit := options.Limit
+		if limit <= 0 {
+			limit = math.MaxInt64
+		}
+
+		query += " LIMIT ?"
+		params = append(params, limit)
+	}
+
+	if options.Offset > 0 {
+		query += " OFFSET ?"
+		params = append(params, options.Offset)
+	}
+
+	return query, params
+}
+
+// repository loads, saves and deletes rows of a table as structs of type T. Columns are the fields of T tagged with
+// db:"<column>[,pk][,auto]", where pk marks (parts of) the primary key, in the order of the key, and auto marks an id
+// generated by the database: rows with a zero id are inserted and get their id, other rows are updated.
+type repository[T any] struct {
+	table   string
+	columns []repositoryColumn
+	auto    bool // the table has an auto id
+
+	selectQuery string // SELECT <all columns> FROM <table>
+	keyQuery    string // WHERE <primary key columns>
+	insertQuery string // insert of all columns but auto ones, returning them
+	updateQuery string // update of all columns but the primary key, of the row with the primary key
+	upsertQuery string // insert of all columns, or update of the row with the same primary key
+}
+
+type repositoryColumn struct {
+	name  string
+	field int
+	pk    bool
+	auto  bool
+}
+
+// newRepository builds the queries of the table from the tags of T, it panics if T isn't tagged properly, so mistakes
+// are found on startup.
+func newRepository[T any](table string) *repository[T] {
+	repo := &repository[T]{table: table}
+
+	structType := reflect.TypeFor[T]()
+	for i := 0; i < structType.NumField(); i++ {
+		tag, found := structType.Field(i).Tag.Lookup("db")
+		if !found {
+			continue
+		}
+
+		options := strings.Split(tag, ",")
+
+		column := repositoryColumn{name: options[0], field: i}
+		for _, option := range options[1:] {
+			switch option {
+			case "pk":
+				column.pk = true
+			case "auto":
+				column.auto = true
+			default:
+				panic(fmt.Errorf("%s.%s: unknown db tag option %s", structType.Name(), structType.Field(i).Name, option))
+			}
+		}
+
+		repo.columns = append(repo.columns, column)
+	}
+
+	var allColumns, keyColumns, insertColumns, autoColumns, updateColumns, upserts []string
+	for _, column := range repo.columns {
+		allColumns = append(allColumns, column.name)
+
+		if column.pk {
+			keyColumns = append(keyColumns, column.name)
+		} else {
+			updateColumns = append(updateColumns, column.name)
+			upserts = append(upserts, column.name+"=excluded."+column.name)
+		}
+
+		if column.auto {
+			repo.auto = true
+			autoColumns = append(autoColumns, column.name)
+		} else {
+			insertColumns = append(insertColumns, column.name)
+		}
+	}
+
+	if len(keyColumns) == 0 {
+		panic(fmt.Errorf("%s: no primary key columns", structType.Name()))
+	}
+
+	repo.selectQuery = "SELECT " + strings.Join(allColumns, ", ") + " FROM " + table
+	repo.keyQuery = " WHERE " + strings.Join(keyColumns, "=? AND ") + "=?"
+
+	repo.insertQuery = "INSERT INTO " + table + "(" + strings.Join(insertColumns, ", ") + ") VALUES(" + placeholders(len(insertColumns)) + ")"
+	if len(autoColumns) > 0 {
+		repo.insertQuery += " RETURNING " + strings.Join(autoColumns, ", ")
+	}
+
+	if len(updateColumns) > 0 {
+		repo.updateQuery = "UPDATE " + table + " SET " + strings.Join(updateColumns, "=?, ") + "=?" + repo.keyQuery
+	}
+
+	repo.upsertQuery = "INSERT INTO " + table + "(" + strings.Join(allColumns, ", ") + ") VALUES(" + placeholders(len(allColumns)) + ")" +
+		" ON CONFLICT(" + strings.Join(keyColumns, ", ") + ")"
+	if len(upserts) > 0 {
+		repo.upsertQuery += " DO UPDATE SET " + strings.Join(upserts, ", ")
+	} else {
+		repo.upsertQuery += " DO NOTHING"
+	}
+
+	return repo
+}
+
+func placeholders(count int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", count), ", ")
+}
+
+// fields returns pointers to the fields of the row (for scanning), in order of the columns.
+func (repo *repository[T]) fields(row *T) []any {
+	value := reflect.ValueOf(row).Elem()
+
+	fields := make([]any, len(repo.columns))
+	for i, column := range repo.columns {
+		fields[i] = value.Field(column.field).Addr().Interface()
+	}
+
+	return fields
+}
+
+// autoFields returns pointers to the auto fields of the row (for scanning the generated ids), in order of the columns.
+func (repo *repository[T]) autoFields(row *T) []any {
+	value := reflect.ValueOf(row).Elem()
+
+	var fields []any
+	for _, column := range repo.columns {
+		if column.auto {
+			fields = append(fields, value.Field(column.field).Addr().Interface())
+		}
+	}
+
+	return fields
+}
+
+// values returns values of the columns of the row which match the filter function, in order of the columns.
+func (repo *repository[T]) values(row *T, filter func(column repositoryColumn) bool) []any {
+	value := reflect.ValueOf(row).Elem()
+
+	var values []any
+	for _, column := range repo.columns {
+		if filter(column) {
+			values = append(values, value.Field(column.field).Interface())
+		}
+	}
+
+	return values
+}
+
+// load loads the row with the given primary key (values in order of the key columns), it returns false if there's none.
+func (repo *repository[T]) load(db DBTX, row *T, key ...any) (bool, error) {
+	err := db.QueryRow(repo.selectQuery+repo.keyQuery, key...).Scan(repo.fields(row)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (repo *repository[T]) exists(db DBTX, key ...any) (bool, error) {
+	var temp T
+
+	return repo.load(db, &temp, key...)
+}
+
+// save inserts the row if its auto id is 0 (populating the id), otherwise it updates it (see update). Rows of tables
+// without an auto id are upserted: inserted, or updated if a row with their primary key exists, in one statement. Tables
+// whose rows mustn't come back once deleted use insert and update instead.
+func (repo *repository[T]) save(db DBTX, row *T) error {
+	if !repo.auto {
+		_, err := db.Exec(repo.upsertQuery, repo.values(row, func(column repositoryColumn) bool { return true })...)
+
+		return err
+	}
+
+	value := reflect.ValueOf(row).Elem()
+	for _, column := range repo.columns {
+		if column.auto && value.Field(column.field).IsZero() {
+			return repo.insert(db, row)
+		}
+	}
+
+	return repo.update(db, row)
+}
+
+// insert inserts the row, populating its auto id (if any). It fails if a row with the same primary key exists.
+func (repo *repository[T]) insert(db DBTX, row *T) error {
+	values := repo.values(row, func(column repositoryColumn) bool { return !column.auto })
+
+	if !repo.auto {
+		_, err := db.Exec(repo.insertQuery, values...)
+
+		return err
+	}
+
+	return db.QueryRow(repo.insertQuery, values...).Scan(repo.autoFields(row)...)
+}
+
+// update updates the row with its primary key. It returns ErrRowNotFound if there's no such row (e.g. it was deleted
+// after it was loaded), the row isn't inserted again then.
+func (repo *repository[T]) update(db DBTX, row *T) error {
+	if repo.updateQuery == "" {
+		found, err := repo.exists(db, repo.values(row, func(column repositoryColumn) bool { return column.pk })...)
+		if err == nil && !found {
+			err = ErrRowNotFound
+		}
+
+		return err
+	}
+
+	values := repo.values(row, func(column repositoryColumn) bool { return !column.pk })
+	values = append(values, repo.values(row, func(column repositoryColumn) bool { return column.pk })...)
+
+	result, err := db.Exec(repo.updateQuery, values...)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if updated == 0 {
+		return ErrRowNotFound
+	}
+
+	return nil
+}
+
+// delete deletes the row with the primary key of the given row.
+func (repo *repository[T]) delete(db DBTX, row *T) error {
+	_, err := db.Exec("DELETE FROM "+repo.table+repo.keyQuery, repo.values(row, func(column repositoryColumn) bool { return column.pk })...)
+
+	return err
+}
+
+// all returns rows matching the filter (see PrepareQuery).
+func (repo *repository[T]) all(db DBTX, filter map[string]any, options QueryOptions) ([]T, error) {
+	query, params := PrepareQuery(repo.selectQuery, filter)
+
+	return repo.query(db, query, params, options)
+}
+
+// where returns rows matching the condition, for filters PrepareQuery can't express (e.g. OR, LIKE).
+func (repo *repository[T]) where(db DBTX, condition string, params []any, options QueryOptions) ([]T, error) {
+	return repo.query(db, repo.selectQuery+" WHERE "+condition, params, options)
+}
+
+func (repo *repository[T]) query(db DBTX, query string, params []any, options QueryOptions) ([]T, error) {
+	query, params = options.apply(query, params)
+
+	rows, err := db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var result []T
+	for rows.Next() {
+		result = append(result, *new(T))
+
+		if err = rows.Scan(repo.fields(&result[len(result)-1])...); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// count returns how many rows match the filter (see PrepareQuery).
+func (repo *repository[T]) count(db DBTX, filter map[string]any) (int, error) {
+	query, params := PrepareQuery("SELECT COUNT(*) FROM "+repo.table, filter)
+
+	return repo.countQuery(db, query, params)
+}
+
+// countWhere returns how many rows match the condition, for filters PrepareQuery can't express (e.g. OR, LIKE).
+func (repo *repository[T]) countWhere(db DBTX, condition string, params []any) (int, error) {
+	return repo.countQuery(db, "SELECT COUNT(*) FROM "+repo.table+" WHERE "+condition, params)
+}
+
+func (repo *repository[T]) countQuery(db DBTX, query string, params []any) (int, error) {
+	var count int
+	if err := db.QueryRow(query, params...).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// deleteAll deletes rows matching the filter (see PrepareQuery), it returns how many were deleted.
+func (repo *repository[T]) deleteAll(db DBTX, filter map[string]any) (int64, error) {
+	query, params := PrepareQuery("DELETE FROM "+repo.table, filter)
+
+	result, err := db.Exec(query, params...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,406 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/miki208/stravaadventuregame/internal/database/databasetest"
+)
+
+func TestRepositorySaveInsertsRowsWithGeneratedIds(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		first := Place{Name: "Zemun", Lat: 44.84, Lon: 20.41, Population: 168000}
+		if err := first.Save(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		second := Place{Name: "Pancevo", Lat: 44.87, Lon: 20.64, Population: 76000}
+		if err := second.Save(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if first.Id <= 0 || second.Id <= first.Id {
+			t.Fatalf("ids %d and %d, want increasing positive ids", first.Id, second.Id)
+		}
+
+		var loaded Place
+		found, err := loaded.Load(second.Id, db, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !found || loaded != second {
+			t.Errorf("Load(%d) = %v, %+v, want true, %+v", second.Id, found, loaded, second)
+		}
+	})
+}
+
+func TestRepositorySaveDoesNotInsertDeletedRowsAgain(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		location := Location{Lat: 44.8, Lon: 20.4, Name: "Belgrade"}
+		if err := location.Save(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		location.Name = "Beograd"
+		if err := location.Save(db, nil); err != nil {
+			t.Fatalf("Save() of an existing location failed: %v", err)
+		}
+
+		var loaded Location
+		if _, err := loaded.Load(location.Id, db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if loaded.Name != "Beograd" {
+			t.Errorf("name after the update = %q, want \"Beograd\"", loaded.Name)
+		}
+
+		if err := location.Delete(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := location.Save(db, nil); !errors.Is(err, ErrRowNotFound) {
+			t.Errorf("Save() of a deleted location = %v, want ErrRowNotFound", err)
+		}
+
+		if exists, err := LocationExists(location.Id, db, nil); err != nil || exists {
+			t.Errorf("LocationExists() = %v, %v after saving a deleted location, want false", exists, err)
+		}
+	})
+}
+
+func TestAdventureInsertAndSave(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		athlete := Athlete{Id: 1, FirstName: "Jane"}
+		if err := athlete.Save(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		start, end := Location{Name: "Belgrade"}, Location{Name: "Novi Sad"}
+		for _, location := range []*Location{&start, &end} {
+			if err := location.Save(db, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		adventure := Adventure{AthleteId: 1, StartLocation: start.Id, EndLocation: end.Id, TotalDistance: 94}
+		if err := adventure.Save(db, nil); !errors.Is(err, ErrRowNotFound) {
+			t.Errorf("Save() of a new adventure = %v, want ErrRowNotFound", err)
+		}
+
+		if err := adventure.Insert(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := adventure.Insert(db, nil); err == nil {
+			t.Error("Insert() of an existing adventure succeeded, want an error")
+		}
+
+		adventure.CurrentDistance = 10
+		if err := adventure.Save(db, nil); err != nil {
+			t.Fatalf("Save() of an existing adventure failed: %v", err)
+		}
+
+		// abandoned while the progress was being made
+		if err := adventure.Delete(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		adventure.CurrentDistance = 20
+		if err := adventure.Save(db, nil); !errors.Is(err, ErrRowNotFound) {
+			t.Errorf("Save() of an abandoned adventure = %v, want ErrRowNotFound", err)
+		}
+
+		if exists, err := AdventureExists(1, start.Id, end.Id, db, nil); err != nil || exists {
+			t.Errorf("AdventureExists() = %v, %v after saving an abandoned adventure, want false", exists, err)
+		}
+	})
+}
+
+func TestRepositorySaveUpsertsRowsWithNaturalKeys(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		entry := GeocodeCacheEntry{Key: "44.85,20.40", Name: "Zemun", CreatedAt: 1}
+		if err := entry.Save(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		entry.Name, entry.CreatedAt = "Batajnica", 2
+		if err := entry.Save(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := AllGeocodeCacheEntries(db, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(entries) != 1 || entries[0] != entry {
+			t.Errorf("entries = %+v, want [%+v]", entries, entry)
+		}
+	})
+}
+
+func TestRepositoryQueryOptions(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		var ids []int64
+		for _, name := range []string{"a", "b", "c", "d", "e"} {
+			place := Place{Name: name}
+			if err := place.Save(db, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			ids = append(ids, place.Id)
+		}
+
+		tests := []struct {
+			options QueryOptions
+			want    []int64
+		}{
+			{QueryOptions{OrderBy: "id DESC"}, []int64{ids[4], ids[3], ids[2], ids[1], ids[0]}},
+			{QueryOptions{OrderBy: "id", Limit: 2}, []int64{ids[0], ids[1]}},
+			{QueryOptions{OrderBy: "id", Limit: 2, Offset: 2}, []int64{ids[2], ids[3]}},
+			{QueryOptions{OrderBy: "id", Offset: 3}, []int64{ids[3], ids[4]}},
+		}
+
+		for _, test := range tests {
+			places, err := placeRepo.all(db, nil, test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []int64
+			for _, place := range places {
+				got = append(got, place.Id)
+			}
+
+			if len(got) != len(test.want) {
+				t.Errorf("%+v: ids %v, want %v", test.options, got, test.want)
+
+				continue
+			}
+
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("%+v: ids %v, want %v", test.options, got, test.want)
+
+					break
+				}
+			}
+		}
+	})
+}
+
+func TestAllLocationsVisibleTo(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		for _, location := range []Location{
+			{Name: "Private Novi Sad", OwnerId: 1},
+			{Name: "Belgrade"},
+			{Name: "Private Nis", OwnerId: 2},
+			{Name: "Novi Sad"},
+			{Name: "Private Belgrade", OwnerId: 1},
+		} {
+			if err := location.Save(db, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		tests := []struct {
+			limit, offset int
+			want          []string
+		}{
+			{0, 0, []string{"Belgrade", "Novi Sad", "Private Novi Sad", "Private Belgrade"}},
+			{2, 1, []string{"Novi Sad", "Private Novi Sad"}},
+			{2, 4, nil},
+		}
+
+		for _, test := range tests {
+			locations, err := AllLocationsVisibleTo(1, test.limit, test.offset, db, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, location := range locations {
+				got = append(got, location.Name)
+			}
+
+			if !slices.Equal(got, test.want) {
+				t.Errorf("AllLocationsVisibleTo(1, %d, %d) = %v, want %v", test.limit, test.offset, got, test.want)
+			}
+		}
+
+		if count, err := CountLocationsVisibleTo(1, db, nil); err != nil || count != 4 {
+			t.Errorf("CountLocationsVisibleTo(1) = %d, %v, want 4", count, err)
+		}
+	})
+}
+
+func TestAllPlacesByNamePrefix(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		for _, place := range []Place{
+			{Name: "Novi Sad", Population: 300000},
+			{Name: "Novi Pazar", Population: 100000},
+			{Name: "novi_grad", Population: 5000},
+			{Name: "Novixgrad", Population: 1000},
+			{Name: "Nis", Population: 250000},
+		} {
+			if err := place.Save(db, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		tests := []struct {
+			prefix string
+			limit  int
+			want   []string
+		}{
+			{"novi", 10, []string{"Novi Sad", "Novi Pazar", "novi_grad", "Novixgrad"}},
+			{"NOVI ", 1, []string{"Novi Sad"}},
+			{"novi_", 10, []string{"novi_grad"}}, // _ is not a wildcard
+			{"n%", 10, nil},
+		}
+
+		for _, test := range tests {
+			places, err := AllPlacesByNamePrefix(test.prefix, test.limit, db, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, place := range places {
+				got = append(got, place.Name)
+			}
+
+			if len(got) != len(test.want) {
+				t.Errorf("AllPlacesByNamePrefix(%q, %d) = %v, want %v", test.prefix, test.limit, got, test.want)
+
+				continue
+			}
+
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("AllPlacesByNamePrefix(%q, %d) = %v, want %v", test.prefix, test.limit, got, test.want)
+
+					break
+				}
+			}
+		}
+	})
+}
+
+func TestAcquireJobLease(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		steps := []struct {
+			holder string
+			now    int
+			want   int // the generation, 0 if not acquired
+		}{
+			{"a", 100, 1}, // free
+			{"b", 110, 0}, // held by a until 160
+			{"a", 120, 1}, // renewed by a until 180
+			{"b", 170, 0},
+			{"b", 180, 2}, // expired, taken over
+			{"a", 250, 3}, // expired again, taken back
+		}
+
+		for _, step := range steps {
+			generation, err := AcquireJobLease("job", step.holder, step.now, step.now+60, db, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if generation != step.want {
+				t.Errorf("AcquireJobLease(%q at %d) = %d, want %d", step.holder, step.now, generation, step.want)
+			}
+		}
+
+		// runs started with older generations are fenced off, even on the same holder
+		renewals := []struct {
+			holder     string
+			generation int
+			want       bool
+		}{
+			{"a", 1, false},
+			{"b", 2, false},
+			{"b", 3, false},
+			{"a", 3, true},
+		}
+
+		for _, renewal := range renewals {
+			renewed, err := RenewJobLease("job", renewal.holder, renewal.generation, 400, db, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if renewed != renewal.want {
+				t.Errorf("RenewJobLease(%q, %d) = %v, want %v", renewal.holder, renewal.generation, renewed, renewal.want)
+			}
+		}
+
+		// only the holder releases the lease
+		lease := JobLease{Job: "job", Holder: "b"}
+		if err := lease.Delete(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if exists, err := JobLeaseExists("job", db, nil); err != nil || !exists {
+			t.Errorf("JobLeaseExists() = %v, %v after a release by another holder, want true", exists, err)
+		}
+	})
+}
+
+func TestDueOutboxMessages(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		var ids []int
+		for _, message := range []OutboxMessage{
+			{Kind: "a", Status: OutboxMessagePending, NextAttemptAt: 100},
+			{Kind: "b", Status: OutboxMessageDone, NextAttemptAt: 100},
+			{Kind: "c", Status: OutboxMessagePending, NextAttemptAt: 300}, // not due yet
+			{Kind: "d", Status: OutboxMessagePending, NextAttemptAt: 200},
+			{Kind: "e", Status: OutboxMessagePending, NextAttemptAt: 50},
+		} {
+			if err := message.Save(db, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			ids = append(ids, message.Id)
+		}
+
+		tests := []struct {
+			limit int
+			want  []int
+		}{
+			{10, []int{ids[0], ids[3], ids[4]}},
+			{2, []int{ids[0], ids[3]}},
+		}
+
+		for _, test := range tests {
+			messages, err := DueOutboxMessages(200, test.limit, db, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []int
+			for _, message := range messages {
+				got = append(got, message.Id)
+			}
+
+			if len(got) != len(test.want) {
+				t.Errorf("DueOutboxMessages(200, %d) = %v, want %v", test.limit, got, test.want)
+
+				continue
+			}
+
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("DueOutboxMessages(200, %d) = %v, want %v", test.limit, got, test.want)
+
+					break
+				}
+			}
+		}
+	})
+}
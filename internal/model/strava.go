@@ -2,105 +2,60 @@ package model
 
 import (
 	"database/sql"
-	"errors"
 	"time"
 
 	"github.com/miki208/stravaadventuregame/internal/service/strava/externalmodel"
 )
 
 type Athlete struct {
-	*externalmodel.Athlete
+	Id        int64  `db:"id,pk"`
+	FirstName string `db:"first_name"`
+	LastName  string `db:"last_name"`
+	City      string `db:"city"`
+	Country   string `db:"country"`
+	Sex       string `db:"sex"`
 }
 
+var athleteRepo = newRepository[Athlete]("Athlete")
+
 func (athlete *Athlete) FromExternalModel(externalAthlete *externalmodel.Athlete) {
-	athlete.Athlete = externalAthlete
+	athlete.Id = externalAthlete.Id
+	athlete.FirstName = externalAthlete.FirstName
+	athlete.LastName = externalAthlete.LastName
+	athlete.City = externalAthlete.City
+	athlete.Country = externalAthlete.Country
+	athlete.Sex = externalAthlete.Sex
 }
 
 func NewAthlete() *Athlete {
-	return &Athlete{Athlete: &externalmodel.Athlete{}}
+	return &Athlete{}
 }
 
 func (athl *Athlete) Load(id int64, db *sql.DB, tx *sql.Tx) (bool, error) {
-	var err error
-
-	query, params := PrepareQuery("SELECT * FROM Athlete", map[string]any{"id": id})
-
-	var row *sql.Row
-	if tx != nil {
-		row = tx.QueryRow(query, params...)
-	} else {
-		row = db.QueryRow(query, params...)
-	}
-
-	err = row.Scan(&athl.Id, &athl.FirstName, &athl.LastName, &athl.City, &athl.Country, &athl.Sex)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			err = nil
-		}
-
-		return false, err
-	}
-
-	return true, nil
+	return athleteRepo.load(dbtx(db, tx), athl, id)
 }
 
 func (athl *Athlete) Save(db *sql.DB, tx *sql.Tx) error {
-	var err error
-
-	var found bool
-	found, err = AthleteExists(athl.Id, db, tx)
-	if err != nil {
-		return err
-	}
-
-	if found {
-		query := "UPDATE Athlete SET first_name=?, last_name=?, city=?, country=?, sex=? WHERE id=?"
-
-		if tx != nil {
-			_, err = tx.Exec(query, athl.FirstName, athl.LastName, athl.City, athl.Country, athl.Sex, athl.Id)
-		} else {
-			_, err = db.Exec(query, athl.FirstName, athl.LastName, athl.City, athl.Country, athl.Sex, athl.Id)
-		}
-	} else {
-		query := "INSERT INTO Athlete VALUES(?, ?, ?, ?, ?, ?)"
-
-		if tx != nil {
-			_, err = tx.Exec(query, athl.Id, athl.FirstName, athl.LastName, athl.City, athl.Country, athl.Sex)
-		} else {
-			_, err = db.Exec(query, athl.Id, athl.FirstName, athl.LastName, athl.City, athl.Country, athl.Sex)
-		}
-	}
-
-	return err
+	return athleteRepo.save(dbtx(db, tx), athl)
 }
 
 func (athl *Athlete) Delete(db *sql.DB, tx *sql.Tx) error {
-	var err error
-
-	query, params := PrepareQuery("DELETE FROM Athlete", map[string]any{"id": athl.Id})
-
-	if tx != nil {
-		_, err = tx.Exec(query, params...)
-	} else {
-		_, err = db.Exec(query, params...)
-	}
-
-	return err
+	return athleteRepo.delete(dbtx(db, tx), athl)
 }
 
 func AthleteExists(id int64, db *sql.DB, tx *sql.Tx) (bool, error) {
-	temp := NewAthlete()
-
-	return temp.Load(id, db, tx)
+	return athleteRepo.exists(dbtx(db, tx), id)
 }
 
 type StravaCredential struct {
-	AthleteId    int64
-	AccessToken  string
-	RefreshToken string
-	ExpiresAt    int
+	AthleteId    int64  `db:"athlete_id,pk"`
+	AccessToken  string `db:"access_token"`
+	RefreshToken string `db:"refresh_token"`
+	ExpiresAt    int    `db:"expires_at"`
 }
 
+var stravaCredentialRepo = newRepository[StravaCredential]("StravaCredential")
+
 func (stravaCredential *StravaCredential) FromExternalModel(externalTokenExchangeResponse *externalmodel.TokenExchangeResponse) {
 	stravaCredential.AthleteId = externalTokenExchangeResponse.Athl.Id
 	stravaCredential.AccessToken = externalTokenExchangeResponse.AccessToken
@@ -109,76 +64,38 @@ func (stravaCredential *StravaCredential) FromExternalModel(externalTokenExchang
 }
 
 func (cred *StravaCredential) Load(athlId int64, db *sql.DB, tx *sql.Tx) (bool, error) {
-	var err error
-
-	query, params := PrepareQuery("SELECT * FROM StravaCredential", map[string]any{"athlete_id": athlId})
-
-	var row *sql.Row
-	if tx != nil {
-		row = tx.QueryRow(query, params...)
-	} else {
-		row = db.QueryRow(query, params...)
-	}
-
-	err = row.Scan(&cred.AthleteId, &cred.AccessToken, &cred.RefreshToken, &cred.ExpiresAt)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			err = nil
-		}
-
-		return false, err
-	}
-
-	return true, nil
+	return stravaCredentialRepo.load(dbtx(db, tx), cred, athlId)
 }
 
 func (cred *StravaCredential) Save(db *sql.DB, tx *sql.Tx) error {
-	var err error
-
-	var found bool
-	found, err = StravaCredentialExists(cred.AthleteId, db, tx)
-	if err != nil {
-		return err
-	}
-
-	if found {
-		query := "UPDATE StravaCredential SET access_token=?, refresh_token=?, expires_at=? WHERE athlete_id=?"
-
-		if tx != nil {
-			_, err = tx.Exec(query, cred.AccessToken, cred.RefreshToken, cred.ExpiresAt, cred.AthleteId)
-		} else {
-			_, err = db.Exec(query, cred.AccessToken, cred.RefreshToken, cred.ExpiresAt, cred.AthleteId)
-		}
-	} else {
-		query := "INSERT INTO StravaCredential VALUES(?, ?, ?, ?)"
-
-		if tx != nil {
-			_, err = tx.Exec(query, cred.AthleteId, cred.AccessToken, cred.RefreshToken, cred.ExpiresAt)
-		} else {
-			_, err = db.Exec(query, cred.AthleteId, cred.AccessToken, cred.RefreshToken, cred.ExpiresAt)
-		}
-	}
-
-	return err
+	return stravaCredentialRepo.save(dbtx(db, tx), cred)
 }
 
 func StravaCredentialExists(athlId int64, db *sql.DB, tx *sql.Tx) (bool, error) {
-	var temp StravaCredential
-
-	return temp.Load(athlId, db, tx)
+	return stravaCredentialRepo.exists(dbtx(db, tx), athlId)
 }
 
 type StravaWebhookSubscription struct {
 	Id int `json:"id"`
 }
 
+// StravaWebhookEvent is an activity webhook event waiting to be processed (PendingActivity), keyed by the activity.
 type StravaWebhookEvent struct {
-	ObjectId   int64
-	OwnerId    int64
-	AspectType string
-	EventTime  int64
+	ObjectId   int64  `db:"id,pk"`
+	OwnerId    int64  `db:"owner_id"`
+	AspectType string `db:"aspect_type"`
+	EventTime  int64  `db:"event_time"`
+
+	// retry state, events which haven't failed yet have no attempts
+	Attempts      int    `db:"attempts"`
+	LastError     string `db:"last_error"`
+	NextAttemptAt int    `db:"next_attempt_at"`
+	Dead          int    `db:"dead"` // 1 if processing is given up, until an admin requeues or discards the event
+	FailedAt      int    `db:"failed_at"`
 }
 
+var stravaWebhookEventRepo = newRepository[StravaWebhookEvent]("PendingActivity")
+
 func (stravaWebhookEvent *StravaWebhookEvent) FromExternalModel(externalStravaWebhookEvent *externalmodel.StravaWebhookEvent) {
 	stravaWebhookEvent.ObjectId = externalStravaWebhookEvent.ObjectId
 	stravaWebhookEvent.OwnerId = externalStravaWebhookEvent.OwnerId
@@ -186,128 +103,78 @@ func (stravaWebhookEvent *StravaWebhookEvent) FromExternalModel(externalStravaWe
 	stravaWebhookEvent.EventTime = externalStravaWebhookEvent.EventTime
 }
 
+// ResetRetryState gives the event a fresh start, as if it never failed.
+func (ev *StravaWebhookEvent) ResetRetryState() {
+	ev.Attempts = 0
+	ev.LastError = ""
+	ev.NextAttemptAt = 0
+	ev.Dead = 0
+	ev.FailedAt = 0
+}
+
 func (ev *StravaWebhookEvent) Load(activityId int64, db *sql.DB, tx *sql.Tx) (bool, error) {
-	var err error
+	return stravaWebhookEventRepo.load(dbtx(db, tx), ev, activityId)
+}
 
-	query, params := PrepareQuery("SELECT * FROM PendingActivity", map[string]any{"id": activityId})
+func (ev *StravaWebhookEvent) Save(db *sql.DB, tx *sql.Tx) error {
+	return stravaWebhookEventRepo.save(dbtx(db, tx), ev)
+}
 
-	var row *sql.Row
-	if tx != nil {
-		row = tx.QueryRow(query, params...)
-	} else {
-		row = db.QueryRow(query, params...)
-	}
+func (ev *StravaWebhookEvent) Delete(db *sql.DB, tx *sql.Tx) error {
+	return stravaWebhookEventRepo.delete(dbtx(db, tx), ev)
+}
 
-	err = row.Scan(&ev.ObjectId, &ev.OwnerId, &ev.AspectType, &ev.EventTime)
+// SaveRetryState saves the retry state of the event after a failed attempt, only if the queued event is still the one
+// which was attempted, with previousAttempts: a newer event of the activity, a requeue or a discard meanwhile wins over
+// the failure. It returns false if the state wasn't saved because of that.
+func (ev *StravaWebhookEvent) SaveRetryState(previousAttempts int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	result, err := dbtx(db, tx).Exec("UPDATE PendingActivity SET attempts=?, last_error=?, next_attempt_at=?, dead=?, failed_at=? WHERE id=? AND aspect_type=? AND event_time=? AND attempts=?",
+		ev.Attempts, ev.LastError, ev.NextAttemptAt, ev.Dead, ev.FailedAt, ev.ObjectId, ev.AspectType, ev.EventTime, previousAttempts)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			err = nil
-		}
-
 		return false, err
 	}
 
-	return true, nil
-}
+	updated, err := result.RowsAffected()
 
-func (ev *StravaWebhookEvent) Save(db *sql.DB, tx *sql.Tx) error {
-	var err error
+	return updated > 0, err
+}
 
-	var found bool
-	found, err = StravaWebhookEventExists(ev.ObjectId, db, tx)
+// ResetStravaWebhookEventRetryState gives the queued event of the activity a fresh start, it returns false if there's
+// no such event.
+func ResetStravaWebhookEventRetryState(activityId int64, db *sql.DB, tx *sql.Tx) (bool, error) {
+	result, err := dbtx(db, tx).Exec("UPDATE PendingActivity SET attempts=0, last_error='', next_attempt_at=0, dead=0, failed_at=0 WHERE id=?", activityId)
 	if err != nil {
-		return err
-	}
-
-	if found {
-		query := "UPDATE PendingActivity SET aspect_type=?, event_time=? WHERE id=?"
-
-		if tx != nil {
-			_, err = tx.Exec(query, ev.AspectType, ev.EventTime, ev.ObjectId)
-		} else {
-			_, err = db.Exec(query, ev.AspectType, ev.EventTime, ev.ObjectId)
-		}
-	} else {
-		query := "INSERT INTO PendingActivity VALUES(?, ?, ?, ?)"
-
-		if tx != nil {
-			_, err = tx.Exec(query, ev.ObjectId, ev.OwnerId, ev.AspectType, ev.EventTime)
-		} else {
-			_, err = db.Exec(query, ev.ObjectId, ev.OwnerId, ev.AspectType, ev.EventTime)
-		}
+		return false, err
 	}
 
-	return err
-}
-
-func (ev *StravaWebhookEvent) Delete(db *sql.DB, tx *sql.Tx) error {
-	var err error
-
-	query, params := PrepareQuery("DELETE FROM PendingActivity", map[string]any{
-		"id": ev.ObjectId,
-	})
-
-	if tx != nil {
-		_, err = tx.Exec(query, params...)
-	} else {
-		_, err = db.Exec(query, params...)
-	}
+	updated, err := result.RowsAffected()
 
-	return err
+	return updated > 0, err
 }
 
 func StravaWebhookEventExists(activityId int64, db *sql.DB, tx *sql.Tx) (bool, error) {
-	var temp StravaWebhookEvent
-
-	return temp.Load(activityId, db, tx)
+	return stravaWebhookEventRepo.exists(dbtx(db, tx), activityId)
 }
 
 func AllStravaWebhookEvents(db *sql.DB, tx *sql.Tx, filter map[string]any) ([]StravaWebhookEvent, error) {
-	var err error
-
-	var rows *sql.Rows
-	query, params := PrepareQuery("SELECT * FROM PendingActivity", filter)
-	if tx != nil {
-		rows, err = tx.Query(query, params...)
-	} else {
-		rows, err = db.Query(query, params...)
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	defer rows.Close()
-
-	var events []StravaWebhookEvent
-	for rows.Next() {
-		events = append(events, StravaWebhookEvent{})
-
-		eventToEdit := &events[len(events)-1]
-		if err = rows.Scan(&eventToEdit.ObjectId, &eventToEdit.OwnerId, &eventToEdit.AspectType, &eventToEdit.EventTime); err != nil {
-			return nil, err
-		}
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return events, nil
+	return stravaWebhookEventRepo.all(dbtx(db, tx), filter, QueryOptions{})
 }
 
 type Activity struct {
-	Id                 int64
-	Distance           float32
-	MovingTime         int
-	ElapsedTime        int
-	TotalElevationGain float32
-	SportType          string
-	StartDate          int
-	Description        string
+	Id                 int64   `db:"id,pk"`
+	Distance           float32 `db:"distance"`
+	MovingTime         int     `db:"moving_time"`
+	ElapsedTime        int     `db:"elapsed_time"`
+	TotalElevationGain float32 `db:"elevation_gain"`
+	SportType          string  `db:"type"`
+	StartDate          int     `db:"start_date"`
+	Description        string  `db:"description"`
 
-	AthleteId int64
+	AthleteId int64 `db:"athlete_id"`
 }
 
+var activityRepo = newRepository[Activity]("Activity")
+
 func (internalActivity *Activity) FromExternalModel(externalActivity *externalmodel.Activity) {
 	internalActivity.Id = externalActivity.Id
 	internalActivity.Distance = externalActivity.Distance / 1000 // transformation from meters to kilometers is done here
@@ -327,113 +194,31 @@ func (internalActivity *Activity) FromExternalModel(externalActivity *externalmo
 }
 
 func (a *Activity) Load(id int64, db *sql.DB, tx *sql.Tx) (bool, error) {
-	var err error
-
-	query, params := PrepareQuery("SELECT id, athlete_id, type, distance, start_date, moving_time, elapsed_time, elevation_gain, description FROM Activity", map[string]any{
-		"id": id,
-	})
-
-	var row *sql.Row
-	if tx != nil {
-		row = tx.QueryRow(query, params...)
-	} else {
-		row = db.QueryRow(query, params...)
-	}
-
-	err = row.Scan(&a.Id, &a.AthleteId, &a.SportType, &a.Distance, &a.StartDate, &a.MovingTime, &a.ElapsedTime, &a.TotalElevationGain, &a.Description)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			err = nil
-		}
-
-		return false, err
-	}
-
-	return true, nil
+	return activityRepo.load(dbtx(db, tx), a, id)
 }
 
 func (a *Activity) Save(db *sql.DB, tx *sql.Tx) error {
-	var err error
-
-	var found bool
-	found, err = ActivityExists(a.Id, db, tx)
-	if err != nil {
-		return err
-	}
-
-	if found {
-		query := "UPDATE Activity SET type=?, distance=?, start_date=?, moving_time=?, elapsed_time=?, elevation_gain=?, description=? WHERE id=?"
-
-		if tx != nil {
-			_, err = tx.Exec(query, a.SportType, a.Distance, a.StartDate, a.MovingTime, a.ElapsedTime, a.TotalElevationGain, a.Description, a.Id)
-		} else {
-			_, err = db.Exec(query, a.SportType, a.Distance, a.StartDate, a.MovingTime, a.ElapsedTime, a.TotalElevationGain, a.Description, a.Id)
-		}
-	} else {
-		query := "INSERT INTO Activity VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)"
-
-		if tx != nil {
-			_, err = tx.Exec(query, a.Id, a.AthleteId, a.SportType, a.Distance, a.StartDate, a.MovingTime, a.ElapsedTime, a.TotalElevationGain, a.Description)
-		} else {
-			_, err = db.Exec(query, a.Id, a.AthleteId, a.SportType, a.Distance, a.StartDate, a.MovingTime, a.ElapsedTime, a.TotalElevationGain, a.Description)
-		}
-	}
-
-	return err
+	return activityRepo.save(dbtx(db, tx), a)
 }
 
 func (a *Activity) Delete(db *sql.DB, tx *sql.Tx) error {
-	var err error
-
-	query, params := PrepareQuery("DELETE FROM Activity", map[string]any{
-		"id": a.Id,
-	})
-
-	if tx != nil {
-		_, err = tx.Exec(query, params...)
-	} else {
-		_, err = db.Exec(query, params...)
-	}
-
-	return err
+	return activityRepo.delete(dbtx(db, tx), a)
 }
 
 func ActivityExists(id int64, db *sql.DB, tx *sql.Tx) (bool, error) {
-	var temp Activity
-
-	return temp.Load(id, db, tx)
+	return activityRepo.exists(dbtx(db, tx), id)
 }
 
 func AllActivities(db *sql.DB, tx *sql.Tx, filter map[string]any) ([]Activity, error) {
-	var err error
-
-	var rows *sql.Rows
-	query, params := PrepareQuery("SELECT id, athlete_id, type, distance, start_date, moving_time, elapsed_time, elevation_gain, description FROM Activity", filter)
-	if tx != nil {
-		rows, err = tx.Query(query, params...)
-	} else {
-		rows, err = db.Query(query, params...)
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	defer rows.Close()
-
-	var activities []Activity
-	for rows.Next() {
-		activities = append(activities, Activity{})
-
-		activityToEdit := &activities[len(activities)-1]
-		if err = rows.Scan(&activityToEdit.Id, &activityToEdit.AthleteId, &activityToEdit.SportType, &activityToEdit.Distance,
-			&activityToEdit.StartDate, &activityToEdit.MovingTime, &activityToEdit.ElapsedTime, &activityToEdit.TotalElevationGain, &activityToEdit.Description); err != nil {
-			return nil, err
-		}
-	}
+	return activityRepo.all(dbtx(db, tx), filter, QueryOptions{})
+}
 
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
+// AllActivitiesBetween returns a page of activities of the athlete started between the dates (inclusive), the most
+// recent first.
+func AllActivitiesBetween(athleteId int64, from int, to int, limit int, offset int, db *sql.DB, tx *sql.Tx) ([]Activity, error) {
+	return activityRepo.where(dbtx(db, tx), "athlete_id=? AND start_date BETWEEN ? AND ?", []any{athleteId, from, to}, QueryOptions{OrderBy: "start_date DESC", Limit: limit, Offset: offset})
+}
 
-	return activities, nil
+func CountActivitiesBetween(athleteId int64, from int, to int, db *sql.DB, tx *sql.Tx) (int, error) {
+	return activityRepo.countWhere(dbtx(db, tx), "athlete_id=? AND start_date BETWEEN ? AND ?", []any{athleteId, from, to})
 }
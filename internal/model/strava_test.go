@@ -0,0 +1,67 @@
+package model
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/miki208/stravaadventuregame/internal/database/databasetest"
+)
+
+func TestStravaWebhookEventSaveRetryState(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		queued := StravaWebhookEvent{ObjectId: 1, OwnerId: 10, AspectType: "create", EventTime: 100}
+		if err := queued.Save(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		failed := queued
+		failed.Attempts, failed.LastError, failed.NextAttemptAt, failed.FailedAt = 1, "failed", 160, 100
+		if saved, err := failed.SaveRetryState(0, db, nil); err != nil || !saved {
+			t.Fatalf("SaveRetryState() = %v, %v, want true", saved, err)
+		}
+
+		var loaded StravaWebhookEvent
+		if _, err := loaded.Load(1, db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if loaded != failed {
+			t.Errorf("loaded %+v, want %+v", loaded, failed)
+		}
+
+		// requeued by an admin meanwhile
+		if _, err := ResetStravaWebhookEventRetryState(1, db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		failed.Attempts = 2
+		if saved, err := failed.SaveRetryState(1, db, nil); err != nil || saved {
+			t.Errorf("SaveRetryState() after a requeue = %v, %v, want false", saved, err)
+		}
+
+		// replaced by a newer event of the activity
+		newer := StravaWebhookEvent{ObjectId: 1, OwnerId: 10, AspectType: "delete", EventTime: 200}
+		if err := newer.Save(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		failed.Attempts = 1
+		if saved, err := failed.SaveRetryState(0, db, nil); err != nil || saved {
+			t.Errorf("SaveRetryState() after a newer event = %v, %v, want false", saved, err)
+		}
+
+		// processed or discarded, the event must not be queued again
+		if err := newer.Delete(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		newer.Attempts = 1
+		if saved, err := newer.SaveRetryState(0, db, nil); err != nil || saved {
+			t.Errorf("SaveRetryState() after a delete = %v, %v, want false", saved, err)
+		}
+
+		if exists, err := StravaWebhookEventExists(1, db, nil); err != nil || exists {
+			t.Errorf("StravaWebhookEventExists() = %v, %v, want false", exists, err)
+		}
+	})
+}
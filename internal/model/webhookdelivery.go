@@ -0,0 +1,59 @@
+package model
+
+import (
+	"database/sql"
+)
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed" // given up after too many attempts
+)
+
+// WebhookDelivery is one event queued for (or already posted to) one webhook endpoint.
+type WebhookDelivery struct {
+	Id            int    `db:"id,pk,auto"`
+	EndpointId    int    `db:"endpoint_id"`
+	Event         string `db:"event"`
+	Payload       string `db:"payload"` // json
+	Status        string `db:"status"`
+	Attempts      int    `db:"attempts"`
+	NextAttemptAt int    `db:"next_attempt_at"`
+	LastAttemptAt int    `db:"last_attempt_at"`
+	ResponseCode  int    `db:"response_code"` // of the last attempt, 0 if there was no response
+	LastError     string `db:"last_error"`    // of the last attempt
+	CreatedAt     int    `db:"created_at"`
+}
+
+var webhookDeliveryRepo = newRepository[WebhookDelivery]("WebhookDelivery")
+
+func (delivery *WebhookDelivery) Load(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return webhookDeliveryRepo.load(dbtx(db, tx), delivery, id)
+}
+
+// Save inserts the delivery if its id is 0 (populating the id), otherwise it updates the one with the given id.
+func (delivery *WebhookDelivery) Save(db *sql.DB, tx *sql.Tx) error {
+	return webhookDeliveryRepo.save(dbtx(db, tx), delivery)
+}
+
+func (delivery *WebhookDelivery) Delete(db *sql.DB, tx *sql.Tx) error {
+	return webhookDeliveryRepo.delete(dbtx(db, tx), delivery)
+}
+
+func WebhookDeliveryExists(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return webhookDeliveryRepo.exists(dbtx(db, tx), id)
+}
+
+// AllWebhookDeliveries returns deliveries matching the filter, in the order they were queued.
+func AllWebhookDeliveries(db *sql.DB, tx *sql.Tx, filter map[string]any) ([]WebhookDelivery, error) {
+	return webhookDeliveryRepo.all(dbtx(db, tx), filter, QueryOptions{OrderBy: "id"})
+}
+
+// DueWebhookDeliveries returns at most limit pending deliveries whose next attempt is due at now, in the order they
+// were queued.
+func DueWebhookDeliveries(now int, limit int, db *sql.DB, tx *sql.Tx) ([]WebhookDelivery, error) {
+	return webhookDeliveryRepo.all(dbtx(db, tx), map[string]any{
+		"status":          WebhookDeliveryPending,
+		"next_attempt_at": ComparationOperation{FieldValue: now, Operation: "<="},
+	}, QueryOptions{OrderBy: "id", Limit: limit})
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"database/sql"
+)
+
+// WebhookEndpoint is an https url that adventure events are posted to. Endpoints of athletes receive events of their
+// own adventures, while endpoints registered by admins (AthleteId 0) receive events of all athletes.
+type WebhookEndpoint struct {
+	Id        int    `db:"id,pk,auto"`
+	AthleteId int64  `db:"athlete_id"`
+	Url       string `db:"url"`
+	Secret    string `db:"secret"` // used to sign payloads
+	Events    string `db:"events"` // comma separated
+	CreatedAt int    `db:"created_at"`
+}
+
+var webhookEndpointRepo = newRepository[WebhookEndpoint]("WebhookEndpoint")
+
+func (endpoint *WebhookEndpoint) Load(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return webhookEndpointRepo.load(dbtx(db, tx), endpoint, id)
+}
+
+// Save inserts the endpoint if its id is 0 (populating the id), otherwise it updates the one with the given id.
+func (endpoint *WebhookEndpoint) Save(db *sql.DB, tx *sql.Tx) error {
+	return webhookEndpointRepo.save(dbtx(db, tx), endpoint)
+}
+
+func (endpoint *WebhookEndpoint) Delete(db *sql.DB, tx *sql.Tx) error {
+	return webhookEndpointRepo.delete(dbtx(db, tx), endpoint)
+}
+
+func WebhookEndpointExists(id int, db *sql.DB, tx *sql.Tx) (bool, error) {
+	return webhookEndpointRepo.exists(dbtx(db, tx), id)
+}
+
+func AllWebhookEndpoints(db *sql.DB, tx *sql.Tx, filter map[string]any) ([]WebhookEndpoint, error) {
+	return webhookEndpointRepo.all(dbtx(db, tx), filter, QueryOptions{OrderBy: "id"})
+}
@@ -0,0 +1,83 @@
+package scheduledjobs
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+	"github.com/miki208/stravaadventuregame/internal/service/geocoding"
+)
+
+// CoursePlaceBuilder finds towns along courses of ongoing adventures, for courses which don't have them yet.
+func CoursePlaceBuilder(ctx context.Context, app *application.App, fence *application.JobFence) error {
+	slog.Info("CoursePlaceBuilder started.")
+
+	startedAdventures, err := model.AllAdventures(app.SqlDb, nil, map[string]any{"completed": 0})
+	if err != nil {
+		slog.Error("CoursePlaceBuilder > Failed to load started adventures.", "error", err)
+
+		return err
+	}
+
+	visitedCourses := make(map[string]bool)
+	for _, adventure := range startedAdventures {
+		if ctx.Err() != nil {
+			slog.Info("CoursePlaceBuilder > Shutting down, remaining courses are built after the restart.")
+
+			return ctx.Err()
+		}
+
+		courseDbName := model.CourseDbName(adventure.StartLocation, adventure.EndLocation)
+		if visitedCourses[courseDbName] {
+			continue
+		}
+
+		visitedCourses[courseDbName] = true
+
+		exists, err := app.FileDb.Exists("courseplaces", courseDbName)
+		if err != nil {
+			slog.Error("CoursePlaceBuilder > Failed to check if course places exist.", "course", courseDbName, "error", err)
+
+			continue
+		}
+
+		if exists {
+			continue
+		}
+
+		route := model.NewDirectionsRoute()
+		if err = app.FileDb.Read("course", courseDbName, route); err != nil {
+			slog.Error("CoursePlaceBuilder > Failed to read the course.", "course", courseDbName, "error", err)
+
+			continue
+		}
+
+		routePolyline, _, err := helper.DecodePolyline(route.Geometry, route.Elevation, false)
+		if err != nil {
+			slog.Error("CoursePlaceBuilder > Failed to decode the course.", "course", courseDbName, "error", err)
+
+			continue
+		}
+
+		coursePlaces, err := geocoding.BuildCoursePlaces(app.GeocoderSvc, routePolyline, app.CoursePlacesEveryKm)
+		if err != nil {
+			slog.Error("CoursePlaceBuilder > Failed to find places along the course, will retry on the next run.", "course", courseDbName, "error", err)
+
+			continue
+		}
+
+		if err = app.FileDb.Write("courseplaces", courseDbName, &coursePlaces); err != nil {
+			slog.Error("CoursePlaceBuilder > Failed to save course places.", "course", courseDbName, "error", err)
+
+			continue
+		}
+
+		slog.Info("CoursePlaceBuilder > Course places built.", "course", courseDbName, "places", len(coursePlaces.Places))
+	}
+
+	slog.Info("CoursePlaceBuilder finished.")
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package scheduledjobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+func GeocodeCacheCleaner(ctx context.Context, app *application.App, fence *application.JobFence) error {
+	if app.GeocodeCacheSvc == nil {
+		return nil
+	}
+
+	deleteEntriesOlderThan := int(time.Now().Unix()) - app.GeocodeCacheSvc.GetTtlSec()
+
+	slog.Info("GeocodeCacheCleaner started.", "deleteEntriesOlderThan", deleteEntriesOlderThan)
+
+	expiredEntries, err := model.AllGeocodeCacheEntries(app.SqlDb, nil, map[string]any{
+		"created_at": model.ComparationOperation{Operation: "<=", FieldValue: deleteEntriesOlderThan},
+	})
+	if err != nil {
+		slog.Error("Failed to retrieve expired geocode cache entries.", "error", err)
+
+		return err
+	}
+
+	for _, entry := range expiredEntries {
+		if err = entry.Delete(app.SqlDb, nil); err != nil {
+			slog.Error("Failed to delete geocode cache entry.", "key", entry.Key, "error", err)
+
+			continue
+		}
+	}
+
+	slog.Info("GeocodeCacheCleaner finished.", "expired", len(expiredEntries))
+
+	return nil
+}
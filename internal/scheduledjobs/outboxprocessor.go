@@ -0,0 +1,188 @@
+package scheduledjobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+	"github.com/miki208/stravaadventuregame/internal/service/strava"
+)
+
+const (
+	maxOutboxMessagesPerRun = 100
+	maxOutboxAttempts       = 8
+	outboxFirstRetryDelay   = time.Minute
+	outboxMaxRetryDelay     = 6 * time.Hour
+	outboxRetention         = 30 * 24 * time.Hour
+	maxOutboxErrorLength    = 500
+)
+
+// outboxHandlers perform side effects by the kind of the outbox message. Handlers may be called more than once for the
+// same message (e.g. if saving the outcome fails), so they should be idempotent.
+var outboxHandlers = map[string]func(app *application.App, payload string) error{
+	helper.OutboxActivityDescription: updateActivityDescription,
+}
+
+// OutboxProcessor performs side effects queued in the outbox, e.g. Strava activity description updates. Failed messages
+// are retried with exponential backoff, and given up after maxOutboxAttempts. Old messages are removed.
+func OutboxProcessor(ctx context.Context, app *application.App, fence *application.JobFence) error {
+	now := time.Now()
+
+	messages, err := model.DueOutboxMessages(int(now.Unix()), maxOutboxMessagesPerRun, app.SqlDb, nil)
+	if err != nil {
+		slog.Error("OutboxProcessor > Failed to load pending outbox messages.", "error", err)
+
+		return err
+	}
+
+	done := 0
+	for i := range messages {
+		if ctx.Err() != nil {
+			slog.Info("OutboxProcessor > Shutting down, remaining outbox messages are processed after the restart.")
+
+			return ctx.Err()
+		}
+
+		// another instance which took the lease over processes the messages
+		if err = fence.Check(nil); err != nil {
+			slog.Warn("OutboxProcessor > Stopping, the lease of the job was lost.", "error", err)
+
+			return err
+		}
+
+		processed, rateLimited := processOutboxMessage(app, &messages[i])
+		if rateLimited {
+			slog.Warn("OutboxProcessor > Rate limit error encountered, stopping processing.")
+
+			break
+		}
+
+		if processed {
+			done++
+		}
+	}
+
+	if len(messages) > 0 {
+		slog.Info("OutboxProcessor > Outbox messages processed.", "attempted", len(messages), "done", done)
+	}
+
+	oldMessages, err := model.AllOutboxMessages(app.SqlDb, nil, map[string]any{
+		"created_at": model.ComparationOperation{FieldValue: int(now.Add(-outboxRetention).Unix()), Operation: "<"},
+	})
+	if err != nil {
+		slog.Error("OutboxProcessor > Failed to load old outbox messages.", "error", err)
+
+		return err
+	}
+
+	for _, message := range oldMessages {
+		if message.Status == model.OutboxMessagePending {
+			continue
+		}
+
+		if err = message.Delete(app.SqlDb, nil); err != nil {
+			slog.Error("OutboxProcessor > Failed to delete old outbox message.", "message_id", message.Id, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// processOutboxMessage makes one attempt and records its outcome. It returns true if the side effect was performed, and
+// whether the Strava rate limit was hit (the attempt is not counted then).
+func processOutboxMessage(app *application.App, message *model.OutboxMessage) (bool, bool) {
+	handle, found := outboxHandlers[message.Kind]
+
+	var err error
+	if !found {
+		err = fmt.Errorf("unknown outbox message kind %q", message.Kind)
+	} else {
+		err = handle(app, message.Payload)
+	}
+
+	if stravaErr, ok := err.(*strava.StravaError); ok && stravaErr.StatusCode() == http.StatusTooManyRequests {
+		return false, true
+	}
+
+	now := time.Now()
+
+	message.Attempts++
+	message.LastAttemptAt = int(now.Unix())
+
+	if err == nil {
+		message.Status = model.OutboxMessageDone
+		message.LastError = ""
+	} else {
+		message.LastError = err.Error()
+		if len(message.LastError) > maxOutboxErrorLength {
+			message.LastError = message.LastError[:maxOutboxErrorLength]
+		}
+
+		if !found || message.Attempts >= maxOutboxAttempts {
+			message.Status = model.OutboxMessageFailed
+
+			slog.Warn("OutboxProcessor > Giving up on the outbox message.", "message_id", message.Id, "kind", message.Kind, "error", err)
+		} else {
+			message.NextAttemptAt = int(now.Add(outboxRetryDelay(message.Attempts)).Unix())
+
+			slog.Error("OutboxProcessor > Failed to process the outbox message.", "message_id", message.Id, "kind", message.Kind, "error", err)
+		}
+	}
+
+	if err := message.Save(app.SqlDb, nil); err != nil {
+		slog.Error("OutboxProcessor > Failed to save the outbox message.", "message_id", message.Id, "error", err)
+	}
+
+	return message.Status == model.OutboxMessageDone, false
+}
+
+// outboxRetryDelay doubles the delay after each failed attempt: 1m, 2m, 4m... up to outboxMaxRetryDelay.
+func outboxRetryDelay(attempts int) time.Duration {
+	delay := outboxFirstRetryDelay << (attempts - 1)
+	if delay <= 0 || delay > outboxMaxRetryDelay {
+		return outboxMaxRetryDelay
+	}
+
+	return delay
+}
+
+// updateActivityDescription writes the description of the activity on Strava, and saves the updated activity. There is
+// nothing to do for activities deleted after the update was queued.
+func updateActivityDescription(app *application.App, payload string) error {
+	var update helper.ActivityDescriptionUpdate
+	if err := json.Unmarshal([]byte(payload), &update); err != nil {
+		return err
+	}
+
+	exists, err := model.ActivityExists(update.ActivityId, app.SqlDb, nil)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		slog.Info("OutboxProcessor > Skipping the description update of a deleted activity.", "activity_id", update.ActivityId)
+
+		return nil
+	}
+
+	activity, err := app.StravaSvc.UpdateActivity(update.AthleteId, update.ActivityId, map[string]any{
+		"description": update.Description,
+	}, app.SqlDb, nil)
+	if err != nil {
+		return err
+	}
+
+	// deleted while Strava was being updated
+	if err = activity.Save(app.SqlDb, nil); errors.Is(err, model.ErrRowNotFound) {
+		return nil
+	}
+
+	return err
+}
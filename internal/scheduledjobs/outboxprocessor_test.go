@@ -0,0 +1,43 @@
+package scheduledjobs
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/database/databasetest"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+func TestOutboxSkipsDescriptionsOfDeletedActivities(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		// StravaSvc is nil, a call to Strava would panic
+		app := &application.App{SqlDb: db}
+
+		update := helper.ActivityDescriptionUpdate{AthleteId: 1, ActivityId: 10, Description: "Belgrade - Novi Sad: 12.5 km"}
+		if err := helper.EnqueueOutboxMessage(helper.OutboxActivityDescription, update, db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		messages, err := model.DueOutboxMessages(int(time.Now().Unix()), maxOutboxMessagesPerRun, db, nil)
+		if err != nil || len(messages) != 1 {
+			t.Fatalf("DueOutboxMessages() = %+v, %v, want the queued message", messages, err)
+		}
+
+		processed, rateLimited := processOutboxMessage(app, &messages[0])
+		if !processed || rateLimited {
+			t.Errorf("processOutboxMessage() = %v, %v, want true, false", processed, rateLimited)
+		}
+
+		var message model.OutboxMessage
+		if _, err = message.Load(messages[0].Id, db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if message.Status != model.OutboxMessageDone || message.Attempts != 1 {
+			t.Errorf("message %+v, want done after one attempt", message)
+		}
+	})
+}
@@ -1,10 +1,32 @@
 package scheduledjobs
 
-import "github.com/miki208/stravaadventuregame/internal/application"
+import (
+	"time"
 
-func GetScheduledJobs() []application.CronJob {
-	return []application.CronJob{
-		StravaPendingActivityProcessor,
-		StravaOldActivityCleaner,
+	"github.com/miki208/stravaadventuregame/internal/application"
+)
+
+// dailyCleanupSchedule runs the cleaners once a day, at night (UTC), when the application is the least busy
+const dailyCleanupSchedule = "30 3 * * *"
+
+// GetScheduledJobs returns all jobs with their default schedules, which can be overridden in the configuration by job
+// name. Jobs without a schedule run at the default interval.
+func GetScheduledJobs() []application.ScheduledJob {
+	return []application.ScheduledJob{
+		{Name: "StravaPendingActivityProcessor", Run: StravaPendingActivityProcessor},
+		{Name: "StravaOldActivityCleaner", Run: StravaOldActivityCleaner, Schedule: mustParseCronExpression(dailyCleanupSchedule), Jitter: 10 * time.Minute},
+		{Name: "GeocodeCacheCleaner", Run: GeocodeCacheCleaner, Schedule: mustParseCronExpression(dailyCleanupSchedule), Jitter: 10 * time.Minute},
+		{Name: "CoursePlaceBuilder", Run: CoursePlaceBuilder},
+		{Name: "WebhookDeliverer", Run: WebhookDeliverer, Schedule: application.Every(time.Minute)},
+		{Name: "OutboxProcessor", Run: OutboxProcessor, Schedule: application.Every(time.Minute)},
+	}
+}
+
+func mustParseCronExpression(expr string) application.Schedule {
+	schedule, err := application.ParseCronExpression(expr)
+	if err != nil {
+		panic(err)
 	}
+
+	return schedule
 }
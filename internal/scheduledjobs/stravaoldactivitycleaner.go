@@ -1,6 +1,7 @@
 package scheduledjobs
 
 import (
+	"context"
 	"log/slog"
 	"time"
 
@@ -8,7 +9,7 @@ import (
 	"github.com/miki208/stravaadventuregame/internal/model"
 )
 
-func StravaOldActivityCleaner(app *application.App) {
+func StravaOldActivityCleaner(ctx context.Context, app *application.App, fence *application.JobFence) error {
 	deleteActivitiesOlderThan := int(time.Now().Unix()) - app.StravaSvc.GetDeleteOldActivitiesAfterDays()*24*60*60
 
 	slog.Info("StravaOldActivityCleaner started.", "deleteActivitiesOlderThan", deleteActivitiesOlderThan)
@@ -20,7 +21,7 @@ func StravaOldActivityCleaner(app *application.App) {
 	if err != nil {
 		slog.Error("Failed to retrieve activities for deletion.", "error", err)
 
-		return
+		return err
 	}
 
 	for _, activity := range activitiesForDeletion {
@@ -35,4 +36,6 @@ func StravaOldActivityCleaner(app *application.App) {
 	}
 
 	slog.Info("StravaOldActivityCleaner finished.")
+
+	return nil
 }
@@ -1,45 +1,149 @@
 package scheduledjobs
 
 import (
+	"cmp"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/miki208/stravaadventuregame/internal/application"
 	"github.com/miki208/stravaadventuregame/internal/database"
 	"github.com/miki208/stravaadventuregame/internal/helper"
 	"github.com/miki208/stravaadventuregame/internal/model"
+	"github.com/miki208/stravaadventuregame/internal/service/geocoding"
 	"github.com/miki208/stravaadventuregame/internal/service/strava"
+	"github.com/paulmach/orb"
 )
 
-func StravaPendingActivityProcessor(app *application.App) {
+// StravaPendingActivityProcessor processes queued activity events whose processing delay passed. Events of different
+// athletes are processed in parallel by a pool of workers, events of the same athlete by one worker, in order of their
+// event time.
+func StravaPendingActivityProcessor(ctx context.Context, app *application.App, fence *application.JobFence) error {
 	slog.Info("StravaPendingActivityProcessor > StravaPendingActivityProcessor started.")
 
 	evs, err := model.AllStravaWebhookEvents(app.SqlDb, nil, nil)
 	if err != nil {
 		slog.Error("StravaPendingActivityProcessor > Failed to load pending Strava webhook events.", "error", err)
 
-		return
+		return err
+	}
+
+	evsByAthlete := make(map[int64][]model.StravaWebhookEvent)
+	for _, ev := range evs {
+		evsByAthlete[ev.OwnerId] = append(evsByAthlete[ev.OwnerId], ev)
 	}
 
+	athleteEvsCh := make(chan []model.StravaWebhookEvent)
+	var rateLimited atomic.Bool
+	var workers sync.WaitGroup
+
 	processingTimeUnix := time.Now().Unix()
+	for range min(app.ActivityProcessingWorkers, len(evsByAthlete)) {
+		workers.Add(1)
+
+		go func() {
+			defer workers.Done()
+
+			for athleteEvs := range athleteEvsCh {
+				processAthleteEvents(ctx, app, fence, athleteEvs, processingTimeUnix, &rateLimited)
+			}
+		}()
+	}
+
+	for _, athleteEvs := range evsByAthlete {
+		if ctx.Err() != nil || rateLimited.Load() {
+			break
+		}
+
+		athleteEvsCh <- athleteEvs
+	}
+
+	close(athleteEvsCh)
+	workers.Wait()
+
+	pruneActivitySyncs(app)
+
+	slog.Info("StravaPendingActivityProcessor > StravaPendingActivityProcessor finished.")
+
+	if ctx.Err() != nil {
+		slog.Info("StravaPendingActivityProcessor > Shutting down, remaining events are processed after the restart.")
+
+		return ctx.Err()
+	}
+
+	if rateLimited.Load() {
+		return errors.New("stopped by the Strava rate limit, remaining events are processed in the next run")
+	}
+
+	return nil
+}
+
+// processAthleteEvents processes the events of one athlete in order of their event time. It stops at the first event
+// that can't be processed yet (its processing delay didn't pass, or it waits for its next attempt after a failure) or
+// fails, so that later events of the athlete are never applied before earlier ones. Given up (dead) events block the
+// later events of the athlete too, until an admin requeues or discards them.
+func processAthleteEvents(ctx context.Context, app *application.App, fence *application.JobFence, evs []model.StravaWebhookEvent,
+	processingTimeUnix int64, rateLimited *atomic.Bool) {
+
+	slices.SortStableFunc(evs, func(a, b model.StravaWebhookEvent) int {
+		return cmp.Compare(a.EventTime, b.EventTime)
+	})
+
 	for _, ev := range evs {
+		// the event in progress is always finished, the shutdown waits for it
+		if ctx.Err() != nil || rateLimited.Load() {
+			return
+		}
+
 		if ev.EventTime+int64(app.StravaSvc.GetProcessWebhookEventsAfterSec()) >= processingTimeUnix {
-			continue
+			return
+		}
+
+		if ev.Dead == 1 || int64(ev.NextAttemptAt) > processingTimeUnix {
+			return
 		}
 
 		slog.Info("StravaPendingActivityProcessor > Processing webhook event.", "activity_id", ev.ObjectId, "event_time", ev.EventTime, "aspect_type", ev.AspectType)
 
-		if !processOneActivity(app, &ev) {
-			break // if we got a rate limit error, we stop processing
+		applied, limited := processOneActivity(app, fence, &ev)
+		if limited {
+			rateLimited.Store(true) // all workers stop, the rate limit is shared
+
+			return
+		}
+
+		if !applied {
+			return
 		}
 	}
+}
 
-	slog.Info("StravaPendingActivityProcessor > StravaPendingActivityProcessor finished.")
+// entries of the activity sync log are kept this long
+const activitySyncRetention = 30 * 24 * time.Hour
+
+func pruneActivitySyncs(app *application.App) {
+	oldSyncs, err := model.AllActivitySyncs(app.SqlDb, nil, map[string]any{
+		"received_at": model.ComparationOperation{FieldValue: int(time.Now().Add(-activitySyncRetention).Unix()), Operation: "<"},
+	})
+	if err != nil {
+		slog.Error("StravaPendingActivityProcessor > Failed to load old activity syncs.", "error", err)
+
+		return
+	}
+
+	for _, activitySync := range oldSyncs {
+		if err = activitySync.Delete(app.SqlDb, nil); err != nil {
+			slog.Error("StravaPendingActivityProcessor > Failed to delete old activity sync.", "id", activitySync.Id, "error", err)
+		}
+	}
 }
 
 type ActivityProcessingResult int
@@ -51,45 +155,239 @@ const (
 	ActivityNotProcessed
 )
 
-func processOneActivity(app *application.App, ev *model.StravaWebhookEvent) bool {
+// String returns the result as shown to the athlete.
+func (result ActivityProcessingResult) String() string {
+	switch result {
+	case ActivityCreated:
+		return "created"
+	case ActivityUpdated:
+		return "updated"
+	case ActivityDeleted:
+		return "deleted"
+	default:
+		return "ignored"
+	}
+}
+
+// SyncStatus returns the status recorded in the activity sync log.
+func (result ActivityProcessingResult) SyncStatus() string {
+	switch result {
+	case ActivityCreated:
+		return model.ActivitySyncCreated
+	case ActivityUpdated:
+		return model.ActivitySyncUpdated
+	case ActivityDeleted:
+		return model.ActivitySyncDeleted
+	default:
+		return model.ActivitySyncIgnored
+	}
+}
+
+// activities are fetched from Strava by the workers in parallel, but applied one at a time: SQLite has a single writer,
+// and concurrent write transactions would fail instead of waiting for each other
+var applyActivityMutex sync.Mutex
+
+// processOneActivity applies the queued event, together with the adventure progress it makes, in one transaction. The
+// activity is fetched from Strava before the transaction. If anything fails, the event stays in the queue and is retried
+// later (see onActivityProcessingFailed). It returns whether the event was applied, and whether processing should stop
+// because of the Strava rate limit.
+func processOneActivity(app *application.App, fence *application.JobFence, ev *model.StravaWebhookEvent) (bool, bool) {
+	app.EventBus.PublishActivityState(ev.OwnerId, helper.ActivityStateUpdate{
+		ActivityId: ev.ObjectId,
+		AspectType: ev.AspectType,
+		State:      helper.ActivityStateProcessing,
+	})
+
+	// check if the athlete exists
+	athlete := model.NewAthlete()
+	athleteExists, err := athlete.Load(ev.OwnerId, app.SqlDb, nil)
+	if err != nil {
+		slog.Error("StravaPendingActivityProcessor > Failed to load athlete.", "athlete_id", ev.OwnerId, "error", err)
+
+		onActivityFetchFailed(app, ev, "internal error", err, true)
+
+		return false, false
+	}
+
+	var newActivity *model.Activity
+	if athleteExists && ev.AspectType != "delete" {
+		// if event is update or create, we need to fetch the activity and check if it should be accepted/modified in db
+		newActivity, err = app.StravaSvc.GetActivity(athlete.Id, ev.ObjectId, app.SqlDb, nil)
+		if err != nil {
+			stravaErr, ok := err.(*strava.StravaError)
+			if ok && stravaErr.StatusCode() == http.StatusTooManyRequests {
+				slog.Error("StravaPendingActivityProcessor > Rate limit error encountered, stopping processing.", "error", stravaErr)
+
+				onActivityFetchFailed(app, ev, "Strava rate limit reached", err, false)
+
+				return false, true
+			}
+
+			slog.Error("StravaPendingActivityProcessor > Failed to fetch activity from Strava.", "activity_id", ev.ObjectId, "error", err)
+
+			onActivityFetchFailed(app, ev, "failed to fetch the activity from Strava", err, true)
+
+			return false, false
+		}
+	}
+
+	// the new position of the adventure is known only in the transaction, but geocoding calls external services, so it
+	// must not run while the mutex is held and the transaction is open: if the position isn't named yet, the transaction is
+	// rolled back, the position is named outside of it, and the event applied again
+	namer := locationNamer{names: make(map[orb.Point]string)}
+	for {
+		applyActivityMutex.Lock()
+		applied := applyActivity(app, fence, ev, athleteExists, newActivity, &namer)
+		applyActivityMutex.Unlock()
+
+		if !namer.nameMissing(app) {
+			return applied, false
+		}
+	}
+}
+
+// the event is applied at most this many times, the position is not named after that (it changed between the runs)
+const maxActivityApplyRuns = 3
+
+// errLocationNameMissing rolls back applying the event when the new position of the adventure isn't named yet.
+var errLocationNameMissing = errors.New("location name missing")
+
+// locationNamer names positions of adventures with names geocoded before the event is applied.
+type locationNamer struct {
+	names   map[orb.Point]string
+	missing *orb.Point
+	runs    int
+}
+
+// name returns the name of the point, or errLocationNameMissing if it's not geocoded yet.
+func (namer *locationNamer) name(point orb.Point) (string, error) {
+	if name, found := namer.names[point]; found {
+		return name, nil
+	}
+
+	if namer.runs+1 >= maxActivityApplyRuns {
+		return geocoding.UnknownLocationName, nil
+	}
+
+	namer.missing = &point
+
+	return "", errLocationNameMissing
+}
+
+// nameMissing geocodes the point which was missing in the last run, and returns whether the event should be applied again.
+// Must be called with applyActivityMutex unlocked.
+func (namer *locationNamer) nameMissing(app *application.App) bool {
+	if namer.missing == nil {
+		return false
+	}
+
+	point := *namer.missing
+	namer.missing = nil
+	namer.runs++
+
+	// naming the location is not worth losing the progress, so geocoding errors are not propagated
+	name, err := app.GeocoderSvc.ReverseGeocode(point.Lon(), point.Lat())
+	if err != nil {
+		slog.Warn("StravaPendingActivityProcessor > Failed to name the current location.", "lat", point.Lat(), "lon", point.Lon(), "error", err)
+
+		name = geocoding.UnknownLocationName
+	}
+
+	namer.names[point] = name
+
+	return true
+}
+
+func onActivityFetchFailed(app *application.App, ev *model.StravaWebhookEvent, reason string, err error, countAttempt bool) {
+	applyActivityMutex.Lock()
+	defer applyActivityMutex.Unlock()
+
+	onActivityProcessingFailed(app, ev, reason, err, countAttempt)
+}
+
+// applyActivity applies the event with the activity fetched from Strava (nil for delete events, or if the athlete doesn't
+// exist). It returns true if the event was applied. If a position of the adventure needs to be named first, it's recorded
+// in the namer and the event is not applied. Must be called with applyActivityMutex locked.
+func applyActivity(app *application.App, fence *application.JobFence, ev *model.StravaWebhookEvent, athleteExists bool,
+	newActivity *model.Activity, namer *locationNamer) bool {
 	tx, err := app.SqlDb.Begin()
 	if err != nil {
 		slog.Error("StravaPendingActivityProcessor > Failed to begin main transaction.", "error", err)
 
-		return true
+		onActivityProcessingFailed(app, ev, "internal error", err, true)
+
+		return false
+	}
+
+	// a newer event of the activity could have arrived (or the event could have been discarded) while the activity was
+	// fetched, the queued event is processed again in the next run then
+	var queuedEv model.StravaWebhookEvent
+	found, err := queuedEv.Load(ev.ObjectId, app.SqlDb, tx)
+	if err != nil || !found || queuedEv.EventTime != ev.EventTime || queuedEv.AspectType != ev.AspectType {
+		tx.Rollback()
+
+		if err != nil {
+			slog.Error("StravaPendingActivityProcessor > Failed to load the queued webhook event.", "activity_id", ev.ObjectId, "error", err)
+
+			onActivityProcessingFailed(app, ev, "internal error", err, true)
+		}
+
+		return false
+	}
+
+	// a run which lost its lease must not apply the event, the instance which took the lease over applies it
+	if err = fence.Check(tx); err != nil {
+		tx.Rollback()
+
+		if errors.Is(err, application.ErrJobLeaseLost) {
+			slog.Warn("StravaPendingActivityProcessor > Not applying the webhook event, the lease of the job was lost.", "activity_id", ev.ObjectId)
+		} else {
+			slog.Error("StravaPendingActivityProcessor > Failed to check the lease of the job.", "activity_id", ev.ObjectId, "error", err)
+
+			onActivityProcessingFailed(app, ev, "internal error", err, true)
+		}
+
+		return false
 	}
 
-	defer tx.Rollback()
+	committed := false
+	failureReason := "internal error"
+	defer func() {
+		if committed {
+			return
+		}
+
+		// the retry state is saved after the failed transaction is rolled back, by a conditional update of the event
+		tx.Rollback()
+
+		if errors.Is(err, errLocationNameMissing) {
+			return
+		}
+
+		onActivityProcessingFailed(app, ev, failureReason, err, true)
+	}()
 
 	processingResult := ActivityNotProcessed
+	var sportType, syncReason string
 
 	// in all cases we need this event to be deleted from the database
 	if err = ev.Delete(app.SqlDb, tx); err != nil {
 		slog.Error("StravaPendingActivityProcessor > Failed to delete webhook event.", "activity_id", ev.ObjectId, "error", err)
 
-		return true
-	}
-
-	// check if the athlete exists
-	var athleteExists bool
-	athlete := model.NewAthlete()
-	athleteExists, err = athlete.Load(ev.OwnerId, app.SqlDb, tx)
-	if err != nil {
-		slog.Error("StravaPendingActivityProcessor > Failed to load athlete.", "athlete_id", ev.OwnerId, "error", err)
-
-		return true
+		return false
 	}
 
 	var existingActivity model.Activity
-	var newActivity *model.Activity
 
-	if athleteExists {
+	if !athleteExists {
+		syncReason = "the athlete is not registered"
+	} else {
 		var foundOld bool
 		foundOld, err = existingActivity.Load(ev.ObjectId, app.SqlDb, tx)
 		if err != nil {
 			slog.Error("StravaPendingActivityProcessor > Failed to load existing activity.", "activity_id", ev.ObjectId, "error", err)
 
-			return true
+			return false
 		}
 
 		if ev.AspectType == "delete" {
@@ -102,26 +400,15 @@ func processOneActivity(app *application.App, ev *model.StravaWebhookEvent) bool
 				if err != nil {
 					slog.Error("StravaPendingActivityProcessor > Failed to delete activity.", "activity_id", existingActivity.Id, "error", err)
 
-					return true
-				}
-			}
-		} else {
-			// if event is update or create, we need to fetch the activity and check if it should be accepted/modified in db
-			newActivity, err = app.StravaSvc.GetActivity(athlete.Id, ev.ObjectId, app.SqlDb, tx)
-			if err != nil {
-				stravaErr, ok := err.(*strava.StravaError)
-				if ok && stravaErr.StatusCode() == http.StatusTooManyRequests {
-					slog.Error("StravaPendingActivityProcessor > Rate limit error encountered, stopping processing.", "error", stravaErr)
-
 					return false
 				}
-
-				slog.Error("StravaPendingActivityProcessor > Failed to fetch activity from Strava.", "activity_id", ev.ObjectId, "error", err)
-
-				return true
+			} else {
+				syncReason = "the activity is not part of the game"
 			}
-
+		} else {
+			// if event is update or create, the activity fetched from Strava decides if it should be accepted/modified in db
 			shouldAcceptNew := slices.Contains(app.SupportedActivityTypes, newActivity.SportType)
+			sportType = newActivity.SportType
 
 			if shouldAcceptNew && foundOld && ev.AspectType == "update" {
 				processingResult = ActivityUpdated
@@ -133,57 +420,184 @@ func processOneActivity(app *application.App, ev *model.StravaWebhookEvent) bool
 				err = newActivity.Save(app.SqlDb, tx)
 			} else if !shouldAcceptNew && foundOld && ev.AspectType == "update" {
 				processingResult = ActivityDeleted
+				syncReason = newActivity.SportType + " is not a supported activity type"
 
 				err = existingActivity.Delete(app.SqlDb, tx)
+			} else if !shouldAcceptNew {
+				syncReason = newActivity.SportType + " is not a supported activity type"
+			} else if foundOld {
+				syncReason = "the activity is already part of the game"
+			} else {
+				syncReason = "the activity is not part of the game"
 			}
 
 			if err != nil {
 				slog.Error("StravaPendingActivityProcessor > Failed to process activity.", "processing_result", processingResult, "activity_id", newActivity.Id, "aspect_type", ev.AspectType, "error", err)
 
-				return true
+				return false
 			}
 		}
 	}
 
-	err = database.CommitOrRollbackSQLiteTransaction(tx)
-	if err != nil {
-		slog.Error("StravaPendingActivityProcessor > Failed to commit transaction.", "error", err)
-
-		return true
-	}
+	// the progress is made in the same transaction, so that it's not lost if something fails
+	var progressedAdventure *model.Adventure
+	progressActivity := newActivity
+	var eventType string
 
 	switch processingResult {
 	case ActivityDeleted:
-		onActivityDeleted(app, &existingActivity)
+		progressedAdventure, err = onActivityDeleted(app, &existingActivity, namer, tx)
+		progressActivity, eventType = &existingActivity, "delete"
 	case ActivityCreated:
-		onActivityCreated(app, newActivity)
+		progressedAdventure, err = onActivityCreated(app, newActivity, namer, tx)
+		eventType = "create"
 	case ActivityUpdated:
-		onActivityUpdated(app, &existingActivity, newActivity)
+		progressedAdventure, err = onActivityUpdated(app, &existingActivity, newActivity, namer, tx)
+		eventType = "update"
+	}
+
+	if errors.Is(err, errLocationNameMissing) {
+		return false
+	}
+
+	if err != nil {
+		slog.Error("StravaPendingActivityProcessor > Failed to update the adventure progress.", "activity_id", ev.ObjectId, "error", err)
+
+		failureReason = "failed to update the adventure progress"
+
+		return false
+	}
+
+	if progressedAdventure != nil {
+		if err = onProgressMade(progressedAdventure, progressActivity, app, eventType, tx); err != nil {
+			slog.Error("StravaPendingActivityProcessor > Failed to queue side effects of the progress.", "activity_id", ev.ObjectId, "error", err)
+
+			failureReason = "failed to update the adventure progress"
+
+			return false
+		}
 	}
 
+	err = helper.ResolveActivitySync(ev.OwnerId, ev.ObjectId, processingResult.SyncStatus(), sportType, syncReason, app.SqlDb, tx)
+	if err != nil {
+		slog.Error("StravaPendingActivityProcessor > Failed to record activity sync.", "activity_id", ev.ObjectId, "error", err)
+
+		return false
+	}
+
+	err = database.CommitOrRollbackTransaction(tx)
+	if err != nil {
+		slog.Error("StravaPendingActivityProcessor > Failed to commit transaction.", "error", err)
+
+		return false
+	}
+
+	committed = true
+
+	if progressedAdventure != nil {
+		app.EventBus.PublishAdventurePosition(progressedAdventure.AthleteId, helper.NewAdventurePositionUpdate(progressedAdventure))
+	}
+
+	app.EventBus.PublishActivityState(ev.OwnerId, helper.ActivityStateUpdate{
+		ActivityId: ev.ObjectId,
+		AspectType: ev.AspectType,
+		State:      helper.ActivityStateApplied,
+		Result:     processingResult.String(),
+	})
+
 	return true
 }
 
-func onActivityDeleted(app *application.App, activity *model.Activity) {
-	slog.Info("StravaPendingActivityProcessor > Activity deleted.", "activity_id", activity)
+const (
+	maxActivityProcessingAttempts = 6
+	activityFirstRetryDelay       = time.Minute
+	activityMaxRetryDelay         = 6 * time.Hour
+	maxActivityErrorLength        = 500
+)
 
-	tx, err := app.SqlDb.Begin()
-	if err != nil {
-		slog.Error("StravaPendingActivityProcessor > (onActivityDeleted) Failed to begin transaction.", "activity_id", activity.Id, "error", err)
+// onActivityProcessingFailed records the failed attempt in the retry state of the queued event, which is retried later
+// with exponential backoff, or given up (dead) after maxActivityProcessingAttempts. Attempts failed for reasons that have
+// nothing to do with the event itself (e.g. the Strava rate limit) are not counted.
+func onActivityProcessingFailed(app *application.App, ev *model.StravaWebhookEvent, reason string, err error, countAttempt bool) {
+	dead := false
 
-		return
+	if countAttempt {
+		previousAttempts := ev.Attempts
+		now := time.Now()
+
+		ev.Attempts++
+		ev.FailedAt = int(now.Unix())
+
+		ev.LastError = reason
+		if err != nil {
+			ev.LastError += ": " + err.Error()
+		}
+
+		if len(ev.LastError) > maxActivityErrorLength {
+			ev.LastError = ev.LastError[:maxActivityErrorLength]
+		}
+
+		if ev.Attempts >= maxActivityProcessingAttempts {
+			dead = true
+			ev.Dead = 1
+
+			slog.Warn("StravaPendingActivityProcessor > Giving up on the webhook event.", "activity_id", ev.ObjectId, "attempts", ev.Attempts)
+		} else {
+			ev.NextAttemptAt = int(now.Add(activityRetryDelay(ev.Attempts)).Unix())
+		}
+
+		saved, saveErr := ev.SaveRetryState(previousAttempts, app.SqlDb, nil)
+		if saveErr != nil {
+			slog.Error("StravaPendingActivityProcessor > Failed to save the retry state.", "activity_id", ev.ObjectId, "error", saveErr)
+		} else if !saved {
+			// the outcome of the newer event, requeue or discard is what the athlete sees
+			slog.Info("StravaPendingActivityProcessor > The failed webhook event was replaced, requeued or discarded meanwhile.", "activity_id", ev.ObjectId)
+
+			return
+		}
+	}
+
+	stateUpdate := helper.ActivityStateUpdate{
+		ActivityId: ev.ObjectId,
+		AspectType: ev.AspectType,
+		State:      helper.ActivityStatePending,
+		Error:      "processing failed, it will be retried",
+	}
+
+	syncReason := reason + ", it will be retried"
+	if dead {
+		stateUpdate.Error = "processing failed too many times"
+		syncReason = fmt.Sprintf("%s, given up after %d attempts", reason, maxActivityProcessingAttempts)
+	}
+
+	app.EventBus.PublishActivityState(ev.OwnerId, stateUpdate)
+
+	if syncErr := helper.ResolveActivitySync(ev.OwnerId, ev.ObjectId, model.ActivitySyncFailed, "", syncReason, app.SqlDb, nil); syncErr != nil {
+		slog.Error("StravaPendingActivityProcessor > Failed to record activity sync.", "activity_id", ev.ObjectId, "error", syncErr)
+	}
+}
+
+// activityRetryDelay doubles the delay after each failed attempt: 1m, 2m, 4m... up to activityMaxRetryDelay.
+func activityRetryDelay(attempts int) time.Duration {
+	delay := activityFirstRetryDelay << (attempts - 1)
+	if delay <= 0 || delay > activityMaxRetryDelay {
+		return activityMaxRetryDelay
 	}
 
-	defer tx.Rollback()
+	return delay
+}
+
+// onActivityDeleted updates the progress of the started adventure in the transaction. It returns the adventure if any progress is
+// made, nil otherwise.
+func onActivityDeleted(app *application.App, activity *model.Activity, namer *locationNamer, tx *sql.Tx) (*model.Adventure, error) {
+	slog.Info("StravaPendingActivityProcessor > Activity deleted.", "activity_id", activity)
 
 	startedAdventure, err := model.AllAdventures(app.SqlDb, tx, map[string]any{
 		"athlete_id": activity.AthleteId,
 		"completed":  0,
 	})
 	if err != nil {
-		slog.Error("StravaPendingActivityProcessor > (onActivityDeleted) Failed to load started adventures.", "athlete_id", activity.AthleteId, "error", err)
-
-		return
+		return nil, fmt.Errorf("failed to load started adventures: %w", err)
 	}
 
 	progressIsMade := false
@@ -201,51 +615,32 @@ func onActivityDeleted(app *application.App, activity *model.Activity) {
 		if startedAdventure[0].CurrentDistance != oldTotalDistance {
 			progressIsMade = true
 
-			err = onTotalDistanceUpdated(&startedAdventure[0], activity, oldTotalDistance, app, tx)
+			err = onTotalDistanceUpdated(&startedAdventure[0], activity, oldTotalDistance, app, namer, tx)
 			if err != nil {
-				slog.Error("StravaPendingActivityProcessor > (onActivityDeleted) Failed to update state on total distance updated.", "error", err)
-
-				return
+				return nil, fmt.Errorf("failed to update state on total distance updated: %w", err)
 			}
 		}
 	}
 
-	if err = database.CommitOrRollbackSQLiteTransaction(tx); err != nil {
-		slog.Error("StravaPendingActivityProcessor > (onActivityDeleted) Failed to commit transaction with updated progress.", "error", err)
-
-		return
+	if !progressIsMade {
+		return nil, nil
 	}
 
-	if progressIsMade {
-		if err = onProgressCommited(&startedAdventure[0], activity, app, "delete"); err != nil {
-			slog.Error("StravaPendingActivityProcessor > (onActivityDeleted) Error occurred on trigger for commited progress.", "error", err)
-
-			return
-		}
-	}
+	return &startedAdventure[0], nil
 }
 
-func onActivityCreated(app *application.App, activity *model.Activity) {
+// onActivityCreated updates the progress of the started adventure in the transaction. It returns the adventure if any progress is
+// made, nil otherwise.
+func onActivityCreated(app *application.App, activity *model.Activity, namer *locationNamer, tx *sql.Tx) (*model.Adventure, error) {
 	slog.Info("StravaPendingActivityProcessor > Activity created.", "activity_id", activity.Id)
 
-	tx, err := app.SqlDb.Begin()
-	if err != nil {
-		slog.Error("StravaPendingActivityProcessor > (onActivityCreated) Failed to begin transaction.", "activity_id", activity.Id, "error", err)
-
-		return
-	}
-
-	defer tx.Rollback()
-
 	// check if there is any started adventure
 	startedAdventure, err := model.AllAdventures(app.SqlDb, tx, map[string]any{
 		"athlete_id": activity.AthleteId,
 		"completed":  0,
 	})
 	if err != nil {
-		slog.Error("StravaPendingActivityProcessor > (onActivityCreated) Failed to load started adventures.", "athlete_id", activity.AthleteId, "error", err)
-
-		return
+		return nil, fmt.Errorf("failed to load started adventures: %w", err)
 	}
 
 	progressIsMade := false
@@ -260,51 +655,33 @@ func onActivityCreated(app *application.App, activity *model.Activity) {
 		if oldTotalDistance != startedAdventure[0].CurrentDistance {
 			progressIsMade = true
 
-			err = onTotalDistanceUpdated(&startedAdventure[0], activity, oldTotalDistance, app, tx)
+			err = onTotalDistanceUpdated(&startedAdventure[0], activity, oldTotalDistance, app, namer, tx)
 			if err != nil {
-				slog.Error("StravaPendingActivityProcessor > (onActivityCreated) Failed to update state on total distance updated.", "error", err)
-
-				return
+				return nil, fmt.Errorf("failed to update state on total distance updated: %w", err)
 			}
 		}
 	}
 
-	if err = database.CommitOrRollbackSQLiteTransaction(tx); err != nil {
-		slog.Error("StravaPendingActivityProcessor > (onActivityCreated) Failed to commit transaction with updated progress.", "error", err)
-
-		return
+	if !progressIsMade {
+		return nil, nil
 	}
 
-	if progressIsMade {
-		if err = onProgressCommited(&startedAdventure[0], activity, app, "create"); err != nil {
-			slog.Error("StravaPendingActivityProcessor > (onActivityCreated) Error occurred on trigger for commited progress.", "error", err)
-
-			return
-		}
-	}
+	return &startedAdventure[0], nil
 }
 
-func onActivityUpdated(app *application.App, oldActivity *model.Activity, newActivity *model.Activity) {
+// onActivityUpdated updates the progress of the started adventure in the transaction. It returns the adventure if any progress is
+// made, nil otherwise.
+func onActivityUpdated(app *application.App, oldActivity *model.Activity, newActivity *model.Activity, namer *locationNamer,
+	tx *sql.Tx) (*model.Adventure, error) {
 	slog.Info("StravaPendingActivityProcessor > Activity updated.", "activity_id", newActivity.Id)
 
-	tx, err := app.SqlDb.Begin()
-	if err != nil {
-		slog.Error("StravaPendingActivityProcessor > (onActivityUpdated) Failed to begin transaction.", "activity_id", newActivity.Id, "error", err)
-
-		return
-	}
-
-	defer tx.Rollback()
-
 	// check if there is any started adventure
 	startedAdventure, err := model.AllAdventures(app.SqlDb, tx, map[string]any{
 		"athlete_id": newActivity.AthleteId,
 		"completed":  0,
 	})
 	if err != nil {
-		slog.Error("StravaPendingActivityProcessor > (onActivityUpdated) Failed to load started adventures.", "athlete_id", newActivity.AthleteId, "error", err)
-
-		return
+		return nil, fmt.Errorf("failed to load started adventures: %w", err)
 	}
 
 	progressIsMade := false
@@ -330,36 +707,32 @@ func onActivityUpdated(app *application.App, oldActivity *model.Activity, newAct
 		if oldTotalDistance != startedAdventure[0].CurrentDistance {
 			progressIsMade = true
 
-			err = onTotalDistanceUpdated(&startedAdventure[0], newActivity, oldTotalDistance, app, tx)
+			err = onTotalDistanceUpdated(&startedAdventure[0], newActivity, oldTotalDistance, app, namer, tx)
 			if err != nil {
-				slog.Error("StravaPendingActivityProcessor > (onActivityUpdated) Failed to update state on total distance updated.", "error", err)
-
-				return
+				return nil, fmt.Errorf("failed to update state on total distance updated: %w", err)
 			}
 		}
 	}
 
-	if err = database.CommitOrRollbackSQLiteTransaction(tx); err != nil {
-		slog.Error("StravaPendingActivityProcessor > (onActivityUpdated) Failed to commit transaction with updated progress.", "error", err)
-
-		return
+	if !progressIsMade {
+		return nil, nil
 	}
 
-	if progressIsMade {
-		if err = onProgressCommited(&startedAdventure[0], newActivity, app, "update"); err != nil {
-			slog.Error("StravaPendingActivityProcessor > (onActivityUpdated) Error occurred on trigger for commited progress.", "error", err)
-
-			return
-		}
-	}
+	return &startedAdventure[0], nil
 }
 
-func onTotalDistanceUpdated(adventure *model.Adventure, activity *model.Activity, oldTotalDistance float32, app *application.App, tx *sql.Tx) error {
+func onTotalDistanceUpdated(adventure *model.Adventure, activity *model.Activity, oldTotalDistance float32, app *application.App,
+	namer *locationNamer, tx *sql.Tx) error {
+	completedNow := false
 	if adventure.CurrentDistance >= adventure.TotalDistance {
+		completedNow = true
+
 		adventure.Completed = 1
 		adventure.CurrentDistance = adventure.TotalDistance
 
-		onAdventureCompleted(adventure, activity, app, tx)
+		if err := onAdventureCompleted(adventure, activity, app, tx); err != nil {
+			return err
+		}
 	} else {
 		if adventure.CurrentDistance == 0 {
 			var startLocation model.Location
@@ -377,8 +750,7 @@ func onTotalDistanceUpdated(adventure *model.Adventure, activity *model.Activity
 			adventure.CurrentLocationIndexOnRoute = 0
 			adventure.CurrentLocationName = startLocation.Name
 		} else {
-			courseDbName := fmt.Sprintf("%d-%d", min(adventure.StartLocation, adventure.EndLocation),
-				max(adventure.StartLocation, adventure.EndLocation))
+			courseDbName := model.CourseDbName(adventure.StartLocation, adventure.EndLocation)
 
 			var route *model.DirectionsRoute = model.NewDirectionsRoute()
 			err := app.FileDb.Read("course", courseDbName, route)
@@ -386,14 +758,14 @@ func onTotalDistanceUpdated(adventure *model.Adventure, activity *model.Activity
 				return err
 			}
 
-			routePolyline, err := helper.DecodePolyline(route.Geometry, adventure.StartLocation > adventure.EndLocation)
+			routePolyline, _, err := helper.DecodePolyline(route.Geometry, route.Elevation, adventure.StartLocation > adventure.EndLocation)
 			if err != nil {
 				return err
 			}
 
 			currentPoint, index := helper.PointAndIndexAtDistanceAlongLine(routePolyline, float64(adventure.CurrentDistance*1000))
 
-			geocodeResults, err := app.OrsSvc.ReverseGeocode(currentPoint.Lon(), currentPoint.Lat(), 10, "country,region,locality,localadmin")
+			locationName, err := namer.name(currentPoint)
 			if err != nil {
 				return err
 			}
@@ -401,11 +773,102 @@ func onTotalDistanceUpdated(adventure *model.Adventure, activity *model.Activity
 			adventure.CurrentLocationLat = currentPoint.Lat()
 			adventure.CurrentLocationLon = currentPoint.Lon()
 			adventure.CurrentLocationIndexOnRoute = index
-			adventure.CurrentLocationName = helper.GetPreferedLocationName(geocodeResults)
+			adventure.CurrentLocationName = locationName
 		}
 	}
 
-	return adventure.Save(app.SqlDb, tx)
+	newlyPassedPlaces, err := updatePassedPlaces(adventure, activity, app, tx)
+	if err != nil {
+		return err
+	}
+
+	if err = adventure.Save(app.SqlDb, tx); err != nil {
+		return err
+	}
+
+	return enqueueProgressWebhookEvents(adventure, activity, newlyPassedPlaces, completedNow, app, tx)
+}
+
+// enqueueProgressWebhookEvents queues webhook events for the progress, in the same transaction as the progress itself.
+func enqueueProgressWebhookEvents(adventure *model.Adventure, activity *model.Activity, newlyPassedPlaces []model.AdventurePassedPlace,
+	completedNow bool, app *application.App, tx *sql.Tx) error {
+	if err := helper.EnqueueWebhookEvent(helper.WebhookEventProgress, adventure, activity, nil, app.SqlDb, tx); err != nil {
+		return err
+	}
+
+	for _, passedPlace := range newlyPassedPlaces {
+		milestone := helper.WebhookMilestone{Name: passedPlace.Name, Km: passedPlace.Km}
+
+		if err := helper.EnqueueWebhookEvent(helper.WebhookEventMilestoneReached, adventure, activity, &milestone, app.SqlDb, tx); err != nil {
+			return err
+		}
+	}
+
+	if completedNow {
+		return helper.EnqueueWebhookEvent(helper.WebhookEventAdventureCompleted, adventure, activity, nil, app.SqlDb, tx)
+	}
+
+	return nil
+}
+
+// updatePassedPlaces records places along the course that the adventure has reached (crediting the given activity),
+// and forgets the ones it is no longer past (e.g. when an activity gets deleted). Newly recorded places are returned.
+func updatePassedPlaces(adventure *model.Adventure, activity *model.Activity, app *application.App, tx *sql.Tx) ([]model.AdventurePassedPlace, error) {
+	places, found, err := helper.LoadCoursePlaces(app.FileDb, adventure)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	passedPlaces, err := model.AllAdventurePassedPlaces(app.SqlDb, tx, map[string]any{
+		"athlete_id":     adventure.AthleteId,
+		"start_location": adventure.StartLocation,
+		"end_location":   adventure.EndLocation,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	isPassed := func(km float64) bool {
+		return adventure.Completed == 1 || km <= float64(adventure.CurrentDistance)
+	}
+
+	alreadyPassed := make(map[float64]bool)
+	for _, passedPlace := range passedPlaces {
+		if isPassed(passedPlace.Km) {
+			alreadyPassed[passedPlace.Km] = true
+
+			continue
+		}
+
+		if err = passedPlace.Delete(app.SqlDb, tx); err != nil {
+			return nil, err
+		}
+	}
+
+	var newlyPassedPlaces []model.AdventurePassedPlace
+	for _, place := range places {
+		if alreadyPassed[place.Km] || !isPassed(place.Km) {
+			continue
+		}
+
+		passedPlace := model.AdventurePassedPlace{
+			AthleteId:     adventure.AthleteId,
+			StartLocation: adventure.StartLocation,
+			EndLocation:   adventure.EndLocation,
+			Km:            place.Km,
+			Name:          place.Name,
+			ActivityId:    activity.Id,
+			PassedDate:    activity.StartDate,
+		}
+
+		if err = passedPlace.Save(app.SqlDb, tx); err != nil {
+			return nil, err
+		}
+
+		newlyPassedPlaces = append(newlyPassedPlaces, passedPlace)
+	}
+
+	return newlyPassedPlaces, nil
 }
 
 func onAdventureCompleted(adventure *model.Adventure, activity *model.Activity, app *application.App, tx *sql.Tx) error {
@@ -429,11 +892,13 @@ func onAdventureCompleted(adventure *model.Adventure, activity *model.Activity,
 	return nil
 }
 
-func onProgressCommited(adventure *model.Adventure, activity *model.Activity, app *application.App, eventType string) error {
+// onProgressMade queues side effects of the progress in the transaction which makes it, so that they can't get lost. They
+// are performed by the OutboxProcessor.
+func onProgressMade(adventure *model.Adventure, activity *model.Activity, app *application.App, eventType string, tx *sql.Tx) error {
 	// for now, just update the activity description with the adventure progress (if enabled)
 
 	var athleteSettings model.AthleteSettings
-	found, err := athleteSettings.Load(adventure.AthleteId, app.SqlDb, nil)
+	found, err := athleteSettings.Load(adventure.AthleteId, app.SqlDb, tx)
 	if err != nil {
 		return err
 	}
@@ -451,12 +916,12 @@ func onProgressCommited(adventure *model.Adventure, activity *model.Activity, ap
 	}
 
 	var locationStart, locationEnd model.Location
-	foundStart, err := locationStart.Load(adventure.StartLocation, app.SqlDb, nil)
+	foundStart, err := locationStart.Load(adventure.StartLocation, app.SqlDb, tx)
 	if err != nil {
 		return err
 	}
 
-	foundEnd, err := locationEnd.Load(adventure.EndLocation, app.SqlDb, nil)
+	foundEnd, err := locationEnd.Load(adventure.EndLocation, app.SqlDb, tx)
 	if err != nil {
 		return err
 	}
@@ -473,8 +938,29 @@ func onProgressCommited(adventure *model.Adventure, activity *model.Activity, ap
 		descriptionText = fmt.Sprintf("Adventure in progress!\nI am at %s (started from %s, at %s (GMT), going to %s).\nDistance traveled: %.2f/%.2f km.",
 			adventure.CurrentLocationName, locationStart.Name, time.Unix(int64(adventure.StartDate), 0).UTC().Format(time.DateTime), locationEnd.Name,
 			adventure.CurrentDistance, adventure.TotalDistance)
+
+		placesText, err := describePlacesAlongCourse(adventure, activity, app, tx)
+		if err != nil {
+			return err
+		}
+
+		descriptionText += placesText
+
+		ascentText, err := describeAscentAlongCourse(adventure, app)
+		if err != nil {
+			return err
+		}
+
+		descriptionText += ascentText
+	}
+
+	mapImage, err := helper.AdventureMapImageOf(adventure, app.SqlDb, tx)
+	if err != nil {
+		return err
 	}
 
+	descriptionText += "\nMap: " + app.GetFullAdventureMapImageUrl(mapImage.ImageId)
+
 	var fullDescription string
 	if activity.Description != "" {
 		fullDescription = activity.Description + "\n\n" + descriptionText
@@ -482,18 +968,73 @@ func onProgressCommited(adventure *model.Adventure, activity *model.Activity, ap
 		fullDescription = descriptionText
 	}
 
-	activity, err = app.StravaSvc.UpdateActivity(activity.AthleteId, activity.Id, map[string]any{
-		"description": fullDescription,
-	}, app.SqlDb, nil)
+	return helper.EnqueueOutboxMessage(helper.OutboxActivityDescription, helper.ActivityDescriptionUpdate{
+		AthleteId:   activity.AthleteId,
+		ActivityId:  activity.Id,
+		Description: fullDescription,
+	}, app.SqlDb, tx)
+}
+
+// describePlacesAlongCourse returns e.g. "\nPassed through X, Y, Z in this run; next: W in 12.4 km.",
+// or an empty string if places along the course are not known.
+func describePlacesAlongCourse(adventure *model.Adventure, activity *model.Activity, app *application.App, tx *sql.Tx) (string, error) {
+	places, found, err := helper.LoadCoursePlaces(app.FileDb, adventure)
+	if err != nil || !found {
+		return "", err
+	}
 
+	passedInThisRun, err := model.AllAdventurePassedPlaces(app.SqlDb, tx, map[string]any{
+		"athlete_id":     adventure.AthleteId,
+		"start_location": adventure.StartLocation,
+		"end_location":   adventure.EndLocation,
+		"activity_id":    activity.Id,
+	})
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	var parts []string
+	if len(passedInThisRun) > 0 {
+		var names []string
+		for _, passedPlace := range passedInThisRun {
+			names = append(names, passedPlace.Name)
+		}
+
+		parts = append(parts, "passed through "+strings.Join(names, ", ")+" in this run")
+	}
+
+	nextPlace, nextPlaceInKm, nextFound := helper.NextCoursePlace(places, float64(adventure.CurrentDistance))
+	if nextFound {
+		parts = append(parts, fmt.Sprintf("next: %s in %.1f km", nextPlace.Name, nextPlaceInKm))
+	}
+
+	if len(parts) == 0 {
+		return "", nil
 	}
 
-	err = activity.Save(app.SqlDb, nil)
+	text := strings.Join(parts, "; ")
+
+	return "\n" + strings.ToUpper(text[:1]) + text[1:] + ".", nil
+}
+
+// describeAscentAlongCourse returns e.g. "\nClimbed: 340/1250 m.", or an empty string if the course has no elevation.
+func describeAscentAlongCourse(adventure *model.Adventure, app *application.App) (string, error) {
+	route := model.NewDirectionsRoute()
+	if err := app.FileDb.Read("course", model.CourseDbName(adventure.StartLocation, adventure.EndLocation), route); err != nil {
+		return "", err
+	}
+
+	if !route.Elevation {
+		return "", nil
+	}
+
+	routePolyline, elevations, err := helper.DecodePolyline(route.Geometry, true, adventure.StartLocation > adventure.EndLocation)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return nil
+	totalAscent, _ := helper.ElevationGain(elevations)
+	ascentSoFar := helper.AscentAlongLine(routePolyline, elevations, float64(adventure.CurrentDistance*1000))
+
+	return fmt.Sprintf("\nClimbed: %.0f/%.0f m.", ascentSoFar, totalAscent), nil
 }
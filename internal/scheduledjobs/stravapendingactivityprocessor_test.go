@@ -0,0 +1,64 @@
+package scheduledjobs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/service/geocoding"
+	"github.com/paulmach/orb"
+)
+
+type countingGeocoder struct {
+	calls int
+	err   error
+}
+
+func (geocoder *countingGeocoder) ReverseGeocode(lon, lat float64) (string, error) {
+	geocoder.calls++
+
+	return "Zemun", geocoder.err
+}
+
+func TestLocationNamer(t *testing.T) {
+	geocoder := &countingGeocoder{}
+	app := &application.App{GeocoderSvc: geocoder}
+	namer := locationNamer{names: make(map[orb.Point]string)}
+	point := orb.Point{20.41, 44.84}
+
+	if _, err := namer.name(point); !errors.Is(err, errLocationNameMissing) {
+		t.Fatalf("name() of a new point = %v, want errLocationNameMissing", err)
+	}
+
+	if !namer.nameMissing(app) {
+		t.Fatal("nameMissing() = false, want true")
+	}
+
+	if name, err := namer.name(point); err != nil || name != "Zemun" {
+		t.Errorf("name() of a named point = %q, %v, want \"Zemun\"", name, err)
+	}
+
+	if namer.nameMissing(app) {
+		t.Error("nameMissing() without a missing point = true, want false")
+	}
+
+	// the position keeps changing between the runs
+	if _, err := namer.name(orb.Point{20.5, 44.9}); !errors.Is(err, errLocationNameMissing) {
+		t.Fatalf("name() of another new point = %v, want errLocationNameMissing", err)
+	}
+
+	geocoder.err = errors.New("unavailable")
+	namer.nameMissing(app)
+
+	if name, err := namer.name(orb.Point{20.5, 44.9}); err != nil || name != geocoding.UnknownLocationName {
+		t.Errorf("name() of a point which failed to geocode = %q, %v, want %q", name, err, geocoding.UnknownLocationName)
+	}
+
+	if name, err := namer.name(orb.Point{20.6, 45.0}); err != nil || name != geocoding.UnknownLocationName {
+		t.Errorf("name() in the last run = %q, %v, want %q", name, err, geocoding.UnknownLocationName)
+	}
+
+	if geocoder.calls != maxActivityApplyRuns-1 {
+		t.Errorf("%d geocoder calls, want %d", geocoder.calls, maxActivityApplyRuns-1)
+	}
+}
@@ -0,0 +1,210 @@
+package scheduledjobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+const (
+	maxWebhookDeliveriesPerRun = 100
+	maxWebhookAttempts         = 8
+	webhookFirstRetryDelay     = 30 * time.Second
+	webhookMaxRetryDelay       = 6 * time.Hour
+	webhookDeliveryRetention   = 30 * 24 * time.Hour
+	maxWebhookErrorLength      = 500
+)
+
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: rejectInternalAddresses,
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+	},
+	// redirects could lead to plain http or internal addresses, the endpoint has to be registered with the final url
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// rejectInternalAddresses makes sure that webhook urls can't be used to reach the internal network of the server.
+func rejectInternalAddresses(network string, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsMulticast() {
+		return fmt.Errorf("webhook address %s is not allowed", host)
+	}
+
+	return nil
+}
+
+// WebhookDeliverer posts queued webhook events to their endpoints. Failed deliveries are retried with exponential
+// backoff, and given up after maxWebhookAttempts. Old deliveries are removed from the log.
+func WebhookDeliverer(ctx context.Context, app *application.App, fence *application.JobFence) error {
+	now := time.Now()
+
+	deliveries, err := model.DueWebhookDeliveries(int(now.Unix()), maxWebhookDeliveriesPerRun, app.SqlDb, nil)
+	if err != nil {
+		slog.Error("WebhookDeliverer > Failed to load pending deliveries.", "error", err)
+
+		return err
+	}
+
+	delivered := 0
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			slog.Info("WebhookDeliverer > Shutting down, remaining deliveries are attempted after the restart.")
+
+			return ctx.Err()
+		}
+
+		// another instance which took the lease over delivers the events
+		if err = fence.Check(nil); err != nil {
+			slog.Warn("WebhookDeliverer > Stopping, the lease of the job was lost.", "error", err)
+
+			return err
+		}
+
+		if deliverWebhook(ctx, app, &deliveries[i]) {
+			delivered++
+		}
+	}
+
+	if len(deliveries) > 0 {
+		slog.Info("WebhookDeliverer > Webhook deliveries attempted.", "attempted", len(deliveries), "delivered", delivered)
+	}
+
+	oldDeliveries, err := model.AllWebhookDeliveries(app.SqlDb, nil, map[string]any{
+		"created_at": model.ComparationOperation{FieldValue: int(now.Add(-webhookDeliveryRetention).Unix()), Operation: "<"},
+	})
+	if err != nil {
+		slog.Error("WebhookDeliverer > Failed to load old deliveries.", "error", err)
+
+		return err
+	}
+
+	for _, delivery := range oldDeliveries {
+		if delivery.Status == model.WebhookDeliveryPending {
+			continue
+		}
+
+		if err = delivery.Delete(app.SqlDb, nil); err != nil {
+			slog.Error("WebhookDeliverer > Failed to delete old delivery.", "delivery_id", delivery.Id, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// deliverWebhook makes one delivery attempt and records its outcome. It returns true if the event was delivered.
+// An attempt cancelled by the context (e.g. on shutdown or when the lease is lost) isn't recorded.
+func deliverWebhook(ctx context.Context, app *application.App, delivery *model.WebhookDelivery) bool {
+	var endpoint model.WebhookEndpoint
+	found, err := endpoint.Load(delivery.EndpointId, app.SqlDb, nil)
+	if err != nil {
+		slog.Error("WebhookDeliverer > Failed to load the endpoint.", "delivery_id", delivery.Id, "endpoint_id", delivery.EndpointId, "error", err)
+
+		return false
+	}
+
+	if !found {
+		// the endpoint got deleted in the meantime, together with its deliveries
+		return false
+	}
+
+	now := time.Now()
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = int(now.Unix())
+	delivery.ResponseCode, err = postWebhook(ctx, &endpoint, delivery, now)
+	if err != nil && ctx.Err() != nil {
+		slog.Info("WebhookDeliverer > Delivery cancelled, it's attempted again later.", "delivery_id", delivery.Id, "endpoint_id", endpoint.Id)
+
+		return false
+	}
+
+	if err == nil {
+		delivery.Status = model.WebhookDeliveryDelivered
+		delivery.LastError = ""
+	} else {
+		delivery.LastError = err.Error()
+		if len(delivery.LastError) > maxWebhookErrorLength {
+			delivery.LastError = delivery.LastError[:maxWebhookErrorLength]
+		}
+
+		if delivery.Attempts >= maxWebhookAttempts {
+			delivery.Status = model.WebhookDeliveryFailed
+
+			slog.Warn("WebhookDeliverer > Giving up on the delivery.", "delivery_id", delivery.Id, "endpoint_id", endpoint.Id, "error", err)
+		} else {
+			delivery.NextAttemptAt = int(now.Add(webhookRetryDelay(delivery.Attempts)).Unix())
+		}
+	}
+
+	if err := delivery.Save(app.SqlDb, nil); err != nil {
+		slog.Error("WebhookDeliverer > Failed to save the delivery.", "delivery_id", delivery.Id, "error", err)
+	}
+
+	return delivery.Status == model.WebhookDeliveryDelivered
+}
+
+// webhookRetryDelay doubles the delay after each failed attempt: 30s, 1m, 2m, 4m... up to webhookMaxRetryDelay.
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookFirstRetryDelay << (attempts - 1)
+	if delay <= 0 || delay > webhookMaxRetryDelay {
+		return webhookMaxRetryDelay
+	}
+
+	return delay
+}
+
+// postWebhook posts the payload, signed with the endpoint secret. Any response other than 2xx is an error.
+func postWebhook(ctx context.Context, endpoint *model.WebhookEndpoint, delivery *model.WebhookDelivery, now time.Time) (int, error) {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "StravaAdventureGame-Webhook/1.0")
+	req.Header.Set("X-Adventure-Event", delivery.Event)
+	req.Header.Set("X-Adventure-Delivery", strconv.Itoa(delivery.Id))
+	req.Header.Set("X-Adventure-Signature", helper.SignWebhookPayload(endpoint.Secret, now.Unix(), body))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// drain a bit of the body, so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, errors.New("endpoint responded with " + resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,43 @@
+package scheduledjobs
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/database/databasetest"
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+func TestWebhookDeliveryCancelledByTheContextIsNotRecorded(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		app := &application.App{SqlDb: db}
+
+		endpoint := model.WebhookEndpoint{AthleteId: 1, Url: "https://example.com/hook", Secret: "whsec_test", Events: "progress"}
+		if err := endpoint.Save(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		delivery := model.WebhookDelivery{EndpointId: endpoint.Id, Event: "progress", Payload: `{"event":"progress"}`, Status: model.WebhookDeliveryPending}
+		if err := delivery.Save(db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if deliverWebhook(ctx, app, &delivery) {
+			t.Error("deliverWebhook() with a cancelled context = true, want false")
+		}
+
+		var saved model.WebhookDelivery
+		if _, err := saved.Load(delivery.Id, db, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if saved.Status != model.WebhookDeliveryPending || saved.Attempts != 0 || saved.LastError != "" {
+			t.Errorf("delivery %+v, want pending without attempts", saved)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package elevation
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// tiles are visited one after another along a course, so only a few of them have to be kept in memory
+const maxCachedTiles = 4
+
+const voidValue = -32768
+
+var ErrNoElevationData = errors.New("no elevation data for this point")
+
+// Dem looks up elevation in SRTM tiles (.hgt files, both 1 and 3 arc-second resolution) stored in a local directory.
+// Tiles are named after their south-west corner, e.g. N45E019.hgt.
+type Dem struct {
+	directory string
+
+	mu    sync.Mutex
+	tiles map[string]*tile
+}
+
+type tile struct {
+	size int // number of samples per row and column
+	data []byte
+}
+
+func CreateDem(directory string) *Dem {
+	return &Dem{
+		directory: directory,
+		tiles:     make(map[string]*tile),
+	}
+}
+
+// Elevation returns elevation in meters at the given point, interpolated from the four surrounding samples.
+func (dem *Dem) Elevation(lat, lon float64) (float64, error) {
+	tileLat, tileLon := math.Floor(lat), math.Floor(lon)
+
+	t, err := dem.loadTile(int(tileLat), int(tileLon))
+	if err != nil {
+		return 0, err
+	}
+
+	// rows go from north to south, columns from west to east
+	row := (tileLat + 1 - lat) * float64(t.size-1)
+	col := (lon - tileLon) * float64(t.size-1)
+
+	row0, col0 := int(math.Floor(row)), int(math.Floor(col))
+	row1, col1 := min(row0+1, t.size-1), min(col0+1, t.size-1)
+
+	var samples [4]float64
+	for i, rc := range [4][2]int{{row0, col0}, {row0, col1}, {row1, col0}, {row1, col1}} {
+		sample := int16(binary.BigEndian.Uint16(t.data[2*(rc[0]*t.size+rc[1]):]))
+		if sample == voidValue {
+			return 0, ErrNoElevationData
+		}
+
+		samples[i] = float64(sample)
+	}
+
+	rowFraction, colFraction := row-float64(row0), col-float64(col0)
+	north := samples[0] + (samples[1]-samples[0])*colFraction
+	south := samples[2] + (samples[3]-samples[2])*colFraction
+
+	return north + (south-north)*rowFraction, nil
+}
+
+func (dem *Dem) loadTile(lat, lon int) (*tile, error) {
+	name := tileName(lat, lon)
+
+	dem.mu.Lock()
+	defer dem.mu.Unlock()
+
+	if t, ok := dem.tiles[name]; ok {
+		return t, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(dem.directory, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoElevationData
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var size int
+	switch len(data) {
+	case 3601 * 3601 * 2:
+		size = 3601
+	case 1201 * 1201 * 2:
+		size = 1201
+	default:
+		return nil, fmt.Errorf("unexpected size of the elevation tile %s", name)
+	}
+
+	if len(dem.tiles) >= maxCachedTiles {
+		clear(dem.tiles)
+	}
+
+	t := &tile{size: size, data: data}
+	dem.tiles[name] = t
+
+	return t, nil
+}
+
+func tileName(lat, lon int) string {
+	latHemisphere, lonHemisphere := 'N', 'E'
+	if lat < 0 {
+		latHemisphere, lat = 'S', -lat
+	}
+
+	if lon < 0 {
+		lonHemisphere, lon = 'W', -lon
+	}
+
+	return fmt.Sprintf("%c%02d%c%03d.hgt", latHemisphere, lat, lonHemisphere, lon)
+}
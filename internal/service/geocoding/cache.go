@@ -0,0 +1,96 @@
+package geocoding
+
+import (
+	"database/sql"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+)
+
+// CachedGeocoder stores names found by the wrapped geocoder in the GeocodeCache table.
+// Points are keyed by coordinates rounded to the given number of decimals, so athletes on the same course
+// (whose positions are rarely exactly the same) share the cached names.
+type CachedGeocoder struct {
+	geocoder ReverseGeocoder
+	db       *sql.DB
+
+	ttlSec            int
+	precision         int
+	warmCourseEveryKm float64
+}
+
+func CreateCachedGeocoder(geocoder ReverseGeocoder, db *sql.DB, ttlSec, precision int, warmCourseEveryKm float64) *CachedGeocoder {
+	return &CachedGeocoder{
+		geocoder:          geocoder,
+		db:                db,
+		ttlSec:            ttlSec,
+		precision:         precision,
+		warmCourseEveryKm: warmCourseEveryKm,
+	}
+}
+
+func (cache *CachedGeocoder) GetTtlSec() int {
+	return cache.ttlSec
+}
+
+func (cache *CachedGeocoder) ReverseGeocode(lon, lat float64) (string, error) {
+	key := cache.key(lon, lat)
+
+	var entry model.GeocodeCacheEntry
+	found, err := entry.Load(key, cache.db, nil)
+	if err != nil {
+		slog.Warn("CachedGeocoder > Failed to read the cache.", "key", key, "error", err)
+	}
+
+	if found && int64(entry.CreatedAt+cache.ttlSec) > time.Now().Unix() {
+		return entry.Name, nil
+	}
+
+	name, err := cache.geocoder.ReverseGeocode(lon, lat)
+	if err != nil {
+		return "", err
+	}
+
+	entry = model.GeocodeCacheEntry{
+		Key:       key,
+		Name:      name,
+		CreatedAt: int(time.Now().Unix()),
+	}
+
+	if err = entry.Save(cache.db, nil); err != nil {
+		slog.Warn("CachedGeocoder > Failed to write to the cache.", "key", key, "error", err)
+	}
+
+	return name, nil
+}
+
+// WarmCourse geocodes points sampled every warmCourseEveryKm along the course, so later progress updates
+// on this course are served from the cache. It does nothing if warming is disabled.
+func (cache *CachedGeocoder) WarmCourse(course orb.LineString) {
+	if cache.warmCourseEveryKm <= 0 || len(course) == 0 {
+		return
+	}
+
+	courseLength := geo.LengthHaversine(course)
+
+	slog.Info("CachedGeocoder > Warming the cache for a course.", "course_length", courseLength, "every_km", cache.warmCourseEveryKm)
+
+	for distance := 0.0; distance <= courseLength; distance += cache.warmCourseEveryKm * 1000 {
+		point, _ := helper.PointAndIndexAtDistanceAlongLine(course, distance)
+
+		if _, err := cache.ReverseGeocode(point.Lon(), point.Lat()); err != nil {
+			slog.Warn("CachedGeocoder > Failed to geocode a point while warming the cache.", "lon", point.Lon(), "lat", point.Lat(), "error", err)
+		}
+	}
+
+	slog.Info("CachedGeocoder > Cache warmed for a course.")
+}
+
+func (cache *CachedGeocoder) key(lon, lat float64) string {
+	return strconv.FormatFloat(lat, 'f', cache.precision, 64) + "," + strconv.FormatFloat(lon, 'f', cache.precision, 64)
+}
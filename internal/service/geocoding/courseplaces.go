@@ -0,0 +1,54 @@
+package geocoding
+
+import (
+	"errors"
+
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+)
+
+// BuildCoursePlaces names points sampled every everyKm along the course (the last point included),
+// and keeps only the first point of every run of the same name.
+// It fails on the first geocoding error (other than place not found), so no incomplete list is stored.
+func BuildCoursePlaces(geocoder ReverseGeocoder, course orb.LineString, everyKm float64) (model.CoursePlaces, error) {
+	courseLength := geo.LengthHaversine(course)
+
+	coursePlaces := model.CoursePlaces{
+		Source:         "geocoder",
+		CourseLengthKm: courseLength / 1000,
+	}
+
+	var distances []float64
+	for distance := 0.0; distance < courseLength; distance += everyKm * 1000 {
+		distances = append(distances, distance)
+	}
+	distances = append(distances, courseLength)
+
+	for _, distance := range distances {
+		point, _ := helper.PointAndIndexAtDistanceAlongLine(course, distance)
+
+		name, err := geocoder.ReverseGeocode(point.Lon(), point.Lat())
+		if errors.Is(err, ErrPlaceNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return model.CoursePlaces{}, err
+		}
+
+		if len(coursePlaces.Places) > 0 && coursePlaces.Places[len(coursePlaces.Places)-1].Name == name {
+			continue
+		}
+
+		coursePlaces.Places = append(coursePlaces.Places, model.CoursePlace{
+			Name: name,
+			Lat:  point.Lat(),
+			Lon:  point.Lon(),
+			Km:   distance / 1000,
+		})
+	}
+
+	return coursePlaces, nil
+}
@@ -0,0 +1,56 @@
+package geocoding
+
+import (
+	"log/slog"
+)
+
+// SearchResult is a place found by its name.
+type SearchResult struct {
+	Name    string  `json:"name"`
+	Country string  `json:"country"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+}
+
+// ForwardGeocoder finds places matching the given text.
+type ForwardGeocoder interface {
+	Search(text string, limit int) ([]SearchResult, error)
+}
+
+// ChainForwardGeocoder asks geocoders in order, and returns results of the first one which finds anything.
+// Like ChainGeocoder, it falls back to the next geocoder on any error. Error is returned only if all geocoders failed.
+type ChainForwardGeocoder struct {
+	geocoders []ForwardGeocoder
+}
+
+func CreateChainForwardGeocoder(geocoders ...ForwardGeocoder) *ChainForwardGeocoder {
+	return &ChainForwardGeocoder{geocoders: geocoders}
+}
+
+func (chain *ChainForwardGeocoder) Search(text string, limit int) ([]SearchResult, error) {
+	var lastErr error
+	anySucceeded := false
+
+	for _, geocoder := range chain.geocoders {
+		results, err := geocoder.Search(text, limit)
+		if err != nil {
+			slog.Warn("ChainForwardGeocoder > Geocoder failed, falling back to the next one.", "text", text, "error", err)
+
+			lastErr = err
+
+			continue
+		}
+
+		if len(results) > 0 {
+			return results, nil
+		}
+
+		anySucceeded = true
+	}
+
+	if anySucceeded {
+		return nil, nil
+	}
+
+	return nil, lastErr
+}
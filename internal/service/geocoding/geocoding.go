@@ -0,0 +1,47 @@
+package geocoding
+
+import (
+	"errors"
+	"log/slog"
+)
+
+// UnknownLocationName is used when no geocoder was able to name a location.
+const UnknownLocationName = "unknown location"
+
+// ErrPlaceNotFound is returned by geocoders which don't know any place near the given point,
+// so the next geocoder in the chain can be tried.
+var ErrPlaceNotFound = errors.New("place not found")
+
+// ReverseGeocoder finds a human readable name of the place at the given point.
+type ReverseGeocoder interface {
+	ReverseGeocode(lon, lat float64) (string, error)
+}
+
+// ChainGeocoder asks geocoders in order, and returns the first name found.
+// Unlike the routing chain, it falls back to the next geocoder on any error, since geocoding is never critical.
+type ChainGeocoder struct {
+	geocoders []ReverseGeocoder
+}
+
+func CreateChainGeocoder(geocoders ...ReverseGeocoder) *ChainGeocoder {
+	return &ChainGeocoder{geocoders: geocoders}
+}
+
+func (chain *ChainGeocoder) ReverseGeocode(lon, lat float64) (string, error) {
+	lastErr := ErrPlaceNotFound
+
+	for _, geocoder := range chain.geocoders {
+		name, err := geocoder.ReverseGeocode(lon, lat)
+		if err == nil {
+			return name, nil
+		}
+
+		if !errors.Is(err, ErrPlaceNotFound) {
+			slog.Warn("ChainGeocoder > Geocoder failed, falling back to the next one.", "lon", lon, "lat", lat, "error", err)
+		}
+
+		lastErr = err
+	}
+
+	return "", lastErr
+}
@@ -0,0 +1,214 @@
+package geocoding
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/miki208/stravaadventuregame/internal/model"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+)
+
+const kmPerDegreeOfLatitude = 111.32
+
+// LocalReverseGeocoder names places using the local gazetteer (Place table), by finding the nearest known place.
+type LocalReverseGeocoder struct {
+	db            *sql.DB
+	maxDistanceKm float64
+}
+
+func CreateLocalReverseGeocoder(db *sql.DB, maxDistanceKm float64) *LocalReverseGeocoder {
+	return &LocalReverseGeocoder{
+		db:            db,
+		maxDistanceKm: maxDistanceKm,
+	}
+}
+
+func (geocoder *LocalReverseGeocoder) ReverseGeocode(lon, lat float64) (string, error) {
+	place, found, err := geocoder.nearestPlace(lon, lat)
+	if err != nil {
+		return "", err
+	}
+
+	if !found {
+		return "", ErrPlaceNotFound
+	}
+
+	if place.Country != "" {
+		return place.Name + ", " + place.Country, nil
+	}
+
+	return place.Name, nil
+}
+
+// nearestPlace searches for places inside of a growing bounding box around the point,
+// so in densely populated areas only a handful of rows is scanned.
+func (geocoder *LocalReverseGeocoder) nearestPlace(lon, lat float64) (model.Place, bool, error) {
+	point := orb.Point{lon, lat}
+
+	for radiusKm := min(5, geocoder.maxDistanceKm); ; radiusKm = min(radiusKm*4, geocoder.maxDistanceKm) {
+		latDelta := radiusKm / kmPerDegreeOfLatitude
+		lonDelta := radiusKm / (kmPerDegreeOfLatitude * math.Max(math.Cos(lat*math.Pi/180), 0.01))
+
+		places, err := model.AllPlacesInBoundingBox(lat-latDelta, lat+latDelta, lon-lonDelta, lon+lonDelta, geocoder.db, nil)
+		if err != nil {
+			return model.Place{}, false, err
+		}
+
+		var nearest model.Place
+		nearestDistance := math.MaxFloat64
+		for _, place := range places {
+			distance := geo.DistanceHaversine(point, orb.Point{place.Lon, place.Lat})
+			if distance < nearestDistance {
+				nearest, nearestDistance = place, distance
+			}
+		}
+
+		// a place found in the box corner may be farther than an unseen one right outside of the box edge
+		if nearestDistance <= radiusKm*1000 {
+			return nearest, true, nil
+		}
+
+		if radiusKm >= geocoder.maxDistanceKm {
+			return model.Place{}, false, nil
+		}
+	}
+}
+
+// ImportGeoNames imports populated places (feature class P) from a GeoNames dump (e.g. cities500.txt).
+// GeoNames ids are kept, so importing the same dump again updates existing places. Returns number of imported places.
+func ImportGeoNames(reader io.Reader, db *sql.DB, tx *sql.Tx) (int, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024) // alternate names can make lines quite long
+
+	imported := 0
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 15 || fields[6] != "P" {
+			continue
+		}
+
+		id, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return imported, err
+		}
+
+		lat, err := strconv.ParseFloat(fields[4], 64)
+		if err != nil {
+			return imported, err
+		}
+
+		lon, err := strconv.ParseFloat(fields[5], 64)
+		if err != nil {
+			return imported, err
+		}
+
+		population, _ := strconv.ParseInt(fields[14], 10, 64)
+
+		place := model.Place{
+			Id:         id,
+			Name:       fields[1],
+			Country:    fields[8],
+			Lat:        lat,
+			Lon:        lon,
+			Population: population,
+		}
+
+		if err = place.Save(db, tx); err != nil {
+			return imported, err
+		}
+
+		imported++
+	}
+
+	return imported, scanner.Err()
+}
+
+// ImportPlacesCsv imports places from a CSV file with name, lat, lon and optional country and population columns
+// (e.g. exported from OSM place nodes). The header row is skipped. Returns number of imported places.
+func ImportPlacesCsv(reader io.Reader, db *sql.DB, tx *sql.Tx) (int, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+
+	imported := 0
+	for line := 1; ; line++ {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return imported, err
+		}
+
+		if len(record) < 3 {
+			continue
+		}
+
+		lat, errLat := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		lon, errLon := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+		if errLat != nil || errLon != nil {
+			if line == 1 {
+				continue // header
+			}
+
+			return imported, errors.Join(errLat, errLon)
+		}
+
+		place := model.Place{
+			Name: strings.TrimSpace(record[0]),
+			Lat:  lat,
+			Lon:  lon,
+		}
+
+		if len(record) > 3 {
+			place.Country = strings.TrimSpace(record[3])
+		}
+
+		if len(record) > 4 {
+			place.Population, _ = strconv.ParseInt(strings.TrimSpace(record[4]), 10, 64)
+		}
+
+		if err = place.Save(db, tx); err != nil {
+			return imported, err
+		}
+
+		imported++
+	}
+
+	return imported, nil
+}
+
+// LocalForwardGeocoder searches places in the local gazetteer by the beginning of their names.
+type LocalForwardGeocoder struct {
+	db *sql.DB
+}
+
+func CreateLocalForwardGeocoder(db *sql.DB) *LocalForwardGeocoder {
+	return &LocalForwardGeocoder{db: db}
+}
+
+func (geocoder *LocalForwardGeocoder) Search(text string, limit int) ([]SearchResult, error) {
+	places, err := model.AllPlacesByNamePrefix(strings.TrimSpace(text), limit, geocoder.db, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for _, place := range places {
+		results = append(results, SearchResult{
+			Name:    place.Name,
+			Country: place.Country,
+			Lat:     place.Lat,
+			Lon:     place.Lon,
+		})
+	}
+
+	return results, nil
+}
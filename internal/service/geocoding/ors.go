@@ -0,0 +1,61 @@
+package geocoding
+
+import (
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/service/openrouteservice"
+)
+
+// OrsReverseGeocoder names places using the OpenRouteService (Pelias) reverse geocoding API.
+type OrsReverseGeocoder struct {
+	orsSvc *openrouteservice.OpenRouteService
+}
+
+func CreateOrsReverseGeocoder(orsSvc *openrouteservice.OpenRouteService) *OrsReverseGeocoder {
+	return &OrsReverseGeocoder{orsSvc: orsSvc}
+}
+
+func (geocoder *OrsReverseGeocoder) ReverseGeocode(lon, lat float64) (string, error) {
+	geocodeResults, err := geocoder.orsSvc.ReverseGeocode(lon, lat, 10, "country,region,locality,localadmin")
+	if err != nil {
+		return "", err
+	}
+
+	name := helper.GetPreferedLocationName(geocodeResults)
+	if name == UnknownLocationName {
+		return "", ErrPlaceNotFound
+	}
+
+	return name, nil
+}
+
+// OrsForwardGeocoder searches places using the OpenRouteService (Pelias) search API.
+type OrsForwardGeocoder struct {
+	orsSvc *openrouteservice.OpenRouteService
+}
+
+func CreateOrsForwardGeocoder(orsSvc *openrouteservice.OpenRouteService) *OrsForwardGeocoder {
+	return &OrsForwardGeocoder{orsSvc: orsSvc}
+}
+
+func (geocoder *OrsForwardGeocoder) Search(text string, limit int) ([]SearchResult, error) {
+	features, err := geocoder.orsSvc.Geocode(text, limit, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for _, feature := range features {
+		if len(feature.Geometry.Coordinates) < 2 {
+			continue
+		}
+
+		results = append(results, SearchResult{
+			Name:    feature.Properties.Label,
+			Country: feature.Properties.CountryCode,
+			Lat:     feature.Geometry.Coordinates[1],
+			Lon:     feature.Geometry.Coordinates[0],
+		})
+	}
+
+	return results, nil
+}
@@ -0,0 +1,198 @@
+package maprender
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // tiles can be jpeg as well
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/paulmach/orb"
+)
+
+const tileSize = 256
+
+const maxZoom = 16
+
+// padding around the drawn course, in pixels
+const padding = 30
+
+var (
+	backgroundColor   = color.RGBA{0xe8, 0xe4, 0xd8, 0xff}
+	completedColor    = color.RGBA{0x00, 0x80, 0x00, 0xff}
+	notCompletedColor = color.RGBA{0xff, 0x00, 0x00, 0xff}
+	startColor        = color.RGBA{0x00, 0x80, 0x00, 0xff}
+	endColor          = color.RGBA{0x20, 0x20, 0x20, 0xff}
+	runnerColor       = color.RGBA{0xfc, 0x4c, 0x02, 0xff}
+	markerBorderColor = color.RGBA{0xff, 0xff, 0xff, 0xff}
+)
+
+// Map is what gets drawn: the course split at the current location, and markers for the start, the end and the runner.
+type Map struct {
+	Completed    orb.LineString
+	NotCompleted orb.LineString
+	Start        orb.Point
+	End          orb.Point
+	Current      orb.Point
+}
+
+// Renderer draws maps of adventures as images. If a tile directory is set, OSM tiles found in it ({z}/{x}/{y}.png)
+// are used as the background. Missing tiles (or no tile directory at all) leave a plain background, so nothing is
+// ever fetched from the network.
+type Renderer struct {
+	tileDirectory string
+	width         int
+	height        int
+}
+
+func CreateRenderer(tileDirectory string, width, height int) *Renderer {
+	return &Renderer{
+		tileDirectory: tileDirectory,
+		width:         width,
+		height:        height,
+	}
+}
+
+// RenderPng renders the map and encodes it as png.
+func (renderer *Renderer) RenderPng(m *Map) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, renderer.Render(m)); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Render renders the map at the highest zoom at which the whole course fits into the image.
+func (renderer *Renderer) Render(m *Map) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, renderer.width, renderer.height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{backgroundColor}, image.Point{}, draw.Src)
+
+	points := append(append(orb.LineString{m.Start, m.End, m.Current}, m.Completed...), m.NotCompleted...)
+	bound := points.Bound()
+
+	zoom := renderer.fittingZoom(bound)
+	centerX, centerY := project(bound.Center(), zoom)
+	originX, originY := centerX-float64(renderer.width)/2, centerY-float64(renderer.height)/2
+
+	if renderer.tileDirectory != "" {
+		renderer.drawTiles(img, zoom, originX, originY)
+	}
+
+	toPixel := func(point orb.Point) (float64, float64) {
+		x, y := project(point, zoom)
+
+		return x - originX, y - originY
+	}
+
+	drawLine(img, m.Completed, toPixel, 2, completedColor)
+	drawLine(img, m.NotCompleted, toPixel, 2, notCompletedColor)
+
+	drawMarker(img, toPixel, m.Start, 5, startColor)
+	drawMarker(img, toPixel, m.End, 5, endColor)
+	drawMarker(img, toPixel, m.Current, 7, runnerColor)
+
+	return img
+}
+
+func (renderer *Renderer) fittingZoom(bound orb.Bound) int {
+	for zoom := maxZoom; zoom > 0; zoom-- {
+		minX, maxY := project(bound.Min, zoom)
+		maxX, minY := project(bound.Max, zoom)
+
+		if maxX-minX <= float64(renderer.width-2*padding) && maxY-minY <= float64(renderer.height-2*padding) {
+			return zoom
+		}
+	}
+
+	return 0
+}
+
+func (renderer *Renderer) drawTiles(img *image.RGBA, zoom int, originX, originY float64) {
+	tilesPerSide := 1 << zoom
+
+	firstTileX, firstTileY := int(math.Floor(originX/tileSize)), int(math.Floor(originY/tileSize))
+	lastTileX, lastTileY := int(math.Floor((originX+float64(renderer.width))/tileSize)), int(math.Floor((originY+float64(renderer.height))/tileSize))
+
+	for tileY := max(firstTileY, 0); tileY <= min(lastTileY, tilesPerSide-1); tileY++ {
+		for tileX := firstTileX; tileX <= lastTileX; tileX++ {
+			tile := renderer.loadTile(zoom, ((tileX%tilesPerSide)+tilesPerSide)%tilesPerSide, tileY)
+			if tile == nil {
+				continue
+			}
+
+			offset := image.Pt(int(math.Round(float64(tileX*tileSize)-originX)), int(math.Round(float64(tileY*tileSize)-originY)))
+			draw.Draw(img, image.Rect(0, 0, tileSize, tileSize).Add(offset), tile, tile.Bounds().Min, draw.Src)
+		}
+	}
+}
+
+// loadTile returns nil if the tile is missing or can't be decoded, the background stays plain in that case.
+func (renderer *Renderer) loadTile(zoom, x, y int) image.Image {
+	file, err := os.Open(filepath.Join(renderer.tileDirectory, fmt.Sprint(zoom), fmt.Sprint(x), fmt.Sprint(y)+".png"))
+	if err != nil {
+		return nil
+	}
+
+	defer file.Close()
+
+	tile, _, err := image.Decode(file)
+	if err != nil {
+		return nil
+	}
+
+	return tile
+}
+
+// project converts the point to pixel coordinates of the whole world at the given zoom (web mercator).
+func project(point orb.Point, zoom int) (float64, float64) {
+	worldSize := float64(int(tileSize) << zoom)
+
+	lat := math.Max(math.Min(point.Lat(), 85.0511), -85.0511) * math.Pi / 180
+	x := (point.Lon() + 180) / 360 * worldSize
+	y := (1 - math.Log(math.Tan(lat)+1/math.Cos(lat))/math.Pi) / 2 * worldSize
+
+	return x, y
+}
+
+func drawLine(img *image.RGBA, line orb.LineString, toPixel func(orb.Point) (float64, float64), radius int, c color.RGBA) {
+	for i := 1; i < len(line); i++ {
+		x0, y0 := toPixel(line[i-1])
+		x1, y1 := toPixel(line[i])
+
+		// stamp a disc every half a pixel along the segment
+		steps := int(math.Ceil(math.Hypot(x1-x0, y1-y0) * 2))
+		for step := 0; step <= steps; step++ {
+			t := 0.0
+			if steps > 0 {
+				t = float64(step) / float64(steps)
+			}
+
+			fillDisc(img, x0+(x1-x0)*t, y0+(y1-y0)*t, radius, c)
+		}
+	}
+}
+
+func drawMarker(img *image.RGBA, toPixel func(orb.Point) (float64, float64), point orb.Point, radius int, c color.RGBA) {
+	x, y := toPixel(point)
+
+	fillDisc(img, x, y, radius+2, markerBorderColor)
+	fillDisc(img, x, y, radius, c)
+}
+
+func fillDisc(img *image.RGBA, centerX, centerY float64, radius int, c color.RGBA) {
+	cx, cy := int(math.Round(centerX)), int(math.Round(centerY))
+
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			if dx*dx+dy*dy <= radius*radius {
+				img.SetRGBA(cx+dx, cy+dy, c)
+			}
+		}
+	}
+}
@@ -2,21 +2,36 @@ package externalmodel
 
 type DirectionsSummary struct {
 	Distance float32 `json:"distance"`
+	Ascent   float32 `json:"ascent,omitempty"`
+	Descent  float32 `json:"descent,omitempty"`
 }
 
 type DirectionsRoute struct {
 	Summary  DirectionsSummary `json:"summary"`
 	Geometry string            `json:"geometry"`
+	// not returned by the api, set when the geometry is encoded with elevation as the third dimension
+	Elevation bool `json:"elevation,omitempty"`
 }
 
 type ReverseGeocodeProperties struct {
-	Layer      string `json:"layer"`
-	Country    string `json:"country"`
-	Region     string `json:"region"`
-	LocalAdmin string `json:"localadmin"`
-	Label      string `json:"label"`
+	Layer       string `json:"layer"`
+	Name        string `json:"name"`
+	Country     string `json:"country"`
+	CountryCode string `json:"country_code"`
+	Region      string `json:"region"`
+	LocalAdmin  string `json:"localadmin"`
+	Label       string `json:"label"`
 }
 
 type ReverseGeocodeFeature struct {
 	Properties ReverseGeocodeProperties `json:"properties"`
 }
+
+type GeocodeGeometry struct {
+	Coordinates []float64 `json:"coordinates"` // lon, lat
+}
+
+type GeocodeFeature struct {
+	Geometry   GeocodeGeometry          `json:"geometry"`
+	Properties ReverseGeocodeProperties `json:"properties"`
+}
@@ -3,6 +3,7 @@ package externalmodel
 type DirectionsRequest struct {
 	Coordinates [][]float64 `json:"coordinates"`
 	Units       string      `json:"units"`
+	Elevation   bool        `json:"elevation,omitempty"`
 }
 
 type DirectionsResponse struct {
@@ -12,3 +13,7 @@ type DirectionsResponse struct {
 type ReverseGeocodeResponse struct {
 	Features []ReverseGeocodeFeature `json:"features"`
 }
+
+type GeocodeSearchResponse struct {
+	Features []GeocodeFeature `json:"features"`
+}
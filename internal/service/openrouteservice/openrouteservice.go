@@ -14,15 +14,17 @@ import (
 )
 
 type OpenRouteService struct {
-	apiKey string
+	apiKey    string
+	elevation bool
 
 	httpClient http.Client
 	baseUrl    string
 }
 
-func CreateService(apiKey string) *OpenRouteService {
+func CreateService(apiKey string, elevation bool) *OpenRouteService {
 	return &OpenRouteService{
-		apiKey: apiKey,
+		apiKey:    apiKey,
+		elevation: elevation,
 
 		httpClient: http.Client{},
 		baseUrl:    "https://api.openrouteservice.org",
@@ -33,6 +35,7 @@ func (ors *OpenRouteService) GetDirections(latStart, lonStart, latEnd, lonEnd fl
 	directionsRequestJson, err := json.Marshal(&externalmodel.DirectionsRequest{
 		Coordinates: [][]float64{{lonStart, latStart}, {lonEnd, latEnd}},
 		Units:       units,
+		Elevation:   ors.elevation,
 	})
 	if err != nil {
 		return nil, &OpenRouteServiceError{statusCode: http.StatusInternalServerError, err: err}
@@ -72,6 +75,8 @@ func (ors *OpenRouteService) GetDirections(latStart, lonStart, latEnd, lonEnd fl
 		return nil, &OpenRouteServiceError{statusCode: http.StatusFailedDependency, err: errors.New("routes returned from the external api are empty")}
 	}
 
+	directionsResponseObj.Routes[0].Elevation = ors.elevation
+
 	internalDirectionsRoute := &model.DirectionsRoute{}
 	internalDirectionsRoute.FromExternalModel(&directionsResponseObj.Routes[0])
 
@@ -132,3 +137,57 @@ func (ors *OpenRouteService) ReverseGeocode(lon, lat float64, numOfResults int,
 
 	return result, nil
 }
+
+func (ors *OpenRouteService) Geocode(text string, numOfResults int, layers string) ([]model.GeocodeFeature, error) {
+	u, err := url.Parse(ors.baseUrl + "/geocode/search")
+	if err != nil {
+		return nil, &OpenRouteServiceError{statusCode: http.StatusInternalServerError, err: err}
+	}
+
+	query := u.Query()
+	query.Set("text", text)
+	query.Set("size", strconv.Itoa(numOfResults))
+	if layers != "" {
+		query.Set("layers", layers)
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, &OpenRouteServiceError{statusCode: http.StatusInternalServerError, err: err}
+	}
+
+	req.Header.Set("Authorization", ors.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := ors.httpClient.Do(req)
+	if err != nil {
+		return nil, &OpenRouteServiceError{statusCode: http.StatusFailedDependency, err: err}
+	}
+
+	defer resp.Body.Close()
+
+	geocodeResponseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &OpenRouteServiceError{statusCode: http.StatusFailedDependency, err: err}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &OpenRouteServiceError{statusCode: http.StatusFailedDependency, err: errors.New("geocoding via external api failed")}
+	}
+
+	var geocodeResponseObj externalmodel.GeocodeSearchResponse
+	err = json.Unmarshal(geocodeResponseBody, &geocodeResponseObj)
+	if err != nil {
+		return nil, &OpenRouteServiceError{statusCode: http.StatusInternalServerError, err: err}
+	}
+
+	var result []model.GeocodeFeature
+	for _, feature := range geocodeResponseObj.Features {
+		result = append(result, model.GeocodeFeature{
+			GeocodeFeature: &feature,
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,16 @@
+package osrm
+
+import "fmt"
+
+type OsrmError struct {
+	statusCode int
+	err        error
+}
+
+func (osrmError *OsrmError) StatusCode() int {
+	return osrmError.statusCode
+}
+
+func (osrmError *OsrmError) Error() string {
+	return fmt.Sprintf("OSRM error (%d): %v", osrmError.statusCode, osrmError.err)
+}
@@ -0,0 +1,7 @@
+package externalmodel
+
+type Route struct {
+	Distance float64 `json:"distance"` // in meters
+	Duration float64 `json:"duration"` // in seconds
+	Geometry string  `json:"geometry"`
+}
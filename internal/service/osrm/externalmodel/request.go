@@ -0,0 +1,7 @@
+package externalmodel
+
+type RouteResponse struct {
+	Code    string  `json:"code"`
+	Message string  `json:"message"`
+	Routes  []Route `json:"routes"`
+}
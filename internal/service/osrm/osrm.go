@@ -0,0 +1,90 @@
+package osrm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+	orsexternalmodel "github.com/miki208/stravaadventuregame/internal/service/openrouteservice/externalmodel"
+	"github.com/miki208/stravaadventuregame/internal/service/osrm/externalmodel"
+)
+
+// Osrm is a client for a self-hosted routing engine which exposes the OSRM route API
+// (OSRM itself, or GraphHopper/Valhalla deployments with an OSRM-compatible endpoint).
+type Osrm struct {
+	baseUrl string
+	profile string
+
+	httpClient http.Client
+}
+
+func CreateService(baseUrl, profile string) *Osrm {
+	return &Osrm{
+		baseUrl: strings.TrimSuffix(baseUrl, "/"),
+		profile: profile,
+
+		httpClient: http.Client{},
+	}
+}
+
+func (svc *Osrm) GetDirections(latStart, lonStart, latEnd, lonEnd float64, units string) (*model.DirectionsRoute, error) {
+	coordinates := fmt.Sprintf("%s,%s;%s,%s",
+		strconv.FormatFloat(lonStart, 'f', -1, 64), strconv.FormatFloat(latStart, 'f', -1, 64),
+		strconv.FormatFloat(lonEnd, 'f', -1, 64), strconv.FormatFloat(latEnd, 'f', -1, 64))
+
+	u, err := url.Parse(svc.baseUrl + "/route/v1/" + svc.profile + "/" + coordinates)
+	if err != nil {
+		return nil, &OsrmError{statusCode: http.StatusInternalServerError, err: err}
+	}
+
+	query := u.Query()
+	query.Set("overview", "full")
+	query.Set("geometries", "polyline") // same encoding (precision 5) as the one returned by ORS
+	u.RawQuery = query.Encode()
+
+	resp, err := svc.httpClient.Get(u.String())
+	if err != nil {
+		return nil, &OsrmError{statusCode: http.StatusFailedDependency, err: err}
+	}
+
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &OsrmError{statusCode: http.StatusFailedDependency, err: err}
+	}
+
+	var routeResponse externalmodel.RouteResponse
+	err = json.Unmarshal(respBody, &routeResponse)
+	if err != nil {
+		return nil, &OsrmError{statusCode: http.StatusFailedDependency, err: err}
+	}
+
+	if resp.StatusCode != http.StatusOK || routeResponse.Code != "Ok" {
+		return nil, &OsrmError{statusCode: http.StatusFailedDependency, err: fmt.Errorf("getting directions via osrm failed: %s %s", routeResponse.Code, routeResponse.Message)}
+	}
+
+	if len(routeResponse.Routes) < 1 {
+		return nil, &OsrmError{statusCode: http.StatusFailedDependency, err: errors.New("routes returned from osrm are empty")}
+	}
+
+	distance, err := helper.MetersToUnits(routeResponse.Routes[0].Distance, units)
+	if err != nil {
+		return nil, &OsrmError{statusCode: http.StatusBadRequest, err: err}
+	}
+
+	internalDirectionsRoute := model.NewDirectionsRoute()
+	internalDirectionsRoute.FromExternalModel(&orsexternalmodel.DirectionsRoute{
+		Summary:  orsexternalmodel.DirectionsSummary{Distance: float32(distance)},
+		Geometry: routeResponse.Routes[0].Geometry,
+	})
+
+	return internalDirectionsRoute, nil
+}
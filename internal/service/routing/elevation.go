@@ -0,0 +1,56 @@
+package routing
+
+import (
+	"log/slog"
+
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+	"github.com/miki208/stravaadventuregame/internal/service/elevation"
+)
+
+// ElevationProvider adds elevation from a local DEM to routes which come without it.
+// If elevation is not known for every point of the route, the route is returned as it is.
+type ElevationProvider struct {
+	provider RoutingProvider
+	dem      *elevation.Dem
+}
+
+func CreateElevationProvider(provider RoutingProvider, dem *elevation.Dem) *ElevationProvider {
+	return &ElevationProvider{
+		provider: provider,
+		dem:      dem,
+	}
+}
+
+func (provider *ElevationProvider) GetDirections(latStart, lonStart, latEnd, lonEnd float64, units string) (*model.DirectionsRoute, error) {
+	route, err := provider.provider.GetDirections(latStart, lonStart, latEnd, lonEnd, units)
+	if err != nil || route.Elevation {
+		return route, err
+	}
+
+	routePolyline, _, err := helper.DecodePolyline(route.Geometry, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	elevations := make([]float64, 0, len(routePolyline))
+	for _, point := range routePolyline {
+		elevation, err := provider.dem.Elevation(point.Lat(), point.Lon())
+		if err != nil {
+			slog.Warn("ElevationProvider > Elevation is not available for the route.", "error", err)
+
+			return route, nil
+		}
+
+		elevations = append(elevations, elevation)
+	}
+
+	ascent, descent := helper.ElevationGain(elevations)
+
+	route.Geometry = helper.EncodePolyline(routePolyline, elevations)
+	route.Elevation = true
+	route.Summary.Ascent = float32(ascent)
+	route.Summary.Descent = float32(descent)
+
+	return route, nil
+}
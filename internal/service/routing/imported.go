@@ -0,0 +1,144 @@
+package routing
+
+import (
+	"database/sql"
+	"strconv"
+
+	"github.com/miki208/stravaadventuregame/internal/database"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/miki208/stravaadventuregame/internal/model"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+)
+
+// coordinates of the imported route endpoints are copied to its locations, so only rounding errors are tolerated
+const endpointTolerance = 1e-6
+
+// ImportedRouteProvider serves routes uploaded by admins, without any external call.
+type ImportedRouteProvider struct {
+	db     *sql.DB
+	fileDb *database.FileDatabase
+}
+
+func CreateImportedRouteProvider(db *sql.DB, fileDb *database.FileDatabase) *ImportedRouteProvider {
+	return &ImportedRouteProvider{
+		db:     db,
+		fileDb: fileDb,
+	}
+}
+
+func (provider *ImportedRouteProvider) GetDirections(latStart, lonStart, latEnd, lonEnd float64, units string) (*model.DirectionsRoute, error) {
+	importedRoutes, err := model.AllImportedRoutesBetween(latStart, lonStart, latEnd, lonEnd, endpointTolerance, provider.db, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(importedRoutes) > 0 {
+		return provider.loadRoute(importedRoutes[0].Id, false, units)
+	}
+
+	// routes are walked in both directions
+	importedRoutes, err = model.AllImportedRoutesBetween(latEnd, lonEnd, latStart, lonStart, endpointTolerance, provider.db, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(importedRoutes) > 0 {
+		return provider.loadRoute(importedRoutes[0].Id, true, units)
+	}
+
+	return nil, ErrRouteNotFound
+}
+
+func (provider *ImportedRouteProvider) loadRoute(importedRouteId int, reverse bool, units string) (*model.DirectionsRoute, error) {
+	route := model.NewDirectionsRoute()
+
+	err := provider.fileDb.Read("importedroute", strconv.Itoa(importedRouteId), route)
+	if err != nil {
+		return nil, err
+	}
+
+	routePolyline, elevations, err := helper.DecodePolyline(route.Geometry, route.Elevation, reverse)
+	if err != nil {
+		return nil, err
+	}
+
+	distance, err := helper.MetersToUnits(geo.LengthHaversine(routePolyline), units)
+	if err != nil {
+		return nil, err
+	}
+
+	route.Summary.Distance = float32(distance)
+	route.Geometry = helper.EncodePolyline(routePolyline, elevations)
+	if route.Elevation {
+		ascent, descent := helper.ElevationGain(elevations)
+
+		route.Summary.Ascent = float32(ascent)
+		route.Summary.Descent = float32(descent)
+	}
+
+	return route, nil
+}
+
+// SaveImportedRoute creates start and end locations for the given line, and stores it as an imported route.
+// Distance of the route is calculated from its geometry, in kilometers. Elevations are optional (nil if not known).
+// The course is written to the file database right away, so if the transaction isn't committed afterwards, it has to be
+// removed with DeleteImportedRouteCourse.
+func SaveImportedRoute(name, startName, endName string, line orb.LineString, elevations []float64, createdAt int, db *sql.DB, tx *sql.Tx, fileDb *database.FileDatabase) (*model.ImportedRoute, error) {
+	startLocation := model.Location{
+		Lat:  line[0].Lat(),
+		Lon:  line[0].Lon(),
+		Name: startName,
+	}
+
+	if err := startLocation.Save(db, tx); err != nil {
+		return nil, err
+	}
+
+	endLocation := model.Location{
+		Lat:  line[len(line)-1].Lat(),
+		Lon:  line[len(line)-1].Lon(),
+		Name: endName,
+	}
+
+	if err := endLocation.Save(db, tx); err != nil {
+		return nil, err
+	}
+
+	distance := float32(geo.LengthHaversine(line) / 1000)
+
+	importedRoute := &model.ImportedRoute{
+		Name:          name,
+		StartLocation: startLocation.Id,
+		EndLocation:   endLocation.Id,
+		Distance:      distance,
+		CreatedAt:     createdAt,
+	}
+
+	if err := importedRoute.Save(db, tx); err != nil {
+		return nil, err
+	}
+
+	route := model.NewDirectionsRoute()
+	route.Summary.Distance = distance
+	route.Geometry = helper.EncodePolyline(line, elevations)
+	if elevations != nil {
+		ascent, descent := helper.ElevationGain(elevations)
+
+		route.Elevation = true
+		route.Summary.Ascent = float32(ascent)
+		route.Summary.Descent = float32(descent)
+	}
+
+	if err := fileDb.Write("importedroute", strconv.Itoa(importedRoute.Id), route); err != nil {
+		return nil, err
+	}
+
+	return importedRoute, nil
+}
+
+// DeleteImportedRouteCourse removes the course of the imported route from the file database, e.g. when the transaction
+// which saved the route is rolled back.
+func DeleteImportedRouteCourse(importedRouteId int, fileDb *database.FileDatabase) error {
+	return fileDb.Delete("importedroute", strconv.Itoa(importedRouteId))
+}
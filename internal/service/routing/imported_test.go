@@ -0,0 +1,130 @@
+package routing
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/miki208/stravaadventuregame/internal/database"
+	"github.com/miki208/stravaadventuregame/internal/database/databasetest"
+	"github.com/miki208/stravaadventuregame/internal/helper"
+	"github.com/paulmach/orb"
+)
+
+func TestImportedRouteProvider(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		fileDb := database.CreateFileDatabase(t.TempDir() + "/")
+		line := orb.LineString{{20.46, 44.82}, {20.2, 45.0}, {19.84, 45.25}}
+
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		importedRoute, err := SaveImportedRoute("Danube", "Belgrade", "Novi Sad", line, []float64{117, 90, 80}, 1, db, tx, fileDb)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = database.CommitOrRollbackTransaction(tx); err != nil {
+			t.Fatal(err)
+		}
+
+		provider := CreateImportedRouteProvider(db, fileDb)
+
+		tests := []struct {
+			name                               string
+			latStart, lonStart, latEnd, lonEnd float64
+			units                              string
+			found                              bool
+			reverse                            bool
+		}{
+			{"forward", 44.82, 20.46, 45.25, 19.84, "km", true, false},
+			{"backward", 45.25, 19.84, 44.82, 20.46, "km", true, true},
+			{"rounding error", 44.8200001, 20.4599999, 45.25, 19.84, "m", true, false},
+			{"another end", 44.82, 20.46, 45.26, 19.84, "km", false, false},
+			{"the same point", 44.82, 20.46, 44.82, 20.46, "km", false, false},
+		}
+
+		for _, test := range tests {
+			route, err := provider.GetDirections(test.latStart, test.lonStart, test.latEnd, test.lonEnd, test.units)
+			if !test.found {
+				if !errors.Is(err, ErrRouteNotFound) {
+					t.Errorf("%s: GetDirections() = %v, want ErrRouteNotFound", test.name, err)
+				}
+
+				continue
+			}
+
+			if err != nil {
+				t.Errorf("%s: GetDirections() failed: %v", test.name, err)
+
+				continue
+			}
+
+			course, elevations, err := helper.DecodePolyline(route.Geometry, route.Elevation, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantStart, wantElevation := line[0], 117.0
+			if test.reverse {
+				wantStart, wantElevation = line[len(line)-1], 80
+			}
+
+			if math.Abs(course[0].Lat()-wantStart.Lat()) > 1e-9 || elevations[0] != wantElevation {
+				t.Errorf("%s: the route starts at %v (%v m), want %v (%v m)", test.name, course[0], elevations[0], wantStart, wantElevation)
+			}
+
+			wantDistance := importedRoute.Distance
+			if test.units == "m" {
+				wantDistance *= 1000
+			}
+
+			if math.Abs(float64(route.Summary.Distance-wantDistance)) > float64(wantDistance)*1e-4 {
+				t.Errorf("%s: distance %v, want %v", test.name, route.Summary.Distance, wantDistance)
+			}
+
+			wantAscent := float32(0)
+			if test.reverse {
+				wantAscent = 37
+			}
+
+			if route.Summary.Ascent != wantAscent || route.Summary.Descent != 37-wantAscent {
+				t.Errorf("%s: ascent %v and descent %v, want %v and %v", test.name, route.Summary.Ascent, route.Summary.Descent, wantAscent, 37-wantAscent)
+			}
+		}
+	})
+}
+
+func TestDeleteImportedRouteCourseAfterRollback(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, driver string, db *sql.DB) {
+		fileDb := database.CreateFileDatabase(t.TempDir() + "/")
+
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		importedRoute, err := SaveImportedRoute("Danube", "Belgrade", "Novi Sad", orb.LineString{{20.46, 44.82}, {19.84, 45.25}}, nil, 1, db, tx, fileDb)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tx.Rollback()
+
+		if err = DeleteImportedRouteCourse(importedRoute.Id, fileDb); err != nil {
+			t.Fatal(err)
+		}
+
+		if exists, err := fileDb.Exists("importedroute", strconv.Itoa(importedRoute.Id)); err != nil || exists {
+			t.Errorf("course of a rolled back route exists = %v, %v, want false", exists, err)
+		}
+
+		if _, err = CreateImportedRouteProvider(db, fileDb).GetDirections(44.82, 20.46, 45.25, 19.84, "km"); !errors.Is(err, ErrRouteNotFound) {
+			t.Errorf("GetDirections() of a rolled back route = %v, want ErrRouteNotFound", err)
+		}
+	})
+}
@@ -0,0 +1,40 @@
+package routing
+
+import (
+	"errors"
+
+	"github.com/miki208/stravaadventuregame/internal/model"
+)
+
+// ErrRouteNotFound is returned by providers which don't know about a route between the given points
+// (e.g. there is no imported route between them), so the next provider in the chain can be tried.
+var ErrRouteNotFound = errors.New("route not found")
+
+// RoutingProvider calculates a course between two points. Returned route goes from the start to the end point.
+type RoutingProvider interface {
+	GetDirections(latStart, lonStart, latEnd, lonEnd float64, units string) (*model.DirectionsRoute, error)
+}
+
+// ChainProvider asks providers in order, and returns the first route found.
+type ChainProvider struct {
+	providers []RoutingProvider
+}
+
+func CreateChainProvider(providers ...RoutingProvider) *ChainProvider {
+	return &ChainProvider{providers: providers}
+}
+
+func (chain *ChainProvider) GetDirections(latStart, lonStart, latEnd, lonEnd float64, units string) (*model.DirectionsRoute, error) {
+	for _, provider := range chain.providers {
+		route, err := provider.GetDirections(latStart, lonStart, latEnd, lonEnd, units)
+		if err == nil {
+			return route, nil
+		}
+
+		if !errors.Is(err, ErrRouteNotFound) {
+			return nil, err
+		}
+	}
+
+	return nil, ErrRouteNotFound
+}
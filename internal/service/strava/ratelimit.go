@@ -0,0 +1,82 @@
+package strava
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimiter is shared by everything that calls the Strava API (activity processing workers, the outbox), so that once
+// the limit is reached nobody calls Strava until the limit resets. Strava limits requests per 15 minutes (reset at
+// natural quarter hours) and per day (reset at midnight UTC), and reports the usage in every response.
+type rateLimiter struct {
+	mutex        sync.Mutex
+	limitedUntil time.Time
+}
+
+// check returns an error with status 429 if the limit was reached and hasn't reset yet at now.
+func (limiter *rateLimiter) check(now time.Time) error {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	if now.Before(limiter.limitedUntil) {
+		return &StravaError{statusCode: http.StatusTooManyRequests, err: errors.New("rate limit reached, waiting until " + limiter.limitedUntil.UTC().Format(time.DateTime))}
+	}
+
+	return nil
+}
+
+// update records the usage reported in the response received at now.
+func (limiter *rateLimiter) update(resp *http.Response, now time.Time) {
+	now = now.UTC()
+
+	var until time.Time
+	if resp.StatusCode == http.StatusTooManyRequests {
+		until = now.Truncate(15 * time.Minute).Add(15 * time.Minute)
+	}
+
+	limits := parseRateLimitHeader(resp.Header.Get("X-RateLimit-Limit"))
+	usages := parseRateLimitHeader(resp.Header.Get("X-RateLimit-Usage"))
+	if len(limits) == 2 && len(usages) == 2 {
+		if usages[0] >= limits[0] {
+			until = now.Truncate(15 * time.Minute).Add(15 * time.Minute)
+		}
+
+		if usages[1] >= limits[1] {
+			until = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		}
+	}
+
+	if until.IsZero() {
+		return
+	}
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	if until.After(limiter.limitedUntil) {
+		limiter.limitedUntil = until
+	}
+}
+
+// parseRateLimitHeader parses e.g. "100,1000" (15 minutes, daily), it returns nil if the header is missing or invalid.
+func parseRateLimitHeader(header string) []int {
+	if header == "" {
+		return nil
+	}
+
+	var values []int
+	for _, part := range strings.Split(header, ",") {
+		value, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil
+		}
+
+		values = append(values, value)
+	}
+
+	return values
+}
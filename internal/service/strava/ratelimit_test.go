@@ -0,0 +1,91 @@
+package strava
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimitHeader(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []int
+	}{
+		{"100,1000", []int{100, 1000}},
+		{" 42 , 7 ", []int{42, 7}},
+		{"", nil},
+		{"100,x", nil},
+		{"100", []int{100}},
+	}
+
+	for _, test := range tests {
+		if got := parseRateLimitHeader(test.header); !slices.Equal(got, test.want) {
+			t.Errorf("parseRateLimitHeader(%q) = %v, want %v", test.header, got, test.want)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2025, 6, 1, 10, 7, 30, 0, time.UTC)
+	nextQuarter := time.Date(2025, 6, 1, 10, 15, 0, 0, time.UTC)
+	midnight := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		statusCode   int
+		limit, usage string
+		limitedUntil time.Time // zero if not limited
+	}{
+		{"under the limits", http.StatusOK, "100,1000", "99,999", time.Time{}},
+		{"15 minute limit reached", http.StatusOK, "100,1000", "100,500", nextQuarter},
+		{"daily limit reached", http.StatusOK, "100,1000", "50,1000", midnight},
+		{"both limits reached", http.StatusOK, "100,1000", "100,1000", midnight},
+		{"too many requests", http.StatusTooManyRequests, "", "", nextQuarter},
+		{"invalid headers", http.StatusOK, "100", "150", time.Time{}},
+	}
+
+	for _, test := range tests {
+		var limiter rateLimiter
+
+		resp := &http.Response{StatusCode: test.statusCode, Header: http.Header{}}
+		resp.Header.Set("X-RateLimit-Limit", test.limit)
+		resp.Header.Set("X-RateLimit-Usage", test.usage)
+
+		limiter.update(resp, now.In(time.FixedZone("CET", 3600)))
+
+		if !limiter.limitedUntil.Equal(test.limitedUntil) {
+			t.Errorf("%s: limited until %v, want %v", test.name, limiter.limitedUntil, test.limitedUntil)
+		}
+
+		err := limiter.check(now.Add(time.Second))
+
+		var stravaError *StravaError
+		if limited := errors.As(err, &stravaError) && stravaError.StatusCode() == http.StatusTooManyRequests; limited != !test.limitedUntil.IsZero() {
+			t.Errorf("%s: check() = %v, want limited %v", test.name, err, !test.limitedUntil.IsZero())
+		}
+
+		if err = limiter.check(test.limitedUntil); err != nil {
+			t.Errorf("%s: check() once the limit resets = %v, want nil", test.name, err)
+		}
+	}
+}
+
+func TestRateLimiterKeepsTheLongerLimit(t *testing.T) {
+	now := time.Date(2025, 6, 1, 10, 7, 30, 0, time.UTC)
+
+	var limiter rateLimiter
+
+	daily := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+	daily.Header.Set("X-RateLimit-Limit", "100,1000")
+	daily.Header.Set("X-RateLimit-Usage", "10,1000")
+	limiter.update(daily, now)
+
+	// a response of a request started before the limit was reached reports the 15 minute limit only
+	limiter.update(&http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}, now)
+
+	if want := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC); !limiter.limitedUntil.Equal(want) {
+		t.Errorf("limited until %v, want %v", limiter.limitedUntil, want)
+	}
+}
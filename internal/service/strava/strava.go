@@ -27,6 +27,8 @@ type Strava struct {
 	verifyToken                  string // TODO: this should be a random string in future
 	deleteOldActivitiesAfterDays int
 	processWebhookEventsAfterSec int
+
+	rateLimiter rateLimiter
 }
 
 func CreateService(clientId int, clientSecret, authorizationCallback, scope, webhookCallback, verifyToken string, deleteOldActivitiesAfterDays, processWebhookEventsAfterSec int) *Strava {
@@ -315,6 +317,10 @@ func (svc *Strava) DeleteSubscription(subscriptionId int) error {
 }
 
 func (svc *Strava) GetActivity(athleteId, activityId int64, db *sql.DB, tx *sql.Tx) (*model.Activity, error) {
+	if err := svc.rateLimiter.check(time.Now()); err != nil {
+		return nil, err
+	}
+
 	req, err := http.NewRequest(http.MethodGet, svc.baseUrl+"/activities/"+strconv.FormatInt(activityId, 10), nil)
 	if err != nil {
 		return nil, &StravaError{statusCode: http.StatusInternalServerError, err: err}
@@ -334,6 +340,8 @@ func (svc *Strava) GetActivity(athleteId, activityId int64, db *sql.DB, tx *sql.
 
 	defer resp.Body.Close()
 
+	svc.rateLimiter.update(resp, time.Now())
+
 	if resp.StatusCode != http.StatusOK {
 		return nil, &StravaError{statusCode: resp.StatusCode, err: errors.New("failed to get activity from strava")}
 	}
@@ -362,6 +370,10 @@ func (svc *Strava) UpdateActivity(athleteId, activityId int64, fieldsToUpdate ma
 		return nil, &StravaError{statusCode: http.StatusBadRequest, err: errors.New("no fields to update")}
 	}
 
+	if err := svc.rateLimiter.check(time.Now()); err != nil {
+		return nil, err
+	}
+
 	updateActivityBody, err := json.Marshal(fieldsToUpdate)
 	if err != nil {
 		return nil, &StravaError{statusCode: http.StatusInternalServerError, err: err}
@@ -387,6 +399,8 @@ func (svc *Strava) UpdateActivity(athleteId, activityId int64, fieldsToUpdate ma
 
 	defer resp.Body.Close()
 
+	svc.rateLimiter.update(resp, time.Now())
+
 	if resp.StatusCode != http.StatusOK {
 		return nil, &StravaError{statusCode: resp.StatusCode, err: errors.New("failed to update activity on strava")}
 	}
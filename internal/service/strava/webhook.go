@@ -13,7 +13,7 @@ import (
 	"github.com/miki208/stravaadventuregame/internal/service/strava/externalmodel"
 )
 
-func (svc *Strava) StravaWebhookCallback(resp http.ResponseWriter, req *http.Request, db *sql.DB, sessionManager *helper.SessionManager) {
+func (svc *Strava) StravaWebhookCallback(resp http.ResponseWriter, req *http.Request, db *sql.DB, sessionManager *helper.SessionManager, eventBus *helper.EventBus) {
 	switch req.Method {
 	case http.MethodGet:
 		svc.handleWebhookForSubscriptionValidation(resp, req, db)
@@ -41,7 +41,7 @@ func (svc *Strava) StravaWebhookCallback(resp http.ResponseWriter, req *http.Req
 		case "athlete":
 			svc.handleWebhookForAthlete(&webhookEvent, db, sessionManager)
 		case "activity":
-			svc.handleWebhookForActivity(&webhookEvent, db)
+			svc.handleWebhookForActivity(&webhookEvent, db, eventBus)
 		}
 
 		resp.WriteHeader(http.StatusOK)
@@ -106,9 +106,20 @@ func (svc *Strava) handleWebhookForAthlete(webhookEvent *externalmodel.StravaWeb
 	}
 }
 
-func (svc *Strava) handleWebhookForActivity(webhookEvent *externalmodel.StravaWebhookEvent, db *sql.DB) {
+func (svc *Strava) handleWebhookForActivity(webhookEvent *externalmodel.StravaWebhookEvent, db *sql.DB, eventBus *helper.EventBus) {
 	// we need to be quick here, we're just going to queue the activity for processing
 
+	// the athlete can follow the activity live, from the state it's left in the queue
+	stateUpdate := helper.ActivityStateUpdate{
+		ActivityId: webhookEvent.ObjectId,
+		AspectType: webhookEvent.AspectType,
+		State:      helper.ActivityStatePending,
+		ProcessAt:  helper.FormatProcessAt(webhookEvent.EventTime, svc.GetProcessWebhookEventsAfterSec()),
+	}
+
+	// when the queued event gets processed
+	processAt := webhookEvent.EventTime + int64(svc.GetProcessWebhookEventsAfterSec())
+
 	// we have a special logic for delete and update events in case there is a pending activity in the database
 	switch webhookEvent.AspectType {
 	case "delete", "update":
@@ -130,6 +141,9 @@ func (svc *Strava) handleWebhookForActivity(webhookEvent *externalmodel.StravaWe
 		}
 
 		if found {
+			// a new event gives a failed (or given up) queued event a fresh start
+			webhookEventInDb.ResetRetryState()
+
 			// new event = delete
 			// 	old event = create -> delete old
 			//	old event = update -> update old to delete
@@ -141,6 +155,9 @@ func (svc *Strava) handleWebhookForActivity(webhookEvent *externalmodel.StravaWe
 				if webhookEventInDb.AspectType == "update" {
 					webhookEventInDb.AspectType = "delete"
 
+					stateUpdate.ProcessAt = helper.FormatProcessAt(webhookEventInDb.EventTime, svc.GetProcessWebhookEventsAfterSec())
+					processAt = webhookEventInDb.EventTime + int64(svc.GetProcessWebhookEventsAfterSec())
+
 					err = webhookEventInDb.Save(db, tx)
 					if err != nil {
 						slog.Error("strava_webhook > Failed to update webhook event to delete.", "error", err, "event_id", webhookEvent.ObjectId)
@@ -154,6 +171,20 @@ func (svc *Strava) handleWebhookForActivity(webhookEvent *externalmodel.StravaWe
 
 						return
 					}
+
+					stateUpdate = helper.ActivityStateUpdate{ActivityId: webhookEvent.ObjectId, AspectType: webhookEvent.AspectType, State: helper.ActivityStateDropped}
+				}
+			} else {
+				// the queued event stays as it is
+				stateUpdate.AspectType = webhookEventInDb.AspectType
+				stateUpdate.ProcessAt = helper.FormatProcessAt(webhookEventInDb.EventTime, svc.GetProcessWebhookEventsAfterSec())
+				processAt = webhookEventInDb.EventTime + int64(svc.GetProcessWebhookEventsAfterSec())
+
+				err = webhookEventInDb.Save(db, tx)
+				if err != nil {
+					slog.Error("strava_webhook > Failed to reset the retry state of webhook event.", "error", err, "event_id", webhookEvent.ObjectId)
+
+					return
 				}
 			}
 		} else {
@@ -171,7 +202,18 @@ func (svc *Strava) handleWebhookForActivity(webhookEvent *externalmodel.StravaWe
 			}
 		}
 
-		if err = database.CommitOrRollbackSQLiteTransaction(tx); err != nil {
+		err = helper.RecordActivitySyncReceived(webhookEvent.OwnerId, webhookEvent.ObjectId, webhookEvent.AspectType, processAt, db, tx)
+		if err == nil && stateUpdate.State == helper.ActivityStateDropped {
+			err = helper.ResolveActivitySync(webhookEvent.OwnerId, webhookEvent.ObjectId, model.ActivitySyncDropped, "",
+				"deleted on Strava before it was processed", db, tx)
+		}
+		if err != nil {
+			slog.Error("strava_webhook > Failed to record activity sync.", "error", err, "event_id", webhookEvent.ObjectId)
+
+			return
+		}
+
+		if err = database.CommitOrRollbackTransaction(tx); err != nil {
 			slog.Error("strava_webhook > Failed to commit transaction for webhook event.", "error", err, "event_id", webhookEvent.ObjectId)
 
 			return
@@ -185,5 +227,12 @@ func (svc *Strava) handleWebhookForActivity(webhookEvent *externalmodel.StravaWe
 
 			return
 		}
+
+		// the event is queued anyway, the sync log is only informative
+		if err := helper.RecordActivitySyncReceived(webhookEvent.OwnerId, webhookEvent.ObjectId, webhookEvent.AspectType, processAt, db, nil); err != nil {
+			slog.Error("strava_webhook > Failed to record activity sync.", "error", err, "event_id", webhookEvent.ObjectId)
+		}
 	}
+
+	eventBus.PublishActivityState(webhookEvent.OwnerId, stateUpdate)
 }
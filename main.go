@@ -2,11 +2,15 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"os"
 
 	"log/slog"
 
 	"github.com/miki208/stravaadventuregame/internal/application"
+	"github.com/miki208/stravaadventuregame/internal/command"
 	"github.com/miki208/stravaadventuregame/internal/handler"
+	"github.com/miki208/stravaadventuregame/internal/handler/api"
 	"github.com/miki208/stravaadventuregame/internal/handler/auth"
 	"github.com/miki208/stravaadventuregame/internal/handler/noauth"
 	"github.com/miki208/stravaadventuregame/internal/handler/other"
@@ -20,11 +24,29 @@ func main() {
 	app := application.MakeApp(*configFileName)
 	defer app.Close()
 
+	// maintenance commands (e.g. "places import cities500.txt") are run instead of the server
+	if flag.NArg() > 0 {
+		if err := command.Run(app, flag.Args()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+
+			app.Close()
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	slog.Info("Application started.", "hostname", app.Hostname)
 
 	slog.Info("Registering scheduled jobs...")
 	for _, job := range scheduledjobs.GetScheduledJobs() {
-		app.CronSvc.AddJob(job)
+		if err := app.CronSvc.AddJob(job); err != nil {
+			slog.Error("Failed to register the scheduled job.", "job", job.Name, "error", err)
+			fmt.Fprintln(os.Stderr, err)
+
+			app.Close()
+			os.Exit(1)
+		}
 	}
 	slog.Info("Scheduled jobs registered.")
 
@@ -43,18 +65,62 @@ func main() {
 	}
 	srv := srvFactory.CreateServer(srvOptions)
 
+	// live event streams never end by themselves
+	srv.OnShutdown(app.EventBus.Close)
+
 	srv.AddRoute(app.GetDefaultPageLoggedOutUsersWithoutProxyPathPrefix(), handler.MakeHandlerWoutSession(app, noauth.Authorize))
 	srv.AddRoute(app.StravaSvc.GetAuthorizationCallback(), handler.MakeHandlerWoutSession(app, noauth.StravaAuthCallback))
 	srv.AddRoute(app.GetDefaultPageLoggedInUsersWithoutProxyPathPrefix(), handler.MakeHandlerWSession(app, auth.Welcome))
+	srv.AddRoute("/events", handler.MakeHandlerWSession(app, auth.LiveEvents))
 	srv.AddRoute("/start-adventure", handler.MakeHandlerWSession(app, auth.StartAdventure))
+	srv.AddRoute("/adventure/export", handler.MakeHandlerWSession(app, auth.ExportAdventure))
+	srv.AddRoute("/adventure/share", handler.MakeHandlerWSession(app, auth.ShareAdventure))
+	srv.AddRoute("/adventure/unshare", handler.MakeHandlerWSession(app, auth.UnshareAdventure))
+	srv.AddRoute("/locations/search", handler.MakeHandlerWSession(app, auth.SearchLocations))
+	srv.AddRoute("/locations/create", handler.MakeHandlerWSession(app, auth.CreateMyLocation))
+	srv.AddRoute("/locations/delete", handler.MakeHandlerWSession(app, auth.DeleteMyLocation))
 	srv.AddRoute("/logout", handler.MakeHandlerWSession(app, auth.Logout))
 	srv.AddRoute("/deauthorize", handler.MakeHandlerWSession(app, auth.Deauthorize))
 	srv.AddRoute("/settings", handler.MakeHandlerWSession(app, auth.Settings))
+	srv.AddRoute("/settings/tokens/create", handler.MakeHandlerWSession(app, auth.CreateApiToken))
+	srv.AddRoute("/settings/tokens/revoke", handler.MakeHandlerWSession(app, auth.RevokeApiToken))
+	srv.AddRoute("/sync", handler.MakeHandlerWSession(app, auth.ActivitySync))
+	srv.AddRoute("/webhooks", handler.MakeHandlerWSession(app, auth.Webhooks))
+	srv.AddRoute("/webhooks/create", handler.MakeHandlerWSession(app, auth.CreateWebhook))
+	srv.AddRoute("/webhooks/delete", handler.MakeHandlerWSession(app, auth.DeleteWebhook))
 	srv.AddRoute(app.GetAdminPanelPageWithoutProxyPathPrefix(), handler.MakeHandlerWSession(app, auth.AdminPanel))
 	srv.AddRoute("/stravawebhook/delete", handler.MakeHandlerWSession(app, auth.DeleteStravaWebhookSubscription))
 	srv.AddRoute("/stravawebhook/create", handler.MakeHandlerWSession(app, auth.CreateStravaWebhookSubscription))
+	srv.AddRoute("/admin/import-route", handler.MakeHandlerWSession(app, auth.ImportRoute))
+	srv.AddRoute("/admin/webhooks", handler.MakeHandlerWSession(app, auth.AdminWebhooks))
+	srv.AddRoute("/admin/sync", handler.MakeHandlerWSession(app, auth.AdminActivitySync))
+	srv.AddRoute("/admin/sync/requeue", handler.MakeHandlerWSession(app, auth.RequeueActivityEvent))
+	srv.AddRoute("/admin/sync/discard", handler.MakeHandlerWSession(app, auth.DiscardActivityEvent))
+	srv.AddRoute("/admin/jobs", handler.MakeHandlerWSession(app, auth.AdminJobs))
+	srv.AddRoute("/admin/jobs/run", handler.MakeHandlerWSession(app, auth.RunJobNow))
+	srv.AddRoute("/admin/locations", handler.MakeHandlerWSession(app, auth.AdminLocations))
+	srv.AddRoute("/admin/locations/save", handler.MakeHandlerWSession(app, auth.SaveLocation))
+	srv.AddRoute("/admin/locations/delete", handler.MakeHandlerWSession(app, auth.DeleteLocation))
+	srv.AddRoute("/admin/adventures", handler.MakeHandlerWSession(app, auth.AdminCuratedAdventures))
+	srv.AddRoute("/admin/adventures/save", handler.MakeHandlerWSession(app, auth.SaveCuratedAdventure))
+	srv.AddRoute("/admin/adventures/delete", handler.MakeHandlerWSession(app, auth.DeleteCuratedAdventure))
+	srv.AddRoute("/admin/locations/promote", handler.MakeHandlerWSession(app, auth.PromoteLocation))
+	srv.AddRoute("/admin/locations/import", handler.MakeHandlerWSession(app, auth.ImportLocations))
+	srv.AddRoute("/admin/locations/export", handler.MakeHandlerWSession(app, auth.ExportLocations))
 	srv.AddRoute(app.StravaSvc.GetWebhookCallback(), handler.MakeHandlerWoutSession(app, noauth.StravaWebhookCallback))
 	srv.AddRoute("/static/", handler.MakeHandler(app, other.FileServer))
+	srv.AddRoute("/map/{image}", handler.MakeHandler(app, other.AdventureMap))
+	srv.AddRoute("/a/{slug}", handler.MakeHandler(app, other.PublicAdventure))
+	srv.AddRoute("/api/v1/openapi.json", handler.MakeHandler(app, api.OpenApi))
+	srv.AddRoute("/api/v1/athlete", handler.MakeApiHandler(app, api.GetAthlete))
+	srv.AddRoute("/api/v1/settings", handler.MakeApiHandler(app, api.AthleteSettings))
+	srv.AddRoute("/api/v1/stats", handler.MakeApiHandler(app, api.GetStats))
+	srv.AddRoute("/api/v1/locations", handler.MakeApiHandler(app, api.Locations))
+	srv.AddRoute("/api/v1/locations/{id}", handler.MakeApiHandler(app, api.LocationById))
+	srv.AddRoute("/api/v1/adventures", handler.MakeApiHandler(app, api.Adventures))
+	srv.AddRoute("/api/v1/adventures/{start}/{end}", handler.MakeApiHandler(app, api.AdventureByLocations))
+	srv.AddRoute("/api/v1/adventures/{start}/{end}/activities", handler.MakeApiHandler(app, api.AdventureActivities))
+	srv.AddRoute("/api/v1/adventures/{start}/{end}/export", handler.MakeApiHandler(app, api.ExportAdventure))
 
 	srv.ListenAndServe()
 }